@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/certs"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+	"github.com/spf13/cobra"
+)
+
+var (
+	certsDir    string
+	certsHosts  []string
+	certsDays   int
+	certsWithin time.Duration
+
+	certsTraefikDir string
+)
+
+func getCertsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Manage certificates from the local CA",
+		Long: `Manage certificates for internal hostnames, issued by a local CA.
+
+The CA is created in the certificates directory the first time a certificate
+is issued. Its certificate is also written as ca.crt for clients and for
+Traefik's serversTransport rootCAs.
+
+Issued certificates are loaded into Traefik's default store by certs.yml in
+the Traefik dynamic config directory, which uberbase serve serves along with
+the platform configs.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&certsDir, "dir", "./data/certs", "Directory holding the CA and issued certificates")
+	cmd.PersistentFlags().StringVar(&certsTraefikDir, "traefik-dir", traefik.DynamicConfigPath, "Traefik dynamic config directory to write a certificate store config (certs.yml) to, served as it is by uberbase serve (empty to skip)")
+	cmd.AddCommand(getCertsIssueCmd())
+	cmd.AddCommand(getCertsListCmd())
+	cmd.AddCommand(getCertsRenewCmd())
+
+	return cmd
+}
+
+func getCertsIssueCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "issue name",
+		Short: "Issue a certificate for internal hosts",
+		Long: `Issue a certificate for internal hosts, written as name-cert.pem and
+name-key.pem in the certificates directory.
+
+Examples:
+  uberbase certs issue vault --host uberbase --host 127.0.0.1
+  uberbase certs issue postgres --host postgres --days 90`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ca, err := certs.LoadOrCreateCA(certsDir)
+			if err != nil {
+				return err
+			}
+			certificate, err := ca.Issue(args[0], certsHosts, certsValidity())
+			if err != nil {
+				return err
+			}
+			printCertificate(cmd, certificate)
+			return writeTraefikCertificates()
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&certsHosts, "host", nil, "DNS name or IP address to issue for (repeatable)")
+	cmd.Flags().IntVar(&certsDays, "days", 365, "Days the certificate is valid for")
+	cmd.MarkFlagRequired("host")
+
+	return cmd
+}
+
+func getCertsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List issued certificates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			certificates, err := certs.ListCertificates(certsDir)
+			if err != nil {
+				return err
+			}
+			if len(certificates) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "no certificates in %s\n", certsDir)
+				return nil
+			}
+			for _, certificate := range certificates {
+				printCertificate(cmd, certificate)
+			}
+			return nil
+		},
+	}
+}
+
+func getCertsRenewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "renew [name...]",
+		Short: "Renew issued certificates",
+		Long: `Renew issued certificates for the same hosts.
+
+Named certificates are always renewed. Without names, every certificate
+expiring within --within is renewed.
+
+Examples:
+  uberbase certs renew vault
+  uberbase certs renew --within 720h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ca, err := certs.LoadCA(certsDir)
+			if err != nil {
+				return err
+			}
+
+			names := args
+			if len(names) == 0 {
+				certificates, err := certs.ListCertificates(certsDir)
+				if err != nil {
+					return err
+				}
+				for _, certificate := range certificates {
+					if certificate.ExpiresWithin(certsWithin) {
+						names = append(names, certificate.Name)
+					}
+				}
+			}
+			if len(names) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no certificates need renewing")
+				return nil
+			}
+
+			for _, name := range names {
+				certificate, err := ca.Renew(name, certsValidity())
+				if err != nil {
+					return err
+				}
+				printCertificate(cmd, certificate)
+			}
+			return writeTraefikCertificates()
+		},
+	}
+
+	cmd.Flags().IntVar(&certsDays, "days", 365, "Days the renewed certificates are valid for")
+	cmd.Flags().DurationVar(&certsWithin, "within", 30*24*time.Hour, "Renew certificates expiring within this duration")
+
+	return cmd
+}
+
+// writeTraefikCertificates writes a dynamic config loading every issued
+// certificate into Traefik's default store, unless --traefik-dir is empty
+func writeTraefikCertificates() error {
+	if certsTraefikDir == "" {
+		return nil
+	}
+	certificates, err := certs.ListCertificates(certsDir)
+	if err != nil {
+		return err
+	}
+	stored := []traefik.TraefikCertificate{}
+	for _, certificate := range certificates {
+		stored = append(stored, certificate.Traefik())
+	}
+	if err := traefik.NewCertificateStoreConfig(stored...).WriteToFile(certsTraefikDir, "certs.yml"); err != nil {
+		return fmt.Errorf("failed to write certificate store config: %w", err)
+	}
+	return nil
+}
+
+func certsValidity() time.Duration {
+	return time.Duration(certsDays) * 24 * time.Hour
+}
+
+func printCertificate(cmd *cobra.Command, certificate *certs.Certificate) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\texpires %s\n",
+		certificate.Name,
+		strings.Join(certificate.Hosts(), ","),
+		certificate.Cert.NotAfter.Format(time.RFC3339))
+}
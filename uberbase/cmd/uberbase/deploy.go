@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/deploy"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/loadbalancer"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
 	bt_ssh "github.com/bluetongueai/uberbase/uberbase/pkg/ssh"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
 	"github.com/spf13/cobra"
 )
 
+// remoteWorkDir is where deployments and their state live on the host
+const remoteWorkDir = "/root/uberbase-deploy"
+
 var (
 	// Command line flags
 	composePath string
@@ -24,11 +35,37 @@ var (
 	regUser     string
 	regPass     string
 	debug       bool
+
+	stickySessions bool
+	stickyCookie   string
+	drainTimeout   time.Duration
+
+	connectionDrainTimeout time.Duration
+
+	certResolvers       map[string]string
+	defaultCertResolver string
+	tlsEntryPoints      []string
+
+	healthSuccessThreshold int
+	healthFailureThreshold int
+
+	smokeTestsPath string
+	watchWindow    time.Duration
+	pruneVolumes   bool
+	resumeDeploy   bool
+	noRollback     bool
+
+	deployServices   []string
+	withDependencies bool
+
+	inventoryPath    string
+	rolloutStrategy  string
+	rolloutBatchSize int
 )
 
 func getDeployCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "deploy [flags] host",
+		Use:   "deploy [flags] host...",
 		Short: "A tool to deploy services from docker-compose.yml",
 		Long: `A tool to deploy services from docker-compose.yml.
 
@@ -39,6 +76,30 @@ Examples:
   # Using SSH key from environment
   SSH_PRIVATE_KEY="$(cat ~/.ssh/id_rsa)" uberbase deploy prod.example.com --ssh-user deploy
 
+  # Keep sessions on the previous version for up to 10 minutes
+  uberbase deploy prod.example.com --sticky --drain-timeout 10m
+
+  # Roll back if the new version turns unhealthy in the next 15 minutes
+  uberbase deploy prod.example.com --watch 15m
+
+  # Roll back unless the smoke tests pass against the live routes
+  uberbase deploy prod.example.com --smoke-tests smoke.yml
+
+  # Issue certificates for routed domains with Let's Encrypt
+  uberbase deploy prod.example.com --default-cert-resolver letsencryptresolver
+
+  # Deploy only the api and worker services, leaving the others as they are
+  uberbase deploy prod.example.com --service api --service worker
+
+  # Roll out to three hosts two at a time, rolling all back if one fails
+  uberbase deploy web1.example.com web2.example.com web3.example.com --strategy batch --batch-size 2
+
+  # Roll out to the hosts listed in an inventory file, one at a time
+  uberbase deploy --inventory hosts.yml
+
+  # Pick up a deploy of the same commit that was interrupted
+  uberbase deploy prod.example.com --resume
+
   # Minimal usage
   uberbase deploy prod.example.com`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -46,86 +107,92 @@ Examples:
 				logging.SetDebugLevel()
 			}
 
-			// Parse hosts from flag or positional arguments
-			var host string
-			if len(args) > 0 {
-				host = args[0]
-			} else {
+			// Parse hosts from the inventory or positional arguments
+			hosts := args
+			if inventoryPath != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("hosts cannot be given with --inventory")
+				}
+				inventory, err := deploy.LoadInventory(inventoryPath)
+				if err != nil {
+					return err
+				}
+				hosts = inventory
+			}
+			if len(hosts) == 0 {
 				return fmt.Errorf("no hosts specified")
 			}
 
-			// Get SSH key configuration
-			sshKeySource := bt_ssh.File
-			var sshKeyData string
-			if sshKeyFile == "" {
-				sshKeySource = bt_ssh.Environment
-				sshKeyData = os.Getenv("SSH_PRIVATE_KEY")
-				logging.Logger.Debug("Using SSH key from environment")
-			} else {
-				logging.Logger.Debug("Using SSH key from filepath", sshKeyFile)
+			if drainTimeout > 0 && !stickySessions {
+				return fmt.Errorf("--drain-timeout requires --sticky")
 			}
 
-			if sshKeyData == "" && sshKeyFile == "" {
-				return fmt.Errorf("either SSH key file (-i) or SSH key environment variable (SSH_PRIVATE_KEY) must be provided")
+			sshKey, err := loadSSHKey()
+			if err != nil {
+				return err
 			}
 
-			sshKey := bt_ssh.NewSSHKey(sshKeySource, sshKeyEnv, sshKeyFile)
-			if _, err := sshKey.Load(); err != nil {
-				return fmt.Errorf("failed to load SSH key: %w", err)
+			// Locate docker-compose file
+			if err := findComposeFile(); err != nil {
+				return err
 			}
-			logging.Logger.Debug("SSH key loaded successfully", "source", sshKeySource)
+			localWorkDir := filepath.Dir(composePath)
 
-			// Locate docker-compose file
-			if composePath == "" {
-				paths, err := filepath.Glob("docker-compose.yml")
+			var smokeSuite *deploy.SmokeSuite
+			if smokeTestsPath != "" {
+				smokeSuite, err = deploy.LoadSmokeSuite(smokeTestsPath)
 				if err != nil {
-					return fmt.Errorf("failed to find docker-compose.yml: %w", err)
+					return err
 				}
-				if len(paths) == 0 {
-					return fmt.Errorf("no docker-compose.yml file found in working directory")
-				}
-				composePath = paths[0]
-				logging.Logger.Debug("Found docker-compose.yml in current directory", "path", composePath)
 			}
-			localWorkDir := filepath.Dir(composePath)
-
-			logging.LogKeyValues("Initializing deployment", [][2]string{
-				{"host", host},
-				{"local workdir", "\033[34m\"" + localWorkDir + "\"\033[0m"},
-				{"remote workdir", "\033[34m\"" + "/root/uberbase-deploy" + "\"\033[0m"},
-			})
 
-			// Load docker-compose configuration
-			compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
-			if err != nil {
-				return fmt.Errorf("failed to load docker-compose.yml: %w", err)
-			}
-			logging.Logger.Debug("Loaded compose configuration ",
-				"services", compose.Project.Services,
-				"project", compose.Project.Name)
-
-			// Initialize executors
-			localExecutor := core.NewLocalExecutor()
-			remoteExecutor, err := core.NewRemoteExecutor(bt_ssh.SSHConfig{
-				Host: host,
-				User: sshUser,
-				Port: sshPort,
-				Key:  *sshKey,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to create remote executor: %w", err)
+			targets := []deployTarget{}
+			for _, host := range hosts {
+				logging.LogKeyValues("Initializing deployment", [][2]string{
+					{"host", host},
+					{"local workdir", "\033[34m\"" + localWorkDir + "\"\033[0m"},
+					{"remote workdir", "\033[34m\"" + remoteWorkDir + "\"\033[0m"},
+				})
+				target, err := newDeployTarget(host, sshKey, localWorkDir, smokeSuite)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
 			}
 
-			// Create and run deployer
-			deployer, err := deploy.NewDeployer(localExecutor, remoteExecutor, compose, localWorkDir, "/root/uberbase-deploy")
-			if err != nil {
-				return fmt.Errorf("failed to create deployer: %w", err)
+			if len(targets) == 1 {
+				target := targets[0]
+				logging.Logger.Info("Starting deployment to", "host", target.host)
+				err = target.deployer.DeployProject()
+				target.deployer.Report().Log()
+				if err != nil {
+					logging.Logger.Error("Deployment failed", "error", err)
+					return err
+				}
+			} else {
+				rolloutHosts := []deploy.RolloutHost{}
+				for _, target := range targets {
+					rolloutHosts = append(rolloutHosts, deploy.RolloutHost{Host: target.host, Deployer: target.deployer})
+				}
+				rollout, err := deploy.NewRollout(rolloutHosts, rolloutStrategy, rolloutBatchSize)
+				if err != nil {
+					return err
+				}
+				rollout.SetSkipRollback(noRollback)
+
+				logging.Logger.Info("Starting rollout", "hosts", strings.Join(hosts, ", "), "strategy", rolloutStrategy)
+				err = rollout.Run(context.Background())
+				rollout.Report().Print(cmd.OutOrStdout())
+				if err != nil {
+					logging.Logger.Error("Rollout failed", "error", err)
+					return err
+				}
 			}
 
-			logging.Logger.Info("Starting deployment to", "host", host)
-			if err := deployer.DeployProject(); err != nil {
-				logging.Logger.Error("Deployment failed", "error", err)
-				return err
+			if watchWindow > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), watchWindow)
+				defer cancel()
+				return watchTargets(ctx, targets, watchWindow)
 			}
 
 			return nil
@@ -134,14 +201,149 @@ Examples:
 
 	// Define flags
 	cmd.PersistentFlags().StringVarP(&composePath, "file", "f", "", "Path to docker-compose.yml (default: ./docker-compose.yml)")
-	cmd.PersistentFlags().StringVar(&sshUser, "ssh-user", "root", "SSH user")
-	cmd.PersistentFlags().IntVar(&sshPort, "ssh-port", 22, "SSH port")
-	cmd.PersistentFlags().StringVarP(&sshKeyFile, "identity-file", "i", "", "SSH private key file")
-	cmd.PersistentFlags().StringVar(&sshKeyEnv, "ssh-key-env", "SSH_PRIVATE_KEY", "Environment variable containing SSH key")
 	cmd.PersistentFlags().StringVar(&registryURL, "registry", "", "Registry URL")
 	cmd.PersistentFlags().StringVar(&regUser, "registry-user", "", "Registry username")
 	cmd.PersistentFlags().StringVar(&regPass, "registry-pass", "", "Registry password")
-	cmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	cmd.PersistentFlags().BoolVar(&stickySessions, "sticky", false, "Pin sessions to a version with a sticky cookie")
+	cmd.PersistentFlags().StringVar(&stickyCookie, "sticky-cookie", "uberbase", "Prefix of the sticky cookie names, suffixed with the service name")
+	cmd.PersistentFlags().DurationVar(&drainTimeout, "drain-timeout", 0, "How long the previous version keeps serving its sticky sessions")
+	cmd.PersistentFlags().DurationVar(&connectionDrainTimeout, "connection-drain-timeout", 30*time.Second, "How long to wait for in-flight requests to the previous version before stopping it (0 to skip)")
+	cmd.PersistentFlags().StringToStringVar(&certResolvers, "cert-resolver", nil, "Certificate resolver for a domain or wildcard, as domain=resolver (repeatable)")
+	cmd.PersistentFlags().StringVar(&defaultCertResolver, "default-cert-resolver", "", "Certificate resolver for domains without one of their own")
+	cmd.PersistentFlags().StringArrayVar(&tlsEntryPoints, "tls-entrypoint", []string{"websecure"}, "Entrypoint whose routers are given TLS settings (repeatable)")
+	cmd.PersistentFlags().IntVar(&healthSuccessThreshold, "health-success-threshold", 1, "Consecutive passing checks before a new server is healthy")
+	cmd.PersistentFlags().IntVar(&healthFailureThreshold, "health-failure-threshold", 0, "Consecutive failing checks before a new server is unhealthy (0 to retry until timeout)")
+	cmd.PersistentFlags().StringVar(&smokeTestsPath, "smoke-tests", "", "Smoke tests to run against the live routes after cut-over, rolling back if any fail")
+	cmd.PersistentFlags().BoolVar(&pruneVolumes, "prune-volumes", false, "Remove named volumes that are no longer in compose, deleting their data")
+	cmd.PersistentFlags().BoolVar(&resumeDeploy, "resume", false, "Resume an interrupted deploy of the same commit from its journal on the host")
+	cmd.PersistentFlags().StringArrayVar(&deployServices, "service", nil, "Deploy only this service, leaving the others on their current tags (repeatable)")
+	cmd.PersistentFlags().BoolVar(&withDependencies, "with-dependencies", false, "Also deploy the services the --service services depend on")
+	cmd.PersistentFlags().StringVar(&inventoryPath, "inventory", "", "File listing the hosts to deploy to under hosts:, instead of arguments")
+	cmd.PersistentFlags().StringVar(&rolloutStrategy, "strategy", deploy.StrategySerial, "How to roll out to several hosts: serial, batch or parallel")
+	cmd.PersistentFlags().IntVar(&rolloutBatchSize, "batch-size", 1, "Hosts to deploy to at a time with --strategy batch")
+	cmd.PersistentFlags().BoolVar(&noRollback, "no-rollback", false, "Leave a failed deploy in place for debugging instead of rolling it back")
+	cmd.PersistentFlags().DurationVar(&watchWindow, "watch", 0, "Keep watching the deployment's health for this long after it completes, rolling back if it turns unhealthy")
+	addWatchFlags(cmd)
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
 
 	return cmd
 }
+
+// findComposeFile defaults the compose file flag to the working directory's
+// docker-compose.yml
+func findComposeFile() error {
+	if composePath != "" {
+		return nil
+	}
+	paths, err := filepath.Glob("docker-compose.yml")
+	if err != nil {
+		return fmt.Errorf("failed to find docker-compose.yml: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no docker-compose.yml file found in working directory")
+	}
+	composePath = paths[0]
+	logging.Logger.Debug("Found docker-compose.yml in current directory", "path", composePath)
+	return nil
+}
+
+// deployTarget is a host to deploy to, with its connection and deployer
+type deployTarget struct {
+	host     string
+	executor *core.RemoteExecutor
+	compose  *containers.ComposeProject
+	deployer *deploy.Deployer
+}
+
+// newDeployTarget connects to host and creates its deployer from the flags.
+// Each host gets its own compose project, as deployers record remote paths
+// on it.
+func newDeployTarget(host string, sshKey *bt_ssh.SSHKey, localWorkDir string, smokeSuite *deploy.SmokeSuite) (deployTarget, error) {
+	target := deployTarget{host: host}
+
+	// Load docker-compose configuration
+	compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
+	if err != nil {
+		return target, fmt.Errorf("failed to load docker-compose.yml: %w", err)
+	}
+	logging.Logger.Debug("Loaded compose configuration ",
+		"services", compose.Project.Services,
+		"project", compose.Project.Name)
+	target.compose = compose
+
+	// Initialize executors
+	localExecutor := core.NewLocalExecutor()
+	remoteExecutor, err := newRemoteExecutor(host, sshKey)
+	if err != nil {
+		return target, err
+	}
+	target.executor = remoteExecutor
+
+	deployer, err := deploy.NewDeployer(localExecutor, remoteExecutor, compose, localWorkDir, remoteWorkDir)
+	if err != nil {
+		return target, fmt.Errorf("failed to create deployer for %s: %w", host, err)
+	}
+	backend, err := state.ParseBackend(stateBackend, host, remoteExecutor, remoteWorkDir)
+	if err != nil {
+		return target, err
+	}
+	deployer.SetStateBackend(backend)
+	keyProvider, err := state.ParseKeyProvider(stateKey)
+	if err != nil {
+		return target, err
+	}
+	deployer.SetStateKey(keyProvider)
+	if len(certResolvers) > 0 || defaultCertResolver != "" {
+		deployer.SetTLSPolicy(traefik.TraefikTLSPolicy{
+			Resolvers:       certResolvers,
+			DefaultResolver: defaultCertResolver,
+			EntryPoints:     tlsEntryPoints,
+		})
+	}
+	deployer.SetHealthThresholds(health.HealthThresholds{
+		Success: healthSuccessThreshold,
+		Failure: healthFailureThreshold,
+	})
+	deployer.SetConnectionDrainOptions(loadbalancer.ConnectionDrainOptions{
+		Timeout: connectionDrainTimeout,
+	})
+	if stickySessions {
+		deployer.SetSessionOptions(loadbalancer.SessionOptions{
+			Cookie: &traefik.TraefikServiceWeightedStickyCookie{
+				Name:     stickyCookie,
+				HTTPOnly: true,
+			},
+			DrainTimeout: drainTimeout,
+		})
+	}
+	if smokeSuite != nil {
+		deployer.SetSmokeSuite(smokeSuite)
+	}
+
+	deployer.SetPruneVolumes(pruneVolumes)
+	deployer.SetResume(resumeDeploy)
+	deployer.SetSkipRollback(noRollback)
+	deployer.SetServices(deployServices, withDependencies)
+
+	target.deployer = deployer
+	return target, nil
+}
+
+// watchTargets watches every host's deployment until ctx is done, rolling
+// back those that turn unhealthy
+func watchTargets(ctx context.Context, targets []deployTarget, window time.Duration) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target deployTarget) {
+			defer wg.Done()
+			if err := runWatchdog(ctx, target.host, target.executor, target.compose, window); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", target.host, err)
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
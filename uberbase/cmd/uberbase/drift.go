@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/deploy"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	driftFix           bool
+	driftTraefikAPIURL string
+)
+
+func getDriftCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drift [flags] host",
+		Short: "Compare a host with its recorded deployment state",
+		Long: `Compare a host with its recorded deployment state.
+
+Reports recorded containers that are missing, stopped or running another
+image than the one deployed, containers of deployed services that are not
+recorded, and routers and services Traefik serves that differ from the
+recorded routing and the platform's dynamic config files. Exits non-zero when
+the host has drifted, for use in CI.
+
+With --fix, the host is reconciled back to the recorded state: containers are
+brought back up on their recorded images, unrecorded ones are removed and the
+recorded routing is saved again for uberbase serve to provide to Traefik.
+Dynamic config files are never changed.
+
+Examples:
+  uberbase drift prod.example.com
+  uberbase drift prod.example.com --fix`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := args[0]
+
+			if err := findComposeFile(); err != nil {
+				return err
+			}
+			compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
+			if err != nil {
+				return fmt.Errorf("failed to load docker-compose.yml: %w", err)
+			}
+
+			executor, err := connectHost(host)
+			if err != nil {
+				return err
+			}
+
+			detector, err := deploy.NewDriftDetector(executor, compose, remoteWorkDir)
+			if err != nil {
+				return fmt.Errorf("failed to create drift detector: %w", err)
+			}
+			backend, err := state.ParseBackend(stateBackend, host, executor, remoteWorkDir)
+			if err != nil {
+				return err
+			}
+			detector.SetStateBackend(backend)
+			detector.SetTraefikAPIURL(driftTraefikAPIURL)
+			keyProvider, err := state.ParseKeyProvider(stateKey)
+			if err != nil {
+				return err
+			}
+			detector.SetStateKey(keyProvider)
+
+			report, err := detector.Detect()
+			if err != nil {
+				return fmt.Errorf("failed to detect drift: %w", err)
+			}
+			report.Print(cmd.OutOrStdout())
+			if !report.Drifted() {
+				return nil
+			}
+			if !driftFix {
+				return fmt.Errorf("%s has drifted from its recorded state", host)
+			}
+
+			if err := detector.Fix(report); err != nil {
+				return fmt.Errorf("failed to fix drift: %w", err)
+			}
+			report, err = detector.Detect()
+			if err != nil {
+				return fmt.Errorf("failed to detect drift: %w", err)
+			}
+			report.Print(cmd.OutOrStdout())
+			if report.Drifted() {
+				return fmt.Errorf("%s still differs from its recorded state", host)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&composePath, "file", "f", "", "Path to docker-compose.yml (default: ./docker-compose.yml)")
+	cmd.Flags().BoolVar(&driftFix, "fix", false, "Reconcile the host back to its recorded state")
+	cmd.Flags().StringVar(&driftTraefikAPIURL, "traefik-api-url", "http://localhost:8080/api", "Traefik API URL, as reached from the host")
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
+
+	return cmd
+}
@@ -23,6 +23,13 @@ func init() {
 	rootCmd.AddCommand(getContainerCmd())
 	rootCmd.AddCommand(getStartCmd())
 	rootCmd.AddCommand(getStopCmd())
+	rootCmd.AddCommand(getTraefikCmd())
+	rootCmd.AddCommand(getMaintenanceCmd())
+	rootCmd.AddCommand(getCertsCmd())
+	rootCmd.AddCommand(getWatchCmd())
+	rootCmd.AddCommand(getDriftCmd())
+	rootCmd.AddCommand(getStatusCmd())
+	rootCmd.AddCommand(getRecoverCmd())
 }
 
 // set up signal handling
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/loadbalancer"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maintenanceServices []string
+	maintenanceMode     string
+	maintenanceURL      string
+)
+
+func getMaintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance on|off host",
+		Short: "Put a host's services into or out of maintenance",
+		Long: `Put a host's services into or out of maintenance.
+
+Routers for the selected services are switched in the active Traefik config,
+which Traefik picks up on its next poll. No containers are restarted. The
+original routers are kept in the deployment state and restored by "off".
+
+Modes:
+  service   route traffic to the maintenance url instead of the service
+  errors    serve the maintenance url for 5xx responses from the service
+  redirect  redirect all requests to the maintenance url, which must be on
+            another host than the routers it redirects
+
+Examples:
+  uberbase maintenance on example.com --url http://maintenance:8080
+  uberbase maintenance on example.com --service api --mode redirect --url https://status.example.com
+  uberbase maintenance off example.com`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			action, host := args[0], args[1]
+			if action != "on" && action != "off" {
+				return fmt.Errorf("unknown action %q, expected on or off", action)
+			}
+
+			executor, err := connectHost(host)
+			if err != nil {
+				return err
+			}
+
+			stateManager, err := newStateManager(host, executor)
+			if err != nil {
+				return err
+			}
+			current, err := stateManager.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load state: %w", err)
+			}
+			if current.Traefik == nil || current.Traefik.Active == nil {
+				return fmt.Errorf("%s has no active deployment", host)
+			}
+
+			active := current.Traefik.Active
+			if current.Maintenance != nil {
+				active, err = loadbalancer.DisableMaintenance(active, current.Maintenance)
+				if err != nil {
+					return fmt.Errorf("failed to restore routers: %w", err)
+				}
+			}
+
+			if action == "off" {
+				if current.Maintenance == nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s is not in maintenance\n", host)
+					return nil
+				}
+				if err := stateManager.SetMaintenance(nil, active); err != nil {
+					return fmt.Errorf("failed to disable maintenance: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is out of maintenance\n", host)
+				return nil
+			}
+
+			if maintenanceURL == "" {
+				return fmt.Errorf("--url is required to enable maintenance")
+			}
+			maintenance := &state.MaintenanceState{
+				Mode:     maintenanceMode,
+				URL:      maintenanceURL,
+				Services: maintenanceServices,
+				Since:    time.Now().UTC().Format(time.RFC3339),
+			}
+			maintained, err := loadbalancer.EnableMaintenance(active, current.Traefik.Tag, maintenance)
+			if err != nil {
+				return fmt.Errorf("failed to enable maintenance: %w", err)
+			}
+			if problems := maintained.Validate(); len(problems) > 0 {
+				return fmt.Errorf("invalid maintenance config: %w", errors.Join(problems...))
+			}
+			if err := stateManager.SetMaintenance(maintenance, maintained); err != nil {
+				return fmt.Errorf("failed to enable maintenance: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is in maintenance (%d routers)\n", host, len(maintenance.OriginalRouters))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&maintenanceServices, "service", nil, "Service to put into maintenance (repeatable, defaults to all)")
+	cmd.Flags().StringVar(&maintenanceMode, "mode", state.MaintenanceModeService, "Maintenance mode: service, errors or redirect")
+	cmd.Flags().StringVar(&maintenanceURL, "url", "", "URL of the maintenance page")
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
+
+	return cmd
+}
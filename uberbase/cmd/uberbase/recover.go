@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/deploy"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recoverComplete bool
+	recoverRollback bool
+)
+
+func getRecoverCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recover [flags] host",
+		Short: "Complete or roll back an interrupted deploy",
+		Long: `Complete or roll back an interrupted deploy.
+
+Every deploy journals each phase on the host before it runs it. When a deploy
+is interrupted, by a lost connection or a killed process, the journal is left
+behind and further deploys refuse to start until it is recovered.
+
+By default a deploy that had started removing the containers it replaced is
+completed, and any other is rolled back: traffic is routed back to the
+previous deployment and the new containers removed.
+
+Examples:
+  uberbase recover prod.example.com
+  uberbase recover prod.example.com --rollback`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := args[0]
+
+			mode := deploy.RecoverAuto
+			switch {
+			case recoverComplete && recoverRollback:
+				return fmt.Errorf("--complete and --rollback cannot be used together")
+			case recoverComplete:
+				mode = deploy.RecoverComplete
+			case recoverRollback:
+				mode = deploy.RecoverRollback
+			}
+
+			if err := findComposeFile(); err != nil {
+				return err
+			}
+			compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
+			if err != nil {
+				return fmt.Errorf("failed to load docker-compose.yml: %w", err)
+			}
+
+			executor, err := connectHost(host)
+			if err != nil {
+				return err
+			}
+
+			recovery, err := deploy.NewRecovery(executor, compose, remoteWorkDir)
+			if err != nil {
+				return fmt.Errorf("failed to create recovery: %w", err)
+			}
+			backend, err := state.ParseBackend(stateBackend, host, executor, remoteWorkDir)
+			if err != nil {
+				return err
+			}
+			recovery.SetStateBackend(backend)
+			keyProvider, err := state.ParseKeyProvider(stateKey)
+			if err != nil {
+				return err
+			}
+			recovery.SetStateKey(keyProvider)
+
+			journal, err := recovery.Recover(mode)
+			if err != nil {
+				return fmt.Errorf("failed to recover deploy: %w", err)
+			}
+			if journal == nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s has no interrupted deploy\n", host)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&composePath, "file", "f", "", "Path to docker-compose.yml (default: ./docker-compose.yml)")
+	cmd.Flags().BoolVar(&recoverComplete, "complete", false, "Complete the deploy, if traffic was routed to it")
+	cmd.Flags().BoolVar(&recoverRollback, "rollback", false, "Roll the deploy back, if it has not removed the containers it replaced")
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
+
+	return cmd
+}
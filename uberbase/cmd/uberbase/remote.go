@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	bt_ssh "github.com/bluetongueai/uberbase/uberbase/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// addSSHFlags registers the flags used by commands that connect to a host
+func addSSHFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&sshUser, "ssh-user", "root", "SSH user")
+	cmd.PersistentFlags().IntVar(&sshPort, "ssh-port", 22, "SSH port")
+	cmd.PersistentFlags().StringVarP(&sshKeyFile, "identity-file", "i", "", "SSH private key file")
+	cmd.PersistentFlags().StringVar(&sshKeyEnv, "ssh-key-env", "SSH_PRIVATE_KEY", "Environment variable containing SSH key")
+	cmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
+}
+
+// loadSSHKey loads the SSH key from the identity file, or from the
+// environment when no file is given
+func loadSSHKey() (*bt_ssh.SSHKey, error) {
+	sshKeySource := bt_ssh.File
+	var sshKeyData string
+	if sshKeyFile == "" {
+		sshKeySource = bt_ssh.Environment
+		sshKeyData = os.Getenv("SSH_PRIVATE_KEY")
+		logging.Logger.Debug("Using SSH key from environment")
+	} else {
+		logging.Logger.Debug("Using SSH key from filepath", sshKeyFile)
+	}
+
+	if sshKeyData == "" && sshKeyFile == "" {
+		return nil, fmt.Errorf("either SSH key file (-i) or SSH key environment variable (SSH_PRIVATE_KEY) must be provided")
+	}
+
+	sshKey := bt_ssh.NewSSHKey(sshKeySource, sshKeyEnv, sshKeyFile)
+	if _, err := sshKey.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load SSH key: %w", err)
+	}
+	logging.Logger.Debug("SSH key loaded successfully", "source", sshKeySource)
+	return sshKey, nil
+}
+
+// newRemoteExecutor creates an executor for host using the SSH flags
+func newRemoteExecutor(host string, sshKey *bt_ssh.SSHKey) (*core.RemoteExecutor, error) {
+	remoteExecutor, err := core.NewRemoteExecutor(bt_ssh.SSHConfig{
+		Host: host,
+		User: sshUser,
+		Port: sshPort,
+		Key:  *sshKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote executor: %w", err)
+	}
+	return remoteExecutor, nil
+}
+
+// connectHost loads the SSH key and connects to host, for commands that only
+// need to talk to the remote rather than deploy to it
+func connectHost(host string) (*core.RemoteExecutor, error) {
+	if debug {
+		logging.SetDebugLevel()
+	}
+
+	sshKey, err := loadSSHKey()
+	if err != nil {
+		return nil, err
+	}
+
+	remoteExecutor, err := newRemoteExecutor(host, sshKey)
+	if err != nil {
+		return nil, err
+	}
+	if !remoteExecutor.Test() {
+		return nil, fmt.Errorf("could not connect to %s", host)
+	}
+	return remoteExecutor, nil
+}
@@ -8,27 +8,41 @@ import (
 	"os"
 	"strings"
 
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 	f "github.com/bluetongueai/uberbase/uberbase/pkg/functions"
 	h "github.com/bluetongueai/uberbase/uberbase/pkg/http"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/loadbalancer"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
 )
 
 type ApiConfig struct {
-	Port  int      `json:"port"`
-	Build string   `json:"build"`
-	Pull  []string `json:"pull"`
+	Port      int      `json:"port"`
+	Build     string   `json:"build"`
+	Pull      []string `json:"pull"`
+	DeployDir string   `json:"deployDir"`
+	// State is where deployment state is kept, as for the deploy --state
+	// flag, and Host the name this server's state is kept under
+	State string `json:"state"`
+	Host  string `json:"host"`
+	// StateKey decrypts secrets in the state, as for the --state-key flag
+	StateKey string `json:"stateKey"`
+	// TraefikDir holds the dynamic configs served alongside the active
+	// deployment's routing, such as the platform routes
+	TraefikDir string `json:"traefikDir"`
 }
 
 type FunctionRequest struct {
-	Args    *[]string          `json:args`
-	Detatch *bool              `json:detatch`
-	Env     *map[string]string `json:env`
+	Args    *[]string          `json:"args"`
+	Detatch *bool              `json:"detatch"`
+	Env     *map[string]string `json:"env"`
 }
 
 type StopRequest struct {
-	ContainerId string `json:containerId`
+	ContainerId string `json:"containerId"`
 }
 
 func getServeCmd() *cobra.Command {
@@ -55,6 +69,39 @@ func getServeCmd() *cobra.Command {
 			s.AddRoute("POST", "/api/v1/functions/stop", stopHandler)
 			s.AddRoute("POST", "/api/v1/functions/run/*name", functionHandler)
 
+			deployDir := apiConfig.DeployDir
+			if deployDir == "" {
+				deployDir = remoteWorkDir
+			}
+			stateManager := state.NewStateManager(deployDir, core.NewLocalExecutor())
+			if apiConfig.State != "" {
+				backend, err := state.ParseBackend(apiConfig.State, apiConfig.Host, core.NewLocalExecutor(), deployDir)
+				if err != nil {
+					return fmt.Errorf("failed to configure state backend: %w", err)
+				}
+				stateManager.SetBackend(backend)
+			}
+			keyProvider, err := state.ParseKeyProvider(apiConfig.StateKey)
+			if err != nil {
+				return fmt.Errorf("failed to configure state key: %w", err)
+			}
+			stateManager.SetKeyProvider(keyProvider)
+			traefikDir := apiConfig.TraefikDir
+			if traefikDir == "" {
+				traefikDir = traefik.DynamicConfigPath
+			}
+			s.AddRoute("GET", "/api/v1/traefik/config", h.TraefikConfigHandler(func() (*traefik.TraefikDynamicConfiguration, error) {
+				current, err := stateManager.Load()
+				if err != nil {
+					return nil, err
+				}
+				configs, err := traefik.LoadTraefikDynamicConfigsFrom(traefikDir)
+				if err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("failed to load dynamic configs: %w", err)
+				}
+				return loadbalancer.ProviderConfig(current, configs)
+			}))
+
 			logging.Logger.Infof("Starting server on port %d", apiConfig.Port)
 			s.Start()
 
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stateBackend string
+	stateKey     string
+)
+
+// addStateFlags registers the flags used by commands that read or write a
+// host's deployment state
+func addStateFlags(cmd *cobra.Command) {
+	backend := os.Getenv("UBERBASE_STATE")
+	if backend == "" {
+		backend = "remote"
+	}
+	cmd.PersistentFlags().StringVar(&stateBackend, "state", backend, "Where deployment state is kept: remote, file:///dir, s3://bucket/prefix?endpoint=url or postgres://url (env UBERBASE_STATE)")
+	cmd.PersistentFlags().StringVar(&stateKey, "state-key", os.Getenv("UBERBASE_STATE_KEY"), "Key encrypting secrets in the state: vault:transit-key, age:/path/identity.txt or passphrase, read from UBERBASE_STATE_PASSPHRASE (env UBERBASE_STATE_KEY)")
+}
+
+// newStateManager creates a manager for host's state using the state flags
+func newStateManager(host string, executor core.Executor) (*state.StateManager, error) {
+	backend, err := state.ParseBackend(stateBackend, host, executor, remoteWorkDir)
+	if err != nil {
+		return nil, err
+	}
+	keyProvider, err := state.ParseKeyProvider(stateKey)
+	if err != nil {
+		return nil, err
+	}
+	stateManager := state.NewStateManager(remoteWorkDir, executor)
+	stateManager.SetBackend(backend)
+	stateManager.SetKeyProvider(keyProvider)
+	return stateManager, nil
+}
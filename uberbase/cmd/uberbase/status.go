@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/deploy"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusOutput        string
+	statusTraefikAPIURL string
+)
+
+func getStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [flags] host",
+		Short: "Show what is deployed on a host",
+		Long: `Show what is deployed on a host.
+
+Prints the deployed tag and when it was deployed, the container of each
+service with its image, state, health and uptime, and the active routes with
+their status in Traefik and the health of their servers. Router status is
+read from Traefik's API, which needs the API enabled on the host.
+
+Examples:
+  uberbase status prod.example.com
+  uberbase status prod.example.com --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := args[0]
+			if statusOutput != "text" && statusOutput != "json" {
+				return fmt.Errorf("unknown output %q, expected text or json", statusOutput)
+			}
+
+			if err := findComposeFile(); err != nil {
+				return err
+			}
+			compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
+			if err != nil {
+				return fmt.Errorf("failed to load docker-compose.yml: %w", err)
+			}
+
+			executor, err := connectHost(host)
+			if err != nil {
+				return err
+			}
+
+			inspector, err := deploy.NewStatusInspector(executor, compose, remoteWorkDir, statusTraefikAPIURL)
+			if err != nil {
+				return fmt.Errorf("failed to create status inspector: %w", err)
+			}
+			backend, err := state.ParseBackend(stateBackend, host, executor, remoteWorkDir)
+			if err != nil {
+				return err
+			}
+			inspector.SetStateBackend(backend)
+			keyProvider, err := state.ParseKeyProvider(stateKey)
+			if err != nil {
+				return err
+			}
+			inspector.SetStateKey(keyProvider)
+
+			status, err := inspector.Status(context.Background(), host)
+			if err != nil {
+				return fmt.Errorf("failed to get status: %w", err)
+			}
+
+			if statusOutput == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(status)
+			}
+			status.Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&composePath, "file", "f", "", "Path to docker-compose.yml (default: ./docker-compose.yml)")
+	cmd.Flags().StringVarP(&statusOutput, "output", "o", "text", "Output format: text or json")
+	cmd.Flags().StringVar(&statusTraefikAPIURL, "traefik-api-url", "http://localhost:8080/api", "Traefik API URL, as reached from the host")
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
+
+	return cmd
+}
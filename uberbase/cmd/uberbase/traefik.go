@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func getTraefikCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "traefik",
+		Short: "Inspect Traefik configuration",
+		Long:  `Inspect and check Traefik configuration used by deployments.`,
+	}
+
+	cmd.AddCommand(getTraefikLintCmd())
+	cmd.AddCommand(getTraefikStaticCmd())
+
+	return cmd
+}
+
+func getTraefikLintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint dir",
+		Short: "Validate the Traefik dynamic configs in a directory",
+		Long: `Validate the Traefik dynamic configs in a directory.
+
+The files are merged as Traefik's file provider would merge them, then checked
+for undefined services and middlewares, invalid rules, duplicate entrypoints
+and servers without ports.
+
+Examples:
+  uberbase traefik lint ./traefik/dynamic`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configs, err := traefik.LoadTraefikDynamicConfigsFrom(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load dynamic configs: %w", err)
+			}
+
+			merged, err := traefik.MergeDynamicConfigs(configs)
+			if err != nil {
+				return fmt.Errorf("failed to merge dynamic configs: %w", err)
+			}
+
+			problems := merged.Validate()
+			for _, problem := range problems {
+				fmt.Fprintln(cmd.OutOrStdout(), problem)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problems in %d configs", len(problems), len(configs))
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%d configs ok\n", len(configs))
+			return nil
+		},
+	}
+}
+
+func getTraefikStaticCmd() *cobra.Command {
+	settings := traefik.DefaultTraefikStaticSettings()
+	settings.HTTPPort = envInt("UBERBASE_TRAEFIK_HTTP_PORT", settings.HTTPPort)
+	settings.HTTPSPort = envInt("UBERBASE_TRAEFIK_HTTPS_PORT", settings.HTTPSPort)
+	settings.ACMEEmail = os.Getenv("UBERBASE_ADMIN_EMAIL")
+	if port := os.Getenv("UBERBASE_FUNCTIONS_PORT"); port != "" {
+		settings.ProviderEndpoint = fmt.Sprintf("http://localhost:%s/api/v1/traefik/config", port)
+	}
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "static",
+		Short: "Generate the Traefik static config",
+		Long: `Generate the Traefik static config from uberbase settings.
+
+Defaults are read from the UBERBASE_* environment, as used by bin/configure.
+The config is validated before it is written.
+
+With a provider endpoint, the dynamic directory is loaded through it, as
+uberbase serve serves the directory along with the active deployment.
+Otherwise Traefik's file provider watches the directory.
+
+Examples:
+  uberbase traefik static -o ./_configs/traefik/static/traefik.yml
+  uberbase traefik static --http-port 80 --https-port 443 --dashboard`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := traefik.NewTraefikStaticConfiguration(settings)
+			if problems := config.Validate(); len(problems) > 0 {
+				return fmt.Errorf("invalid static config: %w", errors.Join(problems...))
+			}
+
+			if output == "" {
+				content, err := yaml.Marshal(config)
+				if err != nil {
+					return fmt.Errorf("failed to render static config: %w", err)
+				}
+				fmt.Fprint(cmd.OutOrStdout(), string(content))
+				return nil
+			}
+			if err := config.WriteToFile(output); err != nil {
+				return fmt.Errorf("failed to write static config: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the config to (default: stdout)")
+	cmd.Flags().IntVar(&settings.HTTPPort, "http-port", settings.HTTPPort, "Port of the HTTP entrypoint")
+	cmd.Flags().IntVar(&settings.HTTPSPort, "https-port", settings.HTTPSPort, "Port of the HTTPS entrypoint")
+	cmd.Flags().BoolVar(&settings.RedirectToHTTPS, "redirect-https", settings.RedirectToHTTPS, "Redirect HTTP to HTTPS")
+	cmd.Flags().StringVar(&settings.DynamicConfigDir, "dynamic-dir", settings.DynamicConfigDir, "Directory watched by the file provider, when there is no provider endpoint")
+	cmd.Flags().StringVar(&settings.ProviderEndpoint, "provider-endpoint", settings.ProviderEndpoint, "Endpoint polled by the HTTP provider")
+	cmd.Flags().DurationVar(&settings.ProviderPollInterval, "provider-poll-interval", settings.ProviderPollInterval, "Poll interval of the HTTP provider")
+	cmd.Flags().BoolVar(&settings.Dashboard, "dashboard", settings.Dashboard, "Enable the API and dashboard")
+	cmd.Flags().IntVar(&settings.MetricsPort, "metrics-port", settings.MetricsPort, "Port serving Prometheus metrics (0 to disable)")
+	cmd.Flags().BoolVar(&settings.AccessLog, "access-log", settings.AccessLog, "Enable access logs")
+	cmd.Flags().StringVar(&settings.AccessLogPath, "access-log-path", settings.AccessLogPath, "Access log file (default: stdout)")
+	cmd.Flags().StringVar(&settings.LogLevel, "log-level", settings.LogLevel, "Traefik log level")
+	cmd.Flags().StringVar(&settings.ACMEEmail, "acme-email", settings.ACMEEmail, "Email for Let's Encrypt (disables ACME when empty)")
+	cmd.Flags().StringVar(&settings.ACMEStorage, "acme-storage", settings.ACMEStorage, "File Let's Encrypt certificates are stored in")
+	cmd.Flags().BoolVar(&settings.ACMEHTTPChallenge, "acme-http-challenge", settings.ACMEHTTPChallenge, "Answer Let's Encrypt challenges on the HTTP entrypoint instead of with TLS-ALPN")
+	cmd.Flags().StringArrayVar(&settings.RootCAs, "root-ca", settings.RootCAs, "CA trusted for TLS to services (repeatable)")
+
+	return cmd
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
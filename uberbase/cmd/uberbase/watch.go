@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/deploy"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/events"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval         time.Duration
+	watchFailureThreshold int
+	watchRollbackWindow   time.Duration
+	watchWebhookURL       string
+)
+
+func getWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [flags] host",
+		Short: "Watch a host's deployment and roll back when it turns unhealthy",
+		Long: `Watch a host's deployment and roll back when it turns unhealthy.
+
+The active deployment's containers, uberbase.health probes and routed servers
+are checked every interval. When a server fails --watch-failure-threshold
+checks in a row within --window of the deploy, the previous deployment is
+brought back up and traffic routed to it. Later failures are only reported.
+Events are logged, appended to events.log on the host and posted to --webhook.
+
+Examples:
+  uberbase watch prod.example.com
+  uberbase watch prod.example.com --window 30m --webhook https://hooks.example.com/deploys`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := args[0]
+
+			if err := findComposeFile(); err != nil {
+				return err
+			}
+			compose, err := containers.NewComposeProject(composePath, "uberbase-deploy")
+			if err != nil {
+				return fmt.Errorf("failed to load docker-compose.yml: %w", err)
+			}
+
+			executor, err := connectHost(host)
+			if err != nil {
+				return err
+			}
+
+			return runWatchdog(context.Background(), host, executor, compose, watchRollbackWindow)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&composePath, "file", "f", "", "Path to docker-compose.yml (default: ./docker-compose.yml)")
+	cmd.PersistentFlags().DurationVar(&watchRollbackWindow, "window", 15*time.Minute, "How long after a deploy an unhealthy deployment is rolled back (0 for any time)")
+	addWatchFlags(cmd)
+	addStateFlags(cmd)
+	addSSHFlags(cmd)
+
+	return cmd
+}
+
+// addWatchFlags registers the flags used by commands that run the watchdog
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVar(&watchInterval, "watch-interval", 30*time.Second, "How often the deployment's health is checked")
+	cmd.PersistentFlags().IntVar(&watchFailureThreshold, "watch-failure-threshold", 3, "Consecutive failed checks of a server before the deployment is unhealthy")
+	cmd.PersistentFlags().StringVar(&watchWebhookURL, "webhook", "", "URL to post deployment events to as JSON")
+}
+
+// runWatchdog watches host's deployment until ctx is done
+func runWatchdog(ctx context.Context, host string, executor core.Executor, compose *containers.ComposeProject, window time.Duration) error {
+	emitters := events.Emitters{events.LogEmitter{}, events.NewFileEmitter(executor, remoteWorkDir)}
+	if watchWebhookURL != "" {
+		emitters = append(emitters, &events.WebhookEmitter{URL: watchWebhookURL})
+	}
+
+	watchdog, err := deploy.NewWatchdog(executor, compose, remoteWorkDir, deploy.WatchOptions{
+		Host:             host,
+		Interval:         watchInterval,
+		FailureThreshold: watchFailureThreshold,
+		Window:           window,
+		Emitter:          emitters,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create watchdog: %w", err)
+	}
+	backend, err := state.ParseBackend(stateBackend, host, executor, remoteWorkDir)
+	if err != nil {
+		return err
+	}
+	watchdog.SetStateBackend(backend)
+	keyProvider, err := state.ParseKeyProvider(stateKey)
+	if err != nil {
+		return err
+	}
+	watchdog.SetStateKey(keyProvider)
+	return watchdog.Run(ctx)
+}
@@ -0,0 +1,251 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caName     = "uberbase-ca"
+	caCertFile = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+	// caBundleFile is the CA certificate under the name curl and Traefik's
+	// serversTransport rootCAs expect
+	caBundleFile = "ca.crt"
+
+	DefaultValidity = 365 * 24 * time.Hour
+)
+
+// CA is a local certificate authority issuing certificates for internal
+// hostnames, such as the vault, registry and postgres hosts.
+type CA struct {
+	Cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+// LoadOrCreateCA loads the CA kept in dir, creating it when dir has none.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	ca, err := LoadCA(dir)
+	if err == nil {
+		return ca, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return CreateCA(dir, DefaultValidity)
+}
+
+// LoadCA loads the CA certificate and key kept in dir.
+func LoadCA(dir string) (*CA, error) {
+	cert, err := readCertificate(filepath.Join(dir, caCertFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA certificate: %w", err)
+	}
+	key, err := readKey(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA key: %w", err)
+	}
+	return &CA{Cert: cert, key: key, dir: dir}, nil
+}
+
+// CreateCA creates a new CA in dir, replacing any CA already there.
+func CreateCA(dir string, validity time.Duration) (*CA, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create CA directory: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: caName},
+		DNSNames:              []string{caName},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	if err := writeKey(filepath.Join(dir, caKeyFile), key); err != nil {
+		return nil, err
+	}
+	for _, file := range []string{caCertFile, caBundleFile} {
+		if err := writeCertificate(filepath.Join(dir, file), der); err != nil {
+			return nil, err
+		}
+	}
+	return &CA{Cert: cert, key: key, dir: dir}, nil
+}
+
+// Dir returns the directory the CA and its certificates are kept in.
+func (ca *CA) Dir() string {
+	return ca.dir
+}
+
+// Issue issues a server certificate named name for hosts, which may be DNS
+// names or IP addresses, and writes it to the CA directory as
+// name-cert.pem and name-key.pem.
+func (ca *CA) Issue(name string, hosts []string, validity time.Duration) (*Certificate, error) {
+	if name == "" {
+		return nil, fmt.Errorf("certificate name cannot be empty")
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("certificate %s needs at least one host", name)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key for %s: %w", name, err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(validity)
+	// a certificate cannot outlive the CA that signed it
+	if notAfter.After(ca.Cert.NotAfter) {
+		notAfter = ca.Cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "uberbase-" + name},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate for %s: %w", name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate for %s: %w", name, err)
+	}
+
+	issued := newCertificate(ca.dir, name, cert)
+	if err := writeKey(issued.KeyFile, key); err != nil {
+		return nil, err
+	}
+	if err := writeCertificate(issued.CertFile, der); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Renew re-issues the named certificate for the same hosts.
+func (ca *CA) Renew(name string, validity time.Duration) (*Certificate, error) {
+	existing, err := LoadCertificate(ca.dir, name)
+	if err != nil {
+		return nil, err
+	}
+	return ca.Issue(name, existing.Hosts(), validity)
+}
+
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key: %w", err)
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeCertificate(path string, der []byte) error {
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func readKey(path string) (*ecdsa.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", path)
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	// openssl genpkey writes PKCS#8
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ECDSA key", path)
+	}
+	return key, nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cert, nil
+}
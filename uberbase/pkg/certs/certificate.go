@@ -0,0 +1,89 @@
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+const (
+	certSuffix = "-cert.pem"
+	keySuffix  = "-key.pem"
+)
+
+// Certificate is a certificate issued by the local CA and the files it is
+// kept in.
+type Certificate struct {
+	Name     string
+	CertFile string
+	KeyFile  string
+	Cert     *x509.Certificate
+}
+
+func newCertificate(dir, name string, cert *x509.Certificate) *Certificate {
+	return &Certificate{
+		Name:     name,
+		CertFile: filepath.Join(dir, name+certSuffix),
+		KeyFile:  filepath.Join(dir, name+keySuffix),
+		Cert:     cert,
+	}
+}
+
+// LoadCertificate loads the named certificate from dir.
+func LoadCertificate(dir, name string) (*Certificate, error) {
+	cert, err := readCertificate(filepath.Join(dir, name+certSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", name, err)
+	}
+	return newCertificate(dir, name, cert), nil
+}
+
+// ListCertificates returns the certificates kept in dir, sorted by name.
+func ListCertificates(dir string) ([]*Certificate, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificates: %w", err)
+	}
+
+	certificates := []*Certificate{}
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), certSuffix)
+		if file.IsDir() || !ok {
+			continue
+		}
+		certificate, err := LoadCertificate(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	sort.Slice(certificates, func(i, j int) bool {
+		return certificates[i].Name < certificates[j].Name
+	})
+	return certificates, nil
+}
+
+// Hosts returns the DNS names and IP addresses the certificate is valid for.
+func (c *Certificate) Hosts() []string {
+	hosts := append([]string{}, c.Cert.DNSNames...)
+	for _, ip := range c.Cert.IPAddresses {
+		hosts = append(hosts, ip.String())
+	}
+	return hosts
+}
+
+// ExpiresWithin reports whether the certificate expires within d.
+func (c *Certificate) ExpiresWithin(d time.Duration) bool {
+	return time.Until(c.Cert.NotAfter) < d
+}
+
+// Traefik returns the certificate as Traefik loads it into a TLS store.
+func (c *Certificate) Traefik() traefik.TraefikCertificate {
+	return traefik.TraefikCertificate{CertFile: c.CertFile, KeyFile: c.KeyFile}
+}
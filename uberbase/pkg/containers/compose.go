@@ -2,6 +2,7 @@ package containers
 
 import (
 	"context"
+	"sort"
 
 	"github.com/compose-spec/compose-go/v2/cli"
 	"github.com/compose-spec/compose-go/v2/types"
@@ -36,3 +37,36 @@ func NewComposeProject(composeFilePath string, projectName string) (*ComposeProj
 		Project:       project,
 	}, nil
 }
+
+// SelectServices returns the names of services, and of the services they
+// depend on when dependencies is set. Names not in the project are an error.
+func (c *ComposeProject) SelectServices(services []string, dependencies bool) ([]string, error) {
+	option := types.IgnoreDependencies
+	if dependencies {
+		option = types.IncludeDependencies
+	}
+	selected := []string{}
+	err := c.Project.ForEachService(services, func(name string, _ *types.ServiceConfig) error {
+		selected = append(selected, name)
+		return nil
+	}, option)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// selected reports whether service is among services, every service being
+// selected when there are none
+func selected(services []string, service string) bool {
+	if len(services) == 0 {
+		return true
+	}
+	for _, name := range services {
+		if name == service {
+			return true
+		}
+	}
+	return false
+}
@@ -3,28 +3,85 @@ package containers
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
+// ContainerHealthLog is the result of one run of a container's healthcheck
+type ContainerHealthLog struct {
+	Start    string `json:"Start"`
+	End      string `json:"End"`
+	ExitCode int    `json:"ExitCode"`
+	Output   string `json:"Output"`
+}
+
+// ContainerHealth is the healthcheck status of a container, nil when the
+// container has no healthcheck
+type ContainerHealth struct {
+	Status        string               `json:"Status"`
+	FailingStreak int                  `json:"FailingStreak"`
+	Log           []ContainerHealthLog `json:"Log"`
+}
+
 type ContainerState struct {
-	Status     string `json:"status"`
-	Running    bool   `json:"running"`
-	Paused     bool   `json:"paused"`
-	Restarting bool   `json:"restarting"`
-	OOMKilled  bool   `json:"oom_killed"`
-	Dead       bool   `json:"dead"`
-	StartedAt  string `json:"started_at"`
-	FinishedAt string `json:"finished_at"`
-	Pid        int    `json:"pid"`
-	ExitCode   int    `json:"exit_code"`
-	Error      string `json:"error"`
+	Status     string           `json:"Status"`
+	Running    bool             `json:"Running"`
+	Paused     bool             `json:"Paused"`
+	Restarting bool             `json:"Restarting"`
+	OOMKilled  bool             `json:"OOMKilled"`
+	Dead       bool             `json:"Dead"`
+	StartedAt  string           `json:"StartedAt"`
+	FinishedAt string           `json:"FinishedAt"`
+	Pid        int              `json:"Pid"`
+	ExitCode   int              `json:"ExitCode"`
+	Error      string           `json:"Error"`
+	Health     *ContainerHealth `json:"Health"`
+}
+
+// ContainerConfig is the configuration a container was created with
+type ContainerConfig struct {
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerNetwork is the container's attachment to a network
+type ContainerNetwork struct {
+	IPAddress string `json:"IPAddress"`
+}
+
+// ContainerNetworkSettings are the networks the container is attached to,
+// keyed by name
+type ContainerNetworkSettings struct {
+	Networks map[string]ContainerNetwork `json:"Networks"`
 }
 
 type ContainerInspectInfo struct {
-	ID    string         `json:"id"`
-	State ContainerState `json:"state"`
+	ID    string         `json:"Id"`
+	Name  string         `json:"Name"`
+	Image string         `json:"Image"`
+	State ContainerState `json:"State"`
+	// Config.Image is the image reference the container was created from,
+	// Image the ID of the image it runs
+	Config          ContainerConfig          `json:"Config"`
+	NetworkSettings ContainerNetworkSettings `json:"NetworkSettings"`
+}
+
+// IPAddress returns the container's address on the first of its networks,
+// by name, that gives it one
+func (i ContainerInspectInfo) IPAddress() string {
+	names := make([]string, 0, len(i.NetworkSettings.Networks))
+	for name := range i.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if address := i.NetworkSettings.Networks[name].IPAddress; address != "" {
+			return address
+		}
+	}
+	return ""
 }
 
 func (p *ContainerManager) GetContainerTag(service *types.ServiceConfig) (ContainerTag, error) {
@@ -40,8 +97,9 @@ func (p *ContainerManager) GetContainerTag(service *types.ServiceConfig) (Contai
 }
 
 func (p *ContainerManager) Inspect(containerID string) (ContainerInspectInfo, error) {
-	var inspectInfo ContainerInspectInfo
-	output, err := p.executor.ExecCompose("inspect " + containerID)
+	// inspect prints a list, with one entry per container asked for
+	var inspectInfo []ContainerInspectInfo
+	output, err := p.executor.Exec("inspect --type container " + containerID)
 	if err != nil {
 		return ContainerInspectInfo{}, fmt.Errorf("failed to inspect container: %w", err)
 	}
@@ -49,5 +107,24 @@ func (p *ContainerManager) Inspect(containerID string) (ContainerInspectInfo, er
 	if err != nil {
 		return ContainerInspectInfo{}, fmt.Errorf("failed to unmarshal inspect output: %w", err)
 	}
-	return inspectInfo, nil
+	if len(inspectInfo) == 0 {
+		return ContainerInspectInfo{}, fmt.Errorf("container %s not found", containerID)
+	}
+	return inspectInfo[0], nil
+}
+
+// ProjectContainers lists the names of the compose project's containers,
+// running or not
+func (p *ContainerManager) ProjectContainers() ([]string, error) {
+	output, err := p.executor.Exec("ps -a --filter label=com.docker.compose.project=" + p.Compose.Project.Name + " --format '{{.Names}}'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	names := []string{}
+	for _, name := range strings.Split(output, "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
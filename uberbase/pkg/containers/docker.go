@@ -2,6 +2,7 @@ package containers
 
 import (
 	"strings"
+	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 )
@@ -32,3 +33,7 @@ func (d *Docker) Exec(command string) (string, error) {
 func (d *Docker) ExecCompose(command string) (string, error) {
 	return d.executor.Exec(d.composePath + " " + command)
 }
+
+func (d *Docker) ExecComposeWithin(command string, timeout time.Duration) (string, error) {
+	return d.executor.Exec(withinTimeout(timeout) + d.composePath + " " + command)
+}
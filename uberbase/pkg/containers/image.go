@@ -7,10 +7,12 @@ import (
 	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
 )
 
-func (p *ContainerManager) Build(tag ContainerTag) (string, error) {
+// Build builds the images of services, or of every service when there are
+// none, tagged with tag
+func (p *ContainerManager) Build(tag ContainerTag, services ...string) (string, error) {
 	output := ""
 	for _, service := range p.Compose.Project.Services {
-		if service.Build == nil {
+		if service.Build == nil || !selected(services, service.Name) {
 			continue
 		}
 		image := utils.StripTag(service.Image)
@@ -40,10 +42,11 @@ func (p *ContainerManager) Build(tag ContainerTag) (string, error) {
 	return output, nil
 }
 
-func (p *ContainerManager) Pull(tag ContainerTag) (string, error) {
+// Pull pulls the images of services, or of every service when there are none
+func (p *ContainerManager) Pull(tag ContainerTag, services ...string) (string, error) {
 	output := ""
 	for _, service := range p.Compose.Project.Services {
-		if service.Image == "" {
+		if service.Image == "" || !selected(services, service.Name) {
 			continue
 		}
 		image := service.Image
@@ -59,10 +62,31 @@ func (p *ContainerManager) Pull(tag ContainerTag) (string, error) {
 	return output, nil
 }
 
-func (p *ContainerManager) Push(tag ContainerTag) (string, error) {
+// PullImages pulls each of images, as recorded for services that may be on
+// different tags
+func (p *ContainerManager) PullImages(images ...string) (string, error) {
+	output := ""
+	pulled := make(map[string]bool)
+	for _, image := range images {
+		if image == "" || pulled[image] {
+			continue
+		}
+		pulled[image] = true
+		pullOutput, err := p.executor.Exec("pull " + image)
+		if err != nil {
+			return "", fmt.Errorf("failed to pull image: %w", err)
+		}
+		output += string(pullOutput)
+	}
+	return output, nil
+}
+
+// Push pushes the images built for services, or for every service when there
+// are none
+func (p *ContainerManager) Push(tag ContainerTag, services ...string) (string, error) {
 	output := ""
 	for _, service := range p.Compose.Project.Services {
-		if service.Build == nil {
+		if service.Build == nil || !selected(services, service.Name) {
 			continue
 		}
 		image := utils.StripTag(service.Image)
@@ -75,6 +99,15 @@ func (p *ContainerManager) Push(tag ContainerTag) (string, error) {
 	return output, nil
 }
 
+// ImageID returns the ID of the local image image refers to
+func (p *ContainerManager) ImageID(image string) (string, error) {
+	output, err := p.executor.Exec("image inspect --format '{{.Id}}' " + image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
 func (p *ContainerManager) CompareTags(image string, firstTag ContainerTag, secondTag ContainerTag) (bool, error) {
 	firstTagHash, err := p.executor.Exec("image inspect --format '{{.Id}}' " + image + ":" + string(firstTag))
 	if err != nil {
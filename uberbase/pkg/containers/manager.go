@@ -3,8 +3,10 @@ package containers
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
@@ -19,6 +21,8 @@ type RegistryOptions struct {
 type ContainerExecutor interface {
 	Exec(command string) (string, error)
 	ExecCompose(command string) (string, error)
+	// ExecComposeWithin runs a compose command, killing it once timeout passes
+	ExecComposeWithin(command string, timeout time.Duration) (string, error)
 }
 
 type ContainerManager struct {
@@ -68,8 +72,14 @@ func (p *ContainerManager) Auth(opts RegistryOptions) (string, error) {
 	return p.executor.Exec("login " + opts.Registry + " -u " + opts.Username + " -p " + opts.Password)
 }
 
-func (p *ContainerManager) Up(composeOverrideFilePath string) (string, error) {
-	output, err := p.executor.ExecCompose("-f " + p.Compose.RemoteFilePath + " -f " + composeOverrideFilePath + " up -d")
+// Up brings up services, leaving the services they depend on as they are,
+// or every service when there are none
+func (p *ContainerManager) Up(composeOverrideFilePath string, services ...string) (string, error) {
+	command := "-f " + p.Compose.RemoteFilePath + " -f " + composeOverrideFilePath + " up -d"
+	if len(services) > 0 {
+		command += " --no-deps " + strings.Join(services, " ")
+	}
+	output, err := p.executor.ExecCompose(command)
 	if err != nil {
 		return "", fmt.Errorf("failed to up: %w", err)
 	}
@@ -84,6 +94,16 @@ func (p *ContainerManager) Down(containers []string, composeOverrideFilePath str
 	return string(output), nil
 }
 
+// Stop sends container its stop signal, SIGTERM unless configured otherwise,
+// and kills it if it has not exited after gracePeriod
+func (p *ContainerManager) Stop(container string, gracePeriod time.Duration) (string, error) {
+	output, err := p.executor.Exec(fmt.Sprintf("stop -t %d %s", int(gracePeriod.Seconds()), container))
+	if err != nil {
+		return "", fmt.Errorf("failed to stop: %w", err)
+	}
+	return string(output), nil
+}
+
 func (p *ContainerManager) Start(service *types.ServiceConfig, composeOverrideFilePath string) (string, error) {
 	output, err := p.executor.ExecCompose("-f " + p.Compose.RemoteFilePath + " -f " + composeOverrideFilePath + " up -d " + service.Name)
 	if err != nil {
@@ -104,10 +124,25 @@ func (p *ContainerManager) Run(service *types.ServiceConfig, command []string, p
 	return string(output), nil
 }
 
-func (p *ContainerManager) Exec(service *types.ServiceConfig, command []string, composeOverrideFilePath string) (string, error) {
-	output, err := p.executor.ExecCompose("-f " + p.Compose.RemoteFilePath + " -f " + composeOverrideFilePath + " exec " + service.Name + " " + strings.Join(command, " "))
+// Exec runs command in the service's container without a terminal, killing
+// it once timeout passes unless timeout is zero
+func (p *ContainerManager) Exec(service *types.ServiceConfig, command []string, composeOverrideFilePath string, timeout time.Duration) (string, error) {
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = utils.ShellQuote(arg)
+	}
+	output, err := p.executor.ExecComposeWithin("-f "+p.Compose.RemoteFilePath+" -f "+composeOverrideFilePath+" exec -T "+service.Name+" "+strings.Join(args, " "), timeout)
 	if err != nil {
 		return "", fmt.Errorf("failed to exec: %w", err)
 	}
 	return string(output), nil
 }
+
+// withinTimeout prefixes a command to kill it once timeout passes, rounded up
+// to whole seconds, or is empty when timeout is zero
+func withinTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("timeout %d ", int((timeout+time.Second-1)/time.Second))
+}
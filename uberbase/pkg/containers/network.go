@@ -1,17 +1,63 @@
 package containers
 
-import "fmt"
+import (
+	"fmt"
 
-func (p *ContainerManager) CreateNetwork(name string) (string, error) {
-	output, err := p.executor.ExecCompose("network create " + name)
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// CreateNetwork creates the named network of a compose project with its
+// driver, options, addressing and labels, as CreateVolume does for volumes.
+func (p *ContainerManager) CreateNetwork(key string, config types.NetworkConfig) (string, error) {
+	cmd := "network create"
+	if config.Driver != "" {
+		cmd += " --driver " + utils.ShellQuote(config.Driver)
+	}
+	for _, opt := range sortedOptions(config.DriverOpts) {
+		cmd += " --opt " + utils.ShellQuote(opt)
+	}
+	if config.Internal {
+		cmd += " --internal"
+	}
+	if config.Attachable {
+		cmd += " --attachable"
+	}
+	if config.EnableIPv6 != nil && *config.EnableIPv6 {
+		cmd += " --ipv6"
+	}
+	if config.Ipam.Driver != "" {
+		cmd += " --ipam-driver " + utils.ShellQuote(config.Ipam.Driver)
+	}
+	for _, pool := range config.Ipam.Config {
+		if pool.Subnet != "" {
+			cmd += " --subnet " + utils.ShellQuote(pool.Subnet)
+		}
+		if pool.Gateway != "" {
+			cmd += " --gateway " + utils.ShellQuote(pool.Gateway)
+		}
+		if pool.IPRange != "" {
+			cmd += " --ip-range " + utils.ShellQuote(pool.IPRange)
+		}
+	}
+	for _, label := range composeLabels(config.Labels, p.Compose.Project.Name, "network", key) {
+		cmd += " --label " + utils.ShellQuote(label)
+	}
+	output, err := p.executor.Exec(cmd + " " + utils.ShellQuote(config.Name))
 	if err != nil {
 		return "", fmt.Errorf("failed to create network: %w", err)
 	}
 	return string(output), nil
 }
 
+// NetworkExists reports whether the named network exists
+func (p *ContainerManager) NetworkExists(name string) bool {
+	_, err := p.executor.Exec("network inspect " + utils.ShellQuote(name))
+	return err == nil
+}
+
 func (p *ContainerManager) RemoveNetwork(name string) (string, error) {
-	output, err := p.executor.ExecCompose("network rm " + name)
+	output, err := p.executor.Exec("network rm " + utils.ShellQuote(name))
 	if err != nil {
 		return "", fmt.Errorf("failed to remove network: %w", err)
 	}
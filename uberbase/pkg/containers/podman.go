@@ -3,6 +3,7 @@ package containers
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 )
@@ -34,3 +35,8 @@ func (p *PodmanExecutor) ExecCompose(command string) (string, error) {
 	invocation := fmt.Sprintf("%s %s", p.composePath, command)
 	return p.executor.Exec(invocation)
 }
+
+func (p *PodmanExecutor) ExecComposeWithin(command string, timeout time.Duration) (string, error) {
+	invocation := fmt.Sprintf("%s%s %s", withinTimeout(timeout), p.composePath, command)
+	return p.executor.Exec(invocation)
+}
@@ -1,19 +1,67 @@
 package containers
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
 
-func (p *ContainerManager) CreateVolume(name string) (string, error) {
-	output, err := p.executor.ExecCompose("volume create " + name)
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// CreateVolume creates the named volume of a compose project with its
+// driver, options and labels. The compose labels let compose adopt it as its
+// own rather than treat it as external.
+func (p *ContainerManager) CreateVolume(key string, config types.VolumeConfig) (string, error) {
+	cmd := "volume create"
+	if config.Driver != "" {
+		cmd += " --driver " + utils.ShellQuote(config.Driver)
+	}
+	for _, opt := range sortedOptions(config.DriverOpts) {
+		cmd += " --opt " + utils.ShellQuote(opt)
+	}
+	for _, label := range composeLabels(config.Labels, p.Compose.Project.Name, "volume", key) {
+		cmd += " --label " + utils.ShellQuote(label)
+	}
+	output, err := p.executor.Exec(cmd + " " + utils.ShellQuote(config.Name))
 	if err != nil {
 		return "", fmt.Errorf("failed to create volume: %w", err)
 	}
 	return string(output), nil
 }
 
+// VolumeExists reports whether the named volume exists
+func (p *ContainerManager) VolumeExists(name string) bool {
+	_, err := p.executor.Exec("volume inspect " + utils.ShellQuote(name))
+	return err == nil
+}
+
 func (p *ContainerManager) RemoveVolume(name string) (string, error) {
-	output, err := p.executor.ExecCompose("volume rm " + name)
+	output, err := p.executor.Exec("volume rm " + utils.ShellQuote(name))
 	if err != nil {
 		return "", fmt.Errorf("failed to remove volume: %w", err)
 	}
 	return string(output), nil
 }
+
+// sortedOptions formats driver options as key=value, in key order
+func sortedOptions(options map[string]string) []string {
+	formatted := make([]string, 0, len(options))
+	for key, value := range options {
+		formatted = append(formatted, key+"="+value)
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+// composeLabels formats a resource's labels as key=value, along with the
+// labels compose uses to recognize resources of its project
+func composeLabels(labels types.Labels, project, kind, key string) []string {
+	all := map[string]string{
+		"com.docker.compose.project": project,
+		"com.docker.compose." + kind: key,
+	}
+	for label, value := range labels {
+		all[label] = value
+	}
+	return sortedOptions(all)
+}
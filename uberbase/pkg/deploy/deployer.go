@@ -14,6 +14,7 @@ import (
 	"github.com/bluetongueai/uberbase/uberbase/pkg/loadbalancer"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
 )
 
 // Deployer orchestrates the deployment process
@@ -29,8 +30,25 @@ type Deployer struct {
 	remoteExecutor     core.Executor
 	localWorkDir       string
 	remoteWorkDir      string
+	connectionDrain    loadbalancer.ConnectionDrainOptions
+	smokeSuite         *SmokeSuite
+	pruneVolumes       bool
+	resume             bool
+	skipRollback       bool
+	services           []string
+	withDependencies   bool
+	prebuilt           bool
+	journal            *JournalStore
+	report             *DeployReport
+	// phase is the phase the deploy is in, and journaled whether it has
+	// written a journal on the host
+	phase     string
+	journaled bool
 }
 
+// defaultStopGracePeriod is the compose default for stop_grace_period
+const defaultStopGracePeriod = 10 * time.Second
+
 func NewDeployer(localExecutor core.Executor, remoteExecutor core.Executor, compose *containers.ComposeProject, localWorkDir, remoteWorkDir string) (*Deployer, error) {
 	logging.Logger.Debug("Verifying local deployment environment requirements")
 	if err := localExecutor.Verify(); err != nil {
@@ -60,10 +78,12 @@ func NewDeployer(localExecutor core.Executor, remoteExecutor core.Executor, comp
 		return nil, fmt.Errorf("failed to create git manager: %w", err)
 	}
 	healthChecker := health.NewHealthChecker(remoteContainerMgr)
+	healthChecker.SetHostExecutor(remoteExecutor)
 	trafficManager, err := loadbalancer.NewTrafficManager(remoteContainerMgr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create traffic manager: %w", err)
 	}
+	trafficManager.SetHostExecutor(remoteExecutor)
 
 	logging.Logger.Debug("Deployment components initialized successfully")
 	return &Deployer{
@@ -78,20 +98,107 @@ func NewDeployer(localExecutor core.Executor, remoteExecutor core.Executor, comp
 		remoteExecutor:     remoteExecutor,
 		localWorkDir:       localWorkDir,
 		remoteWorkDir:      remoteWorkDir,
+		journal:            NewJournalStore(remoteExecutor, remoteWorkDir),
 	}, nil
 }
 
+// SetSessionOptions configures session affinity and draining for the deploy
+func (d *Deployer) SetSessionOptions(options loadbalancer.SessionOptions) {
+	d.trafficManager.SetSessionOptions(options)
+}
+
+// SetTLSPolicy sets the certificate resolvers used for deployed routers
+func (d *Deployer) SetTLSPolicy(policy traefik.TraefikTLSPolicy) {
+	d.trafficManager.SetTLSPolicy(policy)
+}
+
+// SetConnectionDrainOptions configures waiting for in-flight requests to the
+// previous version before its containers are stopped
+func (d *Deployer) SetConnectionDrainOptions(options loadbalancer.ConnectionDrainOptions) {
+	d.connectionDrain = options
+}
+
+// SetHealthThresholds sets how many consecutive passes or failures settle the
+// health of the new containers' probes and routed servers
+func (d *Deployer) SetHealthThresholds(thresholds health.HealthThresholds) {
+	d.healthChecker.SetThresholds(thresholds)
+	d.trafficManager.SetHealthThresholds(thresholds)
+}
+
+// SetStateBackend sets where the deployment state is kept, instead of the
+// host's work directory
+func (d *Deployer) SetStateBackend(backend state.StateBackend) {
+	d.stateManager.SetBackend(backend)
+}
+
+// SetStateKey sets the key the sensitive fields of the state are encrypted
+// with, or leaves them in plaintext when it is nil
+func (d *Deployer) SetStateKey(provider state.KeyProvider) {
+	d.stateManager.SetKeyProvider(provider)
+}
+
+// SetSmokeSuite sets the tests run against the live routes after cut-over.
+// The deploy is rolled back when any of them fail.
+func (d *Deployer) SetSmokeSuite(suite *SmokeSuite) {
+	d.smokeSuite = suite
+}
+
+// SetPruneVolumes sets whether named volumes dropped from compose are
+// removed. They are kept by default, as they hold data.
+func (d *Deployer) SetPruneVolumes(prune bool) {
+	d.pruneVolumes = prune
+}
+
+// SetResume sets whether the deploy picks up a deploy of the same commit
+// that was interrupted, as recorded in the journal on the host
+func (d *Deployer) SetResume(resume bool) {
+	d.resume = resume
+}
+
+// SetSkipRollback sets whether a failed deploy is left as it failed, for
+// debugging, rather than rolled back
+func (d *Deployer) SetSkipRollback(skip bool) {
+	d.skipRollback = skip
+}
+
+// SetServices restricts the deploy to services, and the services they depend
+// on when withDependencies is set. The other services keep their current
+// containers. Every service is deployed when there are none.
+func (d *Deployer) SetServices(services []string, withDependencies bool) {
+	d.services = services
+	d.withDependencies = withDependencies
+}
+
+// SetPrebuilt sets whether the images were already built and pushed by
+// BuildImages, for deploying one commit to several hosts
+func (d *Deployer) SetPrebuilt(prebuilt bool) {
+	d.prebuilt = prebuilt
+}
+
+// Report returns the report of the last deploy, whether or not it succeeded
+func (d *Deployer) Report() *DeployReport {
+	return d.report
+}
+
 func (d *Deployer) DeployProject() (err error) {
 	ctx := context.Background()
 	rm := NewRollbackManager(d.stateManager)
 
-	// Panic recovery
+	// deferred first so it sees the error of a recovered panic
+	d.report = &DeployReport{StartedAt: time.Now()}
+	defer func() {
+		d.report.FinishedAt = time.Now()
+		d.report.Err = err
+	}()
+
+	// roll back whatever a failed or panicking deploy changed on the host
+	d.phase, d.journaled = PhasePrepare, false
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("deployment panic: %v", r)
-			if rollbackErr := rm.Rollback(ctx); rollbackErr != nil {
-				err = fmt.Errorf("%v, rollback also failed: %v", err, rollbackErr)
-			}
+		}
+		if err != nil {
+			err = d.fail(ctx, rm, err)
 		}
 	}()
 
@@ -100,31 +207,68 @@ func (d *Deployer) DeployProject() (err error) {
 		return fmt.Errorf("failed to get current git commit: %w", err)
 	}
 	containerTag := containers.ContainerTag(string(newVersion))
+	d.report.Tag = containerTag
 
-	services := []string{}
-	for _, service := range d.compose.Project.Services {
-		services = append(services, service.Name)
+	if _, err := d.remoteExecutor.Exec("mkdir -p " + d.remoteWorkDir); err != nil {
+		return fmt.Errorf("failed to create remote work directory: %w", err)
 	}
-	logging.LogKeyValues("Building and pushing containers", [][2]string{
-		{"tag", string(containerTag)},
-		{"services", strings.Join(services, ", ")},
-	})
 
-	if _, err := d.localContainerMgr.Build(containerTag); err != nil {
-		return fmt.Errorf("failed to build new versions: %w", err)
+	currentState, err := d.stateManager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load current state: %w", err)
 	}
+	rm.SetBaseline(currentState)
 
-	if _, err := d.localContainerMgr.Push(containerTag); err != nil {
-		return fmt.Errorf("failed to push new versions: %w", err)
+	// a deploy interrupted part way must be resumed or recovered first
+	interrupted, err := d.journal.Load()
+	if err != nil {
+		return err
 	}
-
-	if _, err := d.remoteExecutor.Exec("mkdir -p " + d.remoteWorkDir); err != nil {
-		return fmt.Errorf("failed to create remote work directory: %w", err)
+	if interrupted != nil && !d.resume {
+		return fmt.Errorf("a deploy of %s started at %s from %s was interrupted, resume it with deploy --resume or run uberbase recover", interrupted.Tag, interrupted.StartedAt, interrupted.Owner)
+	}
+	if d.resume {
+		if interrupted == nil {
+			return fmt.Errorf("no interrupted deploy to resume")
+		}
+		if interrupted.Tag != containerTag {
+			return fmt.Errorf("the interrupted deploy is of %s, not %s", interrupted.Tag, containerTag)
+		}
+		// once traffic is routed to the new containers the deploy carries on
+		// from the phase it was interrupted in
+		if currentState.Traefik != nil && currentState.Traefik.Tag == containerTag {
+			return d.resumeAfterCutover(ctx, rm, interrupted, currentState)
+		}
+		logging.Logger.Info("Resuming interrupted deploy", "tag", containerTag)
 	}
 
-	currentState, err := d.stateManager.Load()
+	// a deploy of some services leaves the others on their current tags
+	selected, err := d.selectServices()
 	if err != nil {
-		return fmt.Errorf("failed to load current state: %w", err)
+		return err
+	}
+	override := containers.NewComposeOverride(d.compose, containerTag)
+	kept, rolled, err := d.keepServices(override, selected, currentState)
+	if err != nil {
+		return err
+	}
+	if interrupted == nil && deployed(rolled, currentState, containerTag) {
+		logging.Logger.Info("Services already deployed", "tag", containerTag)
+		d.report.Unchanged = true
+		return nil
+	}
+
+	if !d.prebuilt {
+		if err := d.buildImages(containerTag, selected); err != nil {
+			return err
+		}
+	}
+
+	// record each phase on the host before it runs, so an interrupted
+	// deploy can be completed or rolled back
+	journal := interrupted
+	if journal == nil {
+		journal = NewDeployJournal(containerTag, override, currentState, kept)
 	}
 
 	if err := d.remoteExecutor.SendFile(d.compose.LocalFilePath, filepath.Join(d.remoteWorkDir, "docker-compose.yml")); err != nil {
@@ -132,15 +276,44 @@ func (d *Deployer) DeployProject() (err error) {
 	}
 	d.compose.RemoteFilePath = filepath.Join(d.remoteWorkDir, "docker-compose.yml")
 
-	// build a dynamic override file
-	override := containers.NewComposeOverride(d.compose, containerTag)
-	overrideFilePath, err := override.WriteToFile(d.remoteExecutor.(*core.RemoteExecutor), d.remoteWorkDir)
+	// create the volumes and networks the new containers need
+	if err := d.begin(journal, PhaseResources); err != nil {
+		return err
+	}
+	created := &createdResources{}
+	rm.AddRollbackStep(
+		"volumes and networks",
+		func(ctx context.Context) error {
+			return created.remove(d.remoteContainerMgr)
+		},
+		func(ctx context.Context) error {
+			for _, name := range created.networks {
+				if d.remoteContainerMgr.NetworkExists(name) {
+					return fmt.Errorf("network %s still exists after rollback", name)
+				}
+			}
+			for _, name := range created.volumes {
+				if d.remoteContainerMgr.VolumeExists(name) {
+					return fmt.Errorf("volume %s still exists after rollback", name)
+				}
+			}
+			return nil
+		},
+	)
+	volumes, networks, err := d.ensureResources(currentState, created)
 	if err != nil {
-		return fmt.Errorf("failed to write override file: %w", err)
+		return fmt.Errorf("failed to reconcile volumes and networks: %w", err)
 	}
 
+	// build a dynamic override file
+	if err := d.begin(journal, PhaseOverride); err != nil {
+		return err
+	}
+	// each rollback step is registered before its change is made, so a change
+	// that fails part way is undone too
+	overrideFilePath := filepath.Join(d.remoteWorkDir, "docker-compose.override.yml")
 	rm.AddRollbackStep(
-		"rollback-override",
+		"override file",
 		func(ctx context.Context) error {
 			if _, err := d.remoteExecutor.Exec("rm -f " + overrideFilePath); err != nil {
 				return fmt.Errorf("failed to remove override file: %w", err)
@@ -155,44 +328,19 @@ func (d *Deployer) DeployProject() (err error) {
 		},
 	)
 
-	// pull the new containers
-	logging.Logger.Info("Pulling new containers")
-	if _, err := d.remoteContainerMgr.Pull(containerTag); err != nil {
-		return fmt.Errorf("failed to pull new containers: %w", err)
-	}
-
-	// bring up the new containers
-	overrideServices := []string{}
-	for _, service := range override.Services {
-		overrideServices = append(overrideServices, service.Name)
-	}
-	logging.Logger.Infof("Starting new containers: %s", strings.Join(overrideServices, ", "))
-
-	_, err = d.remoteContainerMgr.Up(overrideFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to bring up new containers: %w", err)
+	if _, err := override.WriteToFile(d.remoteExecutor.(*core.RemoteExecutor), d.remoteWorkDir); err != nil {
+		return fmt.Errorf("failed to write override file: %w", err)
 	}
 
-	logging.Logger.Debug("Waiting for container health checks",
-		"services", override.Services,
-		"timeout", "10s")
-
-	healthy, err := d.healthChecker.WaitForContainers(context.Background(), override.Services)
-	if err != nil {
-		return fmt.Errorf("failed to wait for new containers to be healthy: %w", err)
-	}
-	select {
-	case <-healthy:
-		logging.Logger.Info("New containers healthy")
-	case <-time.After(10 * time.Second):
-		return fmt.Errorf("timed out waiting for new containers to be healthy")
+	// pull the new containers
+	if err := d.begin(journal, PhaseUp); err != nil {
+		return err
 	}
-
 	rm.AddRollbackStep(
-		"rollback-up",
+		"new containers",
 		func(ctx context.Context) error {
 			failedServices := []string{}
-			for _, service := range override.Services {
+			for _, service := range rolled.Services {
 				failedServices = append(failedServices, service.Name)
 			}
 			if _, err := d.remoteContainerMgr.Down(failedServices, overrideFilePath); err != nil {
@@ -201,7 +349,7 @@ func (d *Deployer) DeployProject() (err error) {
 			return nil
 		},
 		func(ctx context.Context) error {
-			for _, service := range override.Services {
+			for _, service := range rolled.Services {
 				if info, err := d.remoteContainerMgr.Inspect(service.Name); err == nil && info.State.Status == "running" {
 					return fmt.Errorf("container %s still running after rollback", service.Name)
 				}
@@ -210,54 +358,152 @@ func (d *Deployer) DeployProject() (err error) {
 		},
 	)
 
+	logging.Logger.Info("Pulling new containers")
+	if _, err := d.remoteContainerMgr.Pull(containerTag, selected...); err != nil {
+		return fmt.Errorf("failed to pull new containers: %w", err)
+	}
+
+	// bring up the new containers
+	overrideServices := []string{}
+	for _, service := range rolled.Services {
+		overrideServices = append(overrideServices, service.Name)
+	}
+	logging.Logger.Infof("Starting new containers: %s", strings.Join(overrideServices, ", "))
+
+	_, err = d.remoteContainerMgr.Up(overrideFilePath, selected...)
+	if err != nil {
+		return fmt.Errorf("failed to bring up new containers: %w", err)
+	}
+
+	logging.Logger.Debug("Waiting for container health checks", "services", rolled.Services)
+
+	healthReport, err := d.healthChecker.WaitForContainers(ctx, rolled.Services, overrideFilePath)
+	if healthReport == nil {
+		healthReport = health.NewHealthReport()
+	}
+	if err != nil {
+		d.recordHealth(healthReport)
+		return fmt.Errorf("new containers are not healthy: %w", err)
+	}
+	logging.Logger.Info("New containers healthy")
+
 	logging.Logger.Info("Updating traffic routing")
 	if err := d.trafficManager.Load(); err != nil {
 		return fmt.Errorf("failed to load traffic manager: %w", err)
 	}
-	if err := d.trafficManager.Deploy(context.Background(), &currentState, containerTag); err != nil {
+	err = d.trafficManager.Deploy(ctx, &currentState, containerTag)
+	healthReport.Merge(d.trafficManager.GetHealthReport())
+	d.recordHealth(healthReport)
+	if err != nil {
 		return fmt.Errorf("failed to route traffic: %w", err)
 	}
 
+	previousTag := containers.ContainerTag("")
+	var previousConfig *traefik.TraefikDynamicConfiguration
+	if currentState.Traefik != nil {
+		previousTag = currentState.Traefik.Tag
+		previousConfig = currentState.Traefik.Active
+	}
+
+	activeTag, activeConfig := d.trafficManager.GetActiveConfig()
+	drainConfig, drainTimeout := d.trafficManager.GetDrainConfig()
+
+	// keep the replaced deployment as a rollback target, saved with the
+	// activation so an interrupted deploy can route traffic back to it
+	if previousTag != "" && previousTag != containerTag {
+		d.stateManager.SetPrevious(&state.PreviousDeployment{
+			Tag:        previousTag,
+			DeployedAt: currentState.DeployedAt,
+			Services:   currentState.Compose.Services,
+			Active:     previousConfig,
+		})
+	}
+
+	if err := d.begin(journal, PhaseActivate); err != nil {
+		return err
+	}
 	rm.AddRollbackStep(
-		"traffic-rollback",
+		"traffic routing",
 		func(ctx context.Context) error {
-			if err := d.trafficManager.Deploy(ctx, &currentState, currentState.Tag); err != nil {
+			d.stateManager.SetPrevious(currentState.Previous)
+			if err := d.stateManager.Activate(previousTag, previousConfig); err != nil {
 				return fmt.Errorf("failed to rollback traffic: %w", err)
 			}
 			return nil
 		},
 		func(ctx context.Context) error {
-			// Verify traffic routing
-			if currentState.Tag == "" {
-				// We've never routed traffic before, so we don't have anything to rollback
-				return nil
+			restored, err := d.stateManager.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load state after traffic rollback: %w", err)
 			}
-			for _, config := range currentState.Traefik.Configs {
-				healthy, err := d.healthChecker.WaitForHTTPHealthChecks(ctx, config.HTTP.Services)
-				if err != nil {
-					return fmt.Errorf("health check failed after traffic rollback: %w", err)
-				}
-				select {
-				case <-healthy:
-					return nil
-				case <-time.After(30 * time.Second):
-					return fmt.Errorf("timeout waiting for health checks after traffic rollback")
-				}
+			if restored.Traefik == nil || restored.Traefik.Tag != previousTag {
+				return fmt.Errorf("traffic still routed to %s after rollback", activeTag)
 			}
 			return nil
 		},
 	)
 
-	// bring down the old containers
+	if drainConfig != nil {
+		err = d.stateManager.ActivateDraining(activeTag, drainConfig, activeConfig)
+	} else {
+		err = d.stateManager.Activate(activeTag, activeConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to activate traffic routing: %w", err)
+	}
+
+	// the old containers still run, so a failed smoke test can switch back
+	if d.smokeSuite != nil {
+		if err := d.begin(journal, PhaseSmokeTest); err != nil {
+			return err
+		}
+		logging.Logger.Info("Running smoke tests")
+		results, smokeErr := d.runSmokeTests(ctx, overrideFilePath)
+		d.report.SmokeTests = results
+		if smokeErr != nil {
+			return fmt.Errorf("smoke tests failed: %w", smokeErr)
+		}
+	}
+
+	// let sessions pinned to the old containers finish before removing them
+	if drainConfig != nil {
+		if err := d.begin(journal, PhaseDrain); err != nil {
+			return err
+		}
+		logging.Logger.Info("Draining sessions from previous version", "tag", previousTag, "timeout", drainTimeout.String())
+		select {
+		case <-time.After(drainTimeout):
+		case <-ctx.Done():
+			return fmt.Errorf("deployment cancelled while draining sessions: %w", ctx.Err())
+		}
+		if err := d.stateManager.Activate(activeTag, activeConfig); err != nil {
+			return fmt.Errorf("failed to activate traffic routing after drain: %w", err)
+		}
+	}
+
+	oldServices := []*state.ComposeServiceState{}
 	oldContainers := []string{}
-	for _, service := range currentState.Compose.Services {
-		if service.ContainerName == fmt.Sprintf("%s-%s", service.ServiceName, string(containerTag)) {
+	for _, name := range sortedKeys(currentState.Compose.Services) {
+		service := currentState.Compose.Services[name]
+		if kept[service.ServiceName] || service.ContainerName == fmt.Sprintf("%s-%s", service.ServiceName, string(containerTag)) {
 			continue
 		}
+		oldServices = append(oldServices, service)
 		oldContainers = append(oldContainers, service.ContainerName)
 	}
-	if _, err := d.remoteContainerMgr.Down(oldContainers, overrideFilePath); err != nil {
-		return fmt.Errorf("failed to bring down old containers, environment may be inconsistent: %w, %v", err, oldContainers)
+
+	if err := d.drainConnections(ctx, previousTag, oldContainers); err != nil {
+		return err
+	}
+
+	// bring down the old containers, letting each shut down gracefully first
+	if err := d.begin(journal, PhaseCleanup); err != nil {
+		return err
+	}
+	for _, service := range oldServices {
+		if _, err := d.remoteContainerMgr.Stop(service.ContainerName, stopGracePeriod(d.compose, service.ServiceName)); err != nil {
+			logging.Logger.Warn("Failed to stop old container gracefully", "container", service.ContainerName, "error", err)
+		}
 	}
 
 	logging.Logger.Info("Cleaning up old containers", "count", fmt.Sprintf("%d", len(oldContainers)), "containers", strings.Join(oldContainers, ", "))
@@ -266,15 +512,298 @@ func (d *Deployer) DeployProject() (err error) {
 		return fmt.Errorf("failed to bring down old containers, environment may be inconsistent: %w, %v", err, oldContainers)
 	}
 
-	logging.Logger.Info("Updating deployment state")
+	d.pruneResources(currentState, volumes, networks)
 
-	if err := d.stateManager.Update(override.Services, d.trafficManager.GetDynamicConfigs(), containerTag); err != nil {
+	logging.Logger.Info("Updating deployment state")
+	if err := d.begin(journal, PhaseState); err != nil {
+		return err
+	}
+	d.stateManager.SetResources(volumes, networks)
+	if err := d.stateManager.Update(rolled.Services, d.trafficManager.GetDynamicConfigs(), containerTag); err != nil {
 		return fmt.Errorf("failed to create new state: %w", err)
 	}
+	// record what the containers run, so drift can tell if they change
+	for _, service := range rolled.Services {
+		if info, err := d.remoteContainerMgr.Inspect(service.Name); err == nil {
+			d.stateManager.SetImageID(service.RefName, info.Image)
+		}
+	}
 	if err := d.stateManager.Save(); err != nil {
 		return fmt.Errorf("failed to save new state, this environment cannot be a rollback target: %w", err)
 	}
+	d.removeJournal()
 
 	logging.Logger.Info("Deployment completed successfully", "version", containerTag)
 	return nil
 }
+
+// BuildImages builds and pushes the images of the services to deploy, tagged
+// with the current commit, so deploys of it to several hosts build once
+func (d *Deployer) BuildImages() (containers.ContainerTag, error) {
+	newVersion, err := d.gitManager.GetCurrentCommit()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current git commit: %w", err)
+	}
+	containerTag := containers.ContainerTag(string(newVersion))
+	selected, err := d.selectServices()
+	if err != nil {
+		return "", err
+	}
+	if err := d.buildImages(containerTag, selected); err != nil {
+		return "", err
+	}
+	return containerTag, nil
+}
+
+func (d *Deployer) buildImages(containerTag containers.ContainerTag, selected []string) error {
+	services := selected
+	if services == nil {
+		services = d.compose.Project.ServiceNames()
+	}
+	logging.LogKeyValues("Building and pushing containers", [][2]string{
+		{"tag", string(containerTag)},
+		{"services", strings.Join(services, ", ")},
+	})
+
+	d.phase = PhaseBuild
+	if _, err := d.localContainerMgr.Build(containerTag, selected...); err != nil {
+		return fmt.Errorf("failed to build new versions: %w", err)
+	}
+
+	d.phase = PhasePush
+	if _, err := d.localContainerMgr.Push(containerTag, selected...); err != nil {
+		return fmt.Errorf("failed to push new versions: %w", err)
+	}
+	return nil
+}
+
+// Rollback returns the host to the deployment its last deploy replaced, for
+// undoing a deploy that completed. A host's first deployment replaced
+// nothing, so it is removed instead.
+func (d *Deployer) Rollback(ctx context.Context) error {
+	current, err := d.stateManager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	d.compose.RemoteFilePath = filepath.Join(d.remoteWorkDir, "docker-compose.yml")
+	if current.Previous == nil {
+		return d.teardown(current)
+	}
+	watchdog := &Watchdog{
+		remoteExecutor: d.remoteExecutor,
+		containerMgr:   d.remoteContainerMgr,
+		stateManager:   d.stateManager,
+		remoteWorkDir:  d.remoteWorkDir,
+	}
+	return watchdog.RollbackToPrevious(ctx, current)
+}
+
+// teardown stops routing traffic to the current deployment, removes its
+// containers and clears the state
+func (d *Deployer) teardown(current state.DeploymentState) error {
+	logging.Logger.Info("No previous deployment, removing deployment", "tag", current.Tag)
+	if err := d.stateManager.Reset(); err != nil {
+		return fmt.Errorf("failed to clear state: %w", err)
+	}
+	if current.Compose == nil || len(current.Compose.Services) == 0 {
+		return nil
+	}
+
+	override := &containers.ComposeOverride{Services: overridesFromState(current.Compose.Services)}
+	overrideFilePath, err := override.WriteToFile(d.remoteExecutor.(*core.RemoteExecutor), d.remoteWorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to write override file: %w", err)
+	}
+	removed := []string{}
+	for _, service := range current.Compose.Services {
+		removed = append(removed, service.ContainerName)
+		if _, err := d.remoteContainerMgr.Stop(service.ContainerName, stopGracePeriod(d.compose, service.ServiceName)); err != nil {
+			logging.Logger.Warn("Failed to stop container gracefully", "container", service.ContainerName, "error", err)
+		}
+	}
+	if _, err := d.remoteContainerMgr.Down(removed, overrideFilePath); err != nil {
+		return fmt.Errorf("failed to remove containers, state is cleared but they may still run: %w, %v", err, removed)
+	}
+	logging.Logger.Info("Removed deployment", "tag", current.Tag, "containers", strings.Join(removed, ", "))
+	return nil
+}
+
+// fail rolls back what a failed deploy changed on the host and describes
+// the outcome as a DeployError. Nothing is rolled back when rollback is
+// skipped, nor once the deploy has started removing the containers it
+// replaced, as traffic cannot be routed back to them.
+func (d *Deployer) fail(ctx context.Context, rm *RollbackManager, err error) *DeployError {
+	deployErr := &DeployError{Phase: d.phase, Err: err}
+	pending := rm.Pending()
+
+	switch {
+	case d.phase == PhaseCleanup || d.phase == PhaseState:
+		deployErr.Inconsistent = append(deployErr.Inconsistent, fmt.Sprintf("traffic is routed to %s but the containers it replaces may not all be removed, complete it with uberbase recover --complete", d.report.Tag))
+	case len(pending) == 0:
+		// nothing was changed that needs undoing
+		if d.journaled {
+			d.removeJournal()
+		}
+	case d.skipRollback:
+		logging.Logger.Warn("Rollback skipped, leaving the failed deploy in place", "steps", strings.Join(pending, ", "))
+		for _, name := range pending {
+			deployErr.Inconsistent = append(deployErr.Inconsistent, name+" left in place")
+		}
+		deployErr.Inconsistent = append(deployErr.Inconsistent, "roll it back with uberbase recover --rollback")
+	default:
+		result, rollbackErr := rm.Rollback(ctx)
+		deployErr.RolledBack = result.RolledBack
+		deployErr.RollbackErr = rollbackErr
+		for _, name := range result.Failed {
+			deployErr.Inconsistent = append(deployErr.Inconsistent, name+" not rolled back")
+		}
+		if rollbackErr != nil {
+			deployErr.Inconsistent = append(deployErr.Inconsistent, "finish rolling back with uberbase recover --rollback")
+			break
+		}
+		d.removeJournal()
+		d.report.RolledBack = true
+	}
+
+	// a failed deploy's report outlives its containers
+	if err := d.stateManager.SaveHealth(d.report.Health); err != nil {
+		logging.Logger.Warn("Failed to store health report", "error", err)
+	}
+	return deployErr
+}
+
+// resumeAfterCutover carries on a deploy interrupted after it routed traffic
+// to its containers, running the phases the journal has not completed
+func (d *Deployer) resumeAfterCutover(ctx context.Context, rm *RollbackManager, journal *DeployJournal, current state.DeploymentState) error {
+	phase, done := journal.Last()
+	logging.Logger.Info("Resuming interrupted deploy after cut-over", "tag", journal.Tag, "phase", phase, "done", fmt.Sprint(done))
+	recovery := &Recovery{
+		remoteExecutor: d.remoteExecutor,
+		containerMgr:   d.remoteContainerMgr,
+		stateManager:   d.stateManager,
+		journal:        d.journal,
+		remoteWorkDir:  d.remoteWorkDir,
+	}
+
+	// until the old containers are removed traffic can be routed back to them
+	if !journal.Started(PhaseCleanup) {
+		rm.AddRollbackStep(
+			"interrupted deploy",
+			func(ctx context.Context) error {
+				return recovery.Rollback(journal, current)
+			},
+			nil,
+		)
+	}
+
+	// the tests may not have run, or not passed, before the interruption
+	if d.smokeSuite != nil && !journal.Started(PhaseDrain) && !journal.Started(PhaseCleanup) {
+		overrideFilePath, err := recovery.writeOverride(journal)
+		if err != nil {
+			return err
+		}
+		if err := d.begin(journal, PhaseSmokeTest); err != nil {
+			return err
+		}
+		logging.Logger.Info("Running smoke tests")
+		results, smokeErr := d.runSmokeTests(ctx, overrideFilePath)
+		d.report.SmokeTests = results
+		if smokeErr != nil {
+			return fmt.Errorf("smoke tests failed: %w", smokeErr)
+		}
+	}
+
+	// sessions still pinned to the old containers get what is left of their
+	// drain before the routing left draining is activated
+	if current.Traefik.Next != nil && !journal.Started(PhaseCleanup) {
+		_, drainTimeout := d.trafficManager.GetDrainConfig()
+		if started, ok := journal.startedAt(PhaseDrain); ok {
+			drainTimeout -= time.Since(started)
+		}
+		if err := d.begin(journal, PhaseDrain); err != nil {
+			return err
+		}
+		if drainTimeout > 0 {
+			logging.Logger.Info("Draining sessions from previous version", "tag", journal.PreviousTag, "timeout", drainTimeout.Round(time.Second).String())
+			select {
+			case <-time.After(drainTimeout):
+			case <-ctx.Done():
+				return fmt.Errorf("deployment cancelled while draining sessions: %w", ctx.Err())
+			}
+		}
+		if err := d.stateManager.Activate(journal.Tag, current.Traefik.Next); err != nil {
+			return fmt.Errorf("failed to activate traffic routing after drain: %w", err)
+		}
+		current.Traefik.Active, current.Traefik.Next = current.Traefik.Next, nil
+	}
+
+	if !journal.Started(PhaseCleanup) {
+		if err := d.drainConnections(ctx, journal.PreviousTag, recovery.existingContainers(journal.OldContainers)); err != nil {
+			return err
+		}
+	}
+
+	if err := d.begin(journal, PhaseCleanup); err != nil {
+		return err
+	}
+	return recovery.Complete(journal, current)
+}
+
+// drainConnections waits for the connections still open to the old
+// containers, stopping them regardless when they cannot be counted
+func (d *Deployer) drainConnections(ctx context.Context, previousTag containers.ContainerTag, oldContainers []string) error {
+	if d.connectionDrain.Timeout <= 0 || len(oldContainers) == 0 {
+		return nil
+	}
+	logging.Logger.Info("Draining connections from previous version", "tag", previousTag, "timeout", d.connectionDrain.Timeout.String())
+	drained, err := loadbalancer.WaitForConnectionDrain(ctx, d.remoteExecutor, d.remoteContainerMgr, d.connectionDrain, oldContainers)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("failed to drain connections: %w", err)
+	}
+	if err != nil {
+		logging.Logger.Warn("Cannot drain connections, stopping previous version", "error", err)
+	} else if drained {
+		logging.Logger.Info("Connections drained")
+	}
+	return nil
+}
+
+// begin journals that phase of the deploy is starting
+func (d *Deployer) begin(journal *DeployJournal, phase string) error {
+	journal.Begin(phase)
+	if err := d.journal.Save(journal); err != nil {
+		return fmt.Errorf("failed to journal %s phase: %w", phase, err)
+	}
+	d.phase, d.journaled = phase, true
+	return nil
+}
+
+// removeJournal removes the journal of a deploy that completed or rolled back
+func (d *Deployer) removeJournal() {
+	if err := d.journal.Remove(); err != nil {
+		logging.Logger.Warn("Failed to remove deploy journal, remove it before deploying again", "path", d.journal.Location(), "error", err)
+	}
+}
+
+// recordHealth logs the health of every checked server and records the
+// report in state, saved with the deploy or, when it fails, by fail
+func (d *Deployer) recordHealth(report *health.HealthReport) {
+	report.Servers(func(service, server string, result *health.ServerHealth) {
+		if result.Healthy {
+			logging.Logger.Debug("Healthy", "service", service, "server", server, "attempts", result.Attempts)
+			return
+		}
+		logging.Logger.Warn("Unhealthy", "service", service, "server", server, "attempts", result.Attempts, "error", result.LastError)
+	})
+	d.report.Health = report
+	d.stateManager.SetHealth(report)
+}
+
+// stopGracePeriod returns the stop_grace_period of the compose service, or
+// the compose default when it is not set
+func stopGracePeriod(compose *containers.ComposeProject, serviceName string) time.Duration {
+	if service, ok := compose.Project.Services[serviceName]; ok && service.StopGracePeriod != nil {
+		return time.Duration(*service.StopGracePeriod)
+	}
+	return defaultStopGracePeriod
+}
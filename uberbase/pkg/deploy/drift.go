@@ -0,0 +1,501 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/loadbalancer"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultTraefikAPIURL is where Traefik's API is reached from the host
+const defaultTraefikAPIURL = "http://localhost:8080/api"
+
+// Kinds of drift between the recorded state and the host
+const (
+	DriftMissingContainer = "missing-container"
+	DriftStoppedContainer = "stopped-container"
+	DriftExtraContainer   = "extra-container"
+	DriftImage            = "image"
+	DriftRouting          = "routing"
+)
+
+// Drift is one difference between the recorded state and the host
+type Drift struct {
+	Kind string
+	// Resource is the container, router, service or dynamic config file
+	// that drifted
+	Resource string
+	Detail   string
+}
+
+// DriftReport lists how a host differs from its recorded deployment state
+type DriftReport struct {
+	Tag    containers.ContainerTag
+	Drifts []Drift
+}
+
+// Drifted reports whether the host differs from its recorded state
+func (r *DriftReport) Drifted() bool {
+	return len(r.Drifts) > 0
+}
+
+// Print writes the report as a table
+func (r *DriftReport) Print(w io.Writer) {
+	if !r.Drifted() {
+		fmt.Fprintf(w, "No drift from deployment %s\n", r.Tag)
+		return
+	}
+	fmt.Fprintf(w, "%d differences from deployment %s:\n", len(r.Drifts), r.Tag)
+	for _, drift := range r.Drifts {
+		fmt.Fprintf(w, "  %-18s %-32s %s\n", drift.Kind, drift.Resource, drift.Detail)
+	}
+}
+
+// DriftDetector compares a host's recorded deployment state with the
+// containers running on it and the routing Traefik serves.
+type DriftDetector struct {
+	remoteExecutor core.Executor
+	containerMgr   *containers.ContainerManager
+	stateManager   *state.StateManager
+	remoteWorkDir  string
+	dynamicDir     string
+	traefikAPIURL  string
+}
+
+func NewDriftDetector(remoteExecutor core.Executor, compose *containers.ComposeProject, remoteWorkDir string) (*DriftDetector, error) {
+	containerMgr, err := containers.NewContainerManager(remoteExecutor, compose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container manager: %w", err)
+	}
+	compose.RemoteFilePath = filepath.Join(remoteWorkDir, "docker-compose.yml")
+
+	return &DriftDetector{
+		remoteExecutor: remoteExecutor,
+		containerMgr:   containerMgr,
+		stateManager:   state.NewStateManager(remoteWorkDir, remoteExecutor),
+		remoteWorkDir:  remoteWorkDir,
+		dynamicDir:     traefik.DynamicConfigPath,
+		traefikAPIURL:  defaultTraefikAPIURL,
+	}, nil
+}
+
+// SetTraefikAPIURL sets the URL of Traefik's API, as reached from the host
+func (d *DriftDetector) SetTraefikAPIURL(url string) {
+	d.traefikAPIURL = strings.TrimSuffix(url, "/")
+}
+
+// SetStateBackend sets where the deployment state is kept, instead of the
+// host's work directory
+func (d *DriftDetector) SetStateBackend(backend state.StateBackend) {
+	d.stateManager.SetBackend(backend)
+}
+
+// SetStateKey sets the key the sensitive fields of the state are encrypted
+// with, or leaves them in plaintext when it is nil
+func (d *DriftDetector) SetStateKey(provider state.KeyProvider) {
+	d.stateManager.SetKeyProvider(provider)
+}
+
+// Detect compares the recorded deployment state with the host
+func (d *DriftDetector) Detect() (*DriftReport, error) {
+	current, err := d.stateManager.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if current.Tag == "" {
+		return nil, fmt.Errorf("no deployment recorded")
+	}
+
+	report := &DriftReport{Tag: current.Tag}
+	if err := d.detectContainers(current, report); err != nil {
+		return nil, err
+	}
+	if err := d.detectRouting(current, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// detectContainers finds recorded containers that are missing, stopped or
+// running another image, and containers of recorded services that are not
+// recorded, such as those left behind by a failed deploy
+func (d *DriftDetector) detectContainers(current state.DeploymentState, report *DriftReport) error {
+	services := map[string]*state.ComposeServiceState{}
+	if current.Compose != nil {
+		services = current.Compose.Services
+	}
+
+	recorded := make(map[string]bool)
+	for _, name := range sortedKeys(services) {
+		service := services[name]
+		recorded[service.ContainerName] = true
+
+		info, err := d.containerMgr.Inspect(service.ContainerName)
+		if err != nil {
+			report.Drifts = append(report.Drifts, Drift{Kind: DriftMissingContainer, Resource: service.ContainerName, Detail: "not found"})
+			continue
+		}
+		if !info.State.Running {
+			report.Drifts = append(report.Drifts, Drift{Kind: DriftStoppedContainer, Resource: service.ContainerName, Detail: "status " + info.State.Status})
+		}
+
+		expected := service.ImageID
+		if expected == "" {
+			expected, err = d.containerMgr.ImageID(service.Image)
+			if err != nil {
+				report.Drifts = append(report.Drifts, Drift{Kind: DriftImage, Resource: service.ContainerName, Detail: fmt.Sprintf("image %s not found", service.Image)})
+				continue
+			}
+		}
+		if info.Image != expected {
+			report.Drifts = append(report.Drifts, Drift{Kind: DriftImage, Resource: service.ContainerName, Detail: fmt.Sprintf("runs %s (%s), recorded %s (%s)", shortImageID(info.Image), info.Config.Image, shortImageID(expected), service.Image)})
+		}
+	}
+
+	names, err := d.containerMgr.ProjectContainers()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if recorded[name] {
+			continue
+		}
+		info, err := d.containerMgr.Inspect(name)
+		if err != nil {
+			continue
+		}
+		// services without a build are not deployed by tag, so not recorded
+		if _, ok := services[info.Config.Labels["com.docker.compose.service"]]; !ok {
+			continue
+		}
+		report.Drifts = append(report.Drifts, Drift{Kind: DriftExtraContainer, Resource: name, Detail: "not recorded, status " + info.State.Status})
+	}
+	return nil
+}
+
+// detectRouting compares the routing Traefik serves from uberbase serve with
+// what serve provides from state: the active routing and the unowned
+// dynamic config files
+func (d *DriftDetector) detectRouting(current state.DeploymentState, report *DriftReport) error {
+	configs, err := d.dynamicConfigs(report)
+	if err != nil {
+		return err
+	}
+	expected, err := loadbalancer.ProviderConfig(current, configs)
+	if err != nil {
+		return fmt.Errorf("failed to merge dynamic configs: %w", err)
+	}
+
+	url := d.traefikAPIURL + "/rawdata"
+	output, err := d.remoteExecutor.Exec("curl -sf " + utils.ShellQuote(url))
+	if err != nil {
+		return fmt.Errorf("failed to fetch routing from %s: %w", url, err)
+	}
+	var served traefikRawData
+	if err := json.Unmarshal([]byte(output), &served); err != nil {
+		return fmt.Errorf("failed to parse routing from %s: %w", url, err)
+	}
+
+	report.Drifts = append(report.Drifts, routingDrifts(expected, &served)...)
+	return nil
+}
+
+// dynamicConfigs reads the dynamic config files on the host, keyed by file
+// name, as uberbase serve does. Files that do not parse are reported, as
+// serve cannot provide any routing while they are there.
+func (d *DriftDetector) dynamicConfigs(report *DriftReport) (map[string]*traefik.TraefikDynamicConfiguration, error) {
+	configs := make(map[string]*traefik.TraefikDynamicConfiguration)
+	if _, err := d.remoteExecutor.Exec("test -d " + utils.ShellQuote(d.dynamicDir)); err != nil {
+		return configs, nil
+	}
+	output, err := d.remoteExecutor.Exec("ls -1 " + utils.ShellQuote(d.dynamicDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dynamic configs: %w", err)
+	}
+	for _, name := range strings.Split(output, "\n") {
+		name = strings.TrimSpace(name)
+		if ext := filepath.Ext(name); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		content, err := d.remoteExecutor.Exec("cat " + utils.ShellQuote(filepath.Join(d.dynamicDir, name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dynamic config %s: %w", name, err)
+		}
+		var config traefik.TraefikDynamicConfiguration
+		if err := yaml.Unmarshal([]byte(content), &config); err != nil {
+			report.Drifts = append(report.Drifts, Drift{Kind: DriftRouting, Resource: name, Detail: fmt.Sprintf("file invalid: %v", err)})
+			continue
+		}
+		configs[name] = &config
+	}
+	return configs, nil
+}
+
+// Fix reconciles the host back to its recorded state: recorded containers
+// are brought back up on their recorded images, unrecorded ones removed and
+// the recorded routing provided to Traefik again.
+func (d *DriftDetector) Fix(report *DriftReport) error {
+	current, err := d.stateManager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var recreate, remove []string
+	up := false
+	routing := false
+	for _, drift := range report.Drifts {
+		switch drift.Kind {
+		case DriftMissingContainer, DriftStoppedContainer:
+			up = true
+		case DriftImage:
+			recreate = append(recreate, drift.Resource)
+			up = true
+		case DriftExtraContainer:
+			remove = append(remove, drift.Resource)
+		case DriftRouting:
+			routing = true
+		}
+	}
+
+	services := map[string]*state.ComposeServiceState{}
+	if current.Compose != nil {
+		services = current.Compose.Services
+	}
+	override := &containers.ComposeOverride{Services: overridesFromState(services)}
+	overrideFilePath, err := override.WriteToFile(d.remoteExecutor.(*core.RemoteExecutor), d.remoteWorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to write override file: %w", err)
+	}
+
+	// containers on the wrong image are recreated from the recorded one
+	if replaced := append(recreate, remove...); len(replaced) > 0 {
+		for _, name := range replaced {
+			if _, err := d.containerMgr.Stop(name, defaultStopGracePeriod); err != nil {
+				logging.Logger.Warn("Failed to stop container gracefully", "container", name, "error", err)
+			}
+		}
+		logging.Logger.Info("Removing drifted containers", "containers", strings.Join(replaced, ", "))
+		if _, err := d.containerMgr.Down(replaced, overrideFilePath); err != nil {
+			return fmt.Errorf("failed to remove drifted containers: %w", err)
+		}
+	}
+
+	if up {
+		logging.Logger.Info("Bringing up recorded containers", "tag", current.Tag)
+		if _, err := d.containerMgr.PullImages(recordedImages(services)...); err != nil {
+			return fmt.Errorf("failed to pull recorded containers: %w", err)
+		}
+		if _, err := d.containerMgr.Up(overrideFilePath); err != nil {
+			return fmt.Errorf("failed to bring up recorded containers: %w", err)
+		}
+	}
+
+	if routing {
+		if err := d.restoreRouting(current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreRouting saves the recorded state again, for uberbase serve to
+// provide to Traefik, and waits for Traefik to poll it. The dynamic config
+// files are left alone, as uberbase did not write them.
+func (d *DriftDetector) restoreRouting(current state.DeploymentState) error {
+	logging.Logger.Info("Saving recorded routing for uberbase serve", "tag", current.Tag)
+	if err := d.stateManager.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	time.Sleep(loadbalancer.ProviderPollInterval)
+	return nil
+}
+
+// traefikRawData is the routing Traefik serves, as listed by its API, keyed
+// by name@provider
+type traefikRawData struct {
+	Routers     map[string]traefikRawRouter  `json:"routers"`
+	Services    map[string]traefikRawService `json:"services"`
+	TCPRouters  map[string]traefikRawRouter  `json:"tcpRouters"`
+	TCPServices map[string]traefikRawService `json:"tcpServices"`
+	UDPRouters  map[string]traefikRawRouter  `json:"udpRouters"`
+	UDPServices map[string]traefikRawService `json:"udpServices"`
+}
+
+type traefikRawRouter struct {
+	Rule    string   `json:"rule"`
+	Service string   `json:"service"`
+	Status  string   `json:"status"`
+	Error   []string `json:"error"`
+}
+
+type traefikRawService struct {
+	LoadBalancer *struct {
+		Servers []struct {
+			URL     string `json:"url"`
+			Address string `json:"address"`
+		} `json:"servers"`
+	} `json:"loadBalancer"`
+}
+
+// servedProvider is the provider Traefik names the routing of uberbase serve by
+const servedProvider = "http"
+
+// routingDrifts compares the routers and services Traefik serves from
+// uberbase serve with those expected, describing each router and service
+// by its rule, service and servers
+func routingDrifts(expected *traefik.TraefikDynamicConfiguration, served *traefikRawData) []Drift {
+	want := describeRouting(expected)
+	got := describeRawRouting(served)
+
+	var drifts []Drift
+	for _, resource := range sortedKeys(want) {
+		description, ok := got[resource]
+		switch {
+		case !ok:
+			drifts = append(drifts, Drift{Kind: DriftRouting, Resource: resource, Detail: "not served"})
+		case description != want[resource]:
+			drifts = append(drifts, Drift{Kind: DriftRouting, Resource: resource, Detail: fmt.Sprintf("serves %s, recorded %s", description, want[resource])})
+		}
+	}
+	for _, resource := range sortedKeys(got) {
+		if _, ok := want[resource]; !ok {
+			drifts = append(drifts, Drift{Kind: DriftRouting, Resource: resource, Detail: "served, not recorded"})
+		}
+	}
+
+	for _, protocol := range []string{"http", "tcp", "udp"} {
+		routers := map[string]map[string]traefikRawRouter{"http": served.Routers, "tcp": served.TCPRouters, "udp": served.UDPRouters}[protocol]
+		for _, key := range sortedKeys(routers) {
+			router := routers[key]
+			name, ok := strings.CutSuffix(key, "@"+servedProvider)
+			if ok && router.Status != "" && router.Status != "enabled" {
+				drifts = append(drifts, Drift{Kind: DriftRouting, Resource: protocol + " router " + name, Detail: fmt.Sprintf("%s: %s", router.Status, strings.Join(router.Error, "; "))})
+			}
+		}
+	}
+	return drifts
+}
+
+// describeRouting describes the routers and services of config, keyed by
+// protocol, kind and name
+func describeRouting(config *traefik.TraefikDynamicConfiguration) map[string]string {
+	described := make(map[string]string)
+	if config == nil {
+		return described
+	}
+	if config.HTTP != nil {
+		for name, router := range config.HTTP.Routers {
+			described["http router "+name] = describeRouter(router.Rule, router.Service)
+		}
+		for name, service := range config.HTTP.Services {
+			var servers []string
+			if service.LoadBalancer != nil {
+				for _, server := range service.LoadBalancer.Servers {
+					servers = append(servers, server.URL)
+				}
+			}
+			described["http service "+name] = describeServers(servers)
+		}
+	}
+	if config.TCP != nil {
+		for name, router := range config.TCP.Routers {
+			described["tcp router "+name] = describeRouter(router.Rule, router.Service)
+		}
+		for name, service := range config.TCP.Services {
+			var servers []string
+			if service.LoadBalancer != nil {
+				for _, server := range service.LoadBalancer.Servers {
+					servers = append(servers, server.Address)
+				}
+			}
+			described["tcp service "+name] = describeServers(servers)
+		}
+	}
+	if config.UDP != nil {
+		for name, router := range config.UDP.Routers {
+			described["udp router "+name] = describeRouter("", router.Service)
+		}
+		for name, service := range config.UDP.Services {
+			var servers []string
+			if service.LoadBalancer != nil {
+				for _, server := range service.LoadBalancer.Servers {
+					servers = append(servers, server.Address)
+				}
+			}
+			described["udp service "+name] = describeServers(servers)
+		}
+	}
+	return described
+}
+
+// describeRawRouting describes the routers and services Traefik serves from
+// uberbase serve as describeRouting does
+func describeRawRouting(served *traefikRawData) map[string]string {
+	described := make(map[string]string)
+	protocols := []struct {
+		name     string
+		routers  map[string]traefikRawRouter
+		services map[string]traefikRawService
+	}{
+		{"http", served.Routers, served.Services},
+		{"tcp", served.TCPRouters, served.TCPServices},
+		{"udp", served.UDPRouters, served.UDPServices},
+	}
+	for _, protocol := range protocols {
+		for key, router := range protocol.routers {
+			if name, ok := strings.CutSuffix(key, "@"+servedProvider); ok {
+				service, _, _ := strings.Cut(router.Service, "@")
+				described[protocol.name+" router "+name] = describeRouter(router.Rule, service)
+			}
+		}
+		for key, service := range protocol.services {
+			name, ok := strings.CutSuffix(key, "@"+servedProvider)
+			if !ok {
+				continue
+			}
+			var servers []string
+			if service.LoadBalancer != nil {
+				for _, server := range service.LoadBalancer.Servers {
+					servers = append(servers, server.URL+server.Address)
+				}
+			}
+			described[protocol.name+" service "+name] = describeServers(servers)
+		}
+	}
+	return described
+}
+
+func describeRouter(rule, service string) string {
+	if rule == "" {
+		return "service " + service
+	}
+	return fmt.Sprintf("%s to service %s", rule, service)
+}
+
+func describeServers(servers []string) string {
+	if len(servers) == 0 {
+		return "no servers"
+	}
+	sort.Strings(servers)
+	return "servers " + strings.Join(servers, ", ")
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
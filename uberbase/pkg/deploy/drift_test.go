@@ -0,0 +1,110 @@
+package deploy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// rawData is Traefik's /api/rawdata serving the expected routing below from
+// uberbase serve, alongside its own internal routers
+const rawData = `{
+  "routers": {
+    "api@http": {"rule": "Host(` + "`api.example.com`" + `)", "service": "api", "status": "enabled"},
+    "dashboard@internal": {"rule": "PathPrefix(` + "`/dashboard`" + `)", "service": "dashboard@internal", "status": "enabled"}
+  },
+  "services": {
+    "api@http": {"loadBalancer": {"servers": [{"url": "http://api-b2:8080"}]}},
+    "dashboard@internal": {}
+  },
+  "tcpRouters": {
+    "db@http": {"rule": "HostSNI(` + "`*`" + `)", "service": "db@http", "status": "enabled"}
+  },
+  "tcpServices": {
+    "db@http": {"loadBalancer": {"servers": [{"address": "db-b2:5432"}]}}
+  }
+}`
+
+func expectedRouting() *traefik.TraefikDynamicConfiguration {
+	return &traefik.TraefikDynamicConfiguration{
+		HTTP: &traefik.TraefikHTTPConfiguration{
+			Routers: map[string]traefik.TraefikRouter{
+				"api": {Rule: "Host(`api.example.com`)", Service: "api"},
+			},
+			Services: map[string]traefik.TraefikService{
+				"api": {LoadBalancer: &traefik.TraefikServiceLoadBalancer{Servers: []traefik.TraefikServiceLoadBalancerServer{{URL: "http://api-b2:8080"}}}},
+			},
+		},
+		TCP: &traefik.TraefikTCPConfiguration{
+			Routers: map[string]traefik.TraefikTCPRouter{
+				"db": {Rule: "HostSNI(`*`)", Service: "db"},
+			},
+			Services: map[string]traefik.TraefikTCPService{
+				"db": {LoadBalancer: &traefik.TraefikTCPServiceLoadBalancer{Servers: []traefik.TraefikTCPServiceLoadBalancerServer{{Address: "db-b2:5432"}}}},
+			},
+		},
+	}
+}
+
+func TestRoutingDrifts(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(expected *traefik.TraefikDynamicConfiguration, served *traefikRawData)
+		want   []Drift
+	}{
+		{
+			name:   "served as recorded",
+			change: func(*traefik.TraefikDynamicConfiguration, *traefikRawData) {},
+		},
+		{
+			name: "router not served",
+			change: func(_ *traefik.TraefikDynamicConfiguration, served *traefikRawData) {
+				delete(served.Routers, "api@http")
+			},
+			want: []Drift{{Kind: DriftRouting, Resource: "http router api", Detail: "not served"}},
+		},
+		{
+			name: "router not recorded",
+			change: func(expected *traefik.TraefikDynamicConfiguration, _ *traefikRawData) {
+				delete(expected.TCP.Routers, "db")
+			},
+			want: []Drift{{Kind: DriftRouting, Resource: "tcp router db", Detail: "served, not recorded"}},
+		},
+		{
+			name: "servers on another tag",
+			change: func(expected *traefik.TraefikDynamicConfiguration, _ *traefikRawData) {
+				expected.HTTP.Services["api"].LoadBalancer.Servers[0].URL = "http://api-c3:8080"
+			},
+			want: []Drift{{Kind: DriftRouting, Resource: "http service api", Detail: "serves servers http://api-b2:8080, recorded servers http://api-c3:8080"}},
+		},
+		{
+			name: "router in error",
+			change: func(_ *traefik.TraefikDynamicConfiguration, served *traefikRawData) {
+				served.Routers["api@http"] = traefikRawRouter{Rule: "Host(`api.example.com`)", Service: "api", Status: "disabled", Error: []string{"the service does not exist"}}
+			},
+			want: []Drift{{Kind: DriftRouting, Resource: "http router api", Detail: "disabled: the service does not exist"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var served traefikRawData
+			if err := json.Unmarshal([]byte(rawData), &served); err != nil {
+				t.Fatal(err)
+			}
+			expected := expectedRouting()
+			test.change(expected, &served)
+
+			drifts := routingDrifts(expected, &served)
+			if len(drifts) != len(test.want) {
+				t.Fatalf("drifts = %+v, want %+v", drifts, test.want)
+			}
+			for i := range drifts {
+				if drifts[i] != test.want[i] {
+					t.Errorf("drift %d = %+v, want %+v", i, drifts[i], test.want[i])
+				}
+			}
+		})
+	}
+}
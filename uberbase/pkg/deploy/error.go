@@ -0,0 +1,51 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Phases of a deploy before it changes the host, which are not journaled
+const (
+	PhasePrepare = "prepare"
+	PhaseBuild   = "build"
+	PhasePush    = "push"
+)
+
+// DeployError is a failed deploy: the phase that failed, the steps rolled
+// back and whatever the host is left with that differs from before the deploy.
+type DeployError struct {
+	Phase string
+	Err   error
+	// RolledBack are the rollback steps that were undone, in the order they ran
+	RolledBack []string
+	// RollbackErr is why the rollback did not complete, nil when it did or
+	// was not run
+	RollbackErr error
+	// Inconsistent describes what is left on the host, empty when the host
+	// is as it was before the deploy
+	Inconsistent []string
+}
+
+func (e *DeployError) Error() string {
+	msg := fmt.Sprintf("deploy failed in %s phase: %v", e.Phase, e.Err)
+	if len(e.RolledBack) > 0 {
+		msg += fmt.Sprintf(", rolled back %s", strings.Join(e.RolledBack, ", "))
+	}
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf(", rollback failed: %v", e.RollbackErr)
+	}
+	if len(e.Inconsistent) > 0 {
+		msg += fmt.Sprintf(", host left inconsistent: %s", strings.Join(e.Inconsistent, "; "))
+	}
+	return msg
+}
+
+func (e *DeployError) Unwrap() error {
+	return e.Err
+}
+
+// Consistent reports whether the host was left as it was before the deploy
+func (e *DeployError) Consistent() bool {
+	return len(e.Inconsistent) == 0
+}
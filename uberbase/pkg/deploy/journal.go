@@ -0,0 +1,225 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// journalFileName is the deploy journal in the host's work directory
+const journalFileName = "deploy-journal.yml"
+
+// Phases of a deploy that change the host, in the order they run
+const (
+	PhaseResources = "resources"
+	PhaseOverride  = "override"
+	PhaseUp        = "up"
+	PhaseActivate  = "activate"
+	PhaseSmokeTest = "smoke-test"
+	PhaseDrain     = "drain"
+	PhaseCleanup   = "cleanup"
+	PhaseState     = "state"
+)
+
+// JournalEntry records a deploy phase, written before the phase runs
+type JournalEntry struct {
+	Phase     string `yaml:"phase"`
+	StartedAt string `yaml:"started_at"`
+	Done      bool   `yaml:"done"`
+}
+
+// DeployJournal records the progress of a deploy on its host, so a deploy
+// interrupted part way can be completed or rolled back by another process.
+// It is removed once the deploy completes or is rolled back.
+type DeployJournal struct {
+	Tag         containers.ContainerTag `yaml:"tag"`
+	PreviousTag containers.ContainerTag `yaml:"previous_tag,omitempty"`
+	// Owner is the machine running the deploy
+	Owner     string `yaml:"owner"`
+	StartedAt string `yaml:"started_at"`
+	// Services are the containers the deploy brings up, keyed by service
+	Services map[string]*state.ComposeServiceState `yaml:"services"`
+	// Kept are the services the deploy leaves on their current containers
+	Kept map[string]*state.ComposeServiceState `yaml:"kept,omitempty"`
+	// OldContainers are the containers the deploy replaces
+	OldContainers []string       `yaml:"old_containers,omitempty"`
+	Entries       []JournalEntry `yaml:"entries"`
+}
+
+// NewDeployJournal starts a journal for deploying override's services as
+// tag, replacing the deployment in current but for the services in kept
+func NewDeployJournal(tag containers.ContainerTag, override *containers.ComposeOverride, current state.DeploymentState, kept map[string]bool) *DeployJournal {
+	owner, _ := os.Hostname()
+	journal := &DeployJournal{
+		Tag:       tag,
+		Owner:     owner,
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+		Services:  make(map[string]*state.ComposeServiceState),
+	}
+	if current.Traefik != nil {
+		journal.PreviousTag = current.Traefik.Tag
+	}
+
+	newContainers := make(map[string]bool)
+	for _, service := range override.Services {
+		newContainers[service.Name] = true
+		if kept[service.RefName] {
+			continue
+		}
+		journal.Services[service.RefName] = &state.ComposeServiceState{
+			ServiceName:   service.RefName,
+			ContainerName: service.Name,
+			Hostname:      service.Hostname,
+			Image:         service.Image,
+			Tag:           string(tag),
+		}
+	}
+	if current.Compose != nil {
+		for _, name := range sortedKeys(current.Compose.Services) {
+			service := current.Compose.Services[name]
+			if kept[name] {
+				if journal.Kept == nil {
+					journal.Kept = make(map[string]*state.ComposeServiceState)
+				}
+				journal.Kept[name] = service
+				continue
+			}
+			if !newContainers[service.ContainerName] {
+				journal.OldContainers = append(journal.OldContainers, service.ContainerName)
+			}
+		}
+	}
+	return journal
+}
+
+// Begin records that phase is starting, and so that the phases before it
+// completed
+func (j *DeployJournal) Begin(phase string) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := range j.Entries {
+		if j.Entries[i].Phase == phase {
+			j.Entries[i].StartedAt = now
+			j.Entries[i].Done = false
+			return
+		}
+		j.Entries[i].Done = true
+	}
+	j.Entries = append(j.Entries, JournalEntry{Phase: phase, StartedAt: now})
+}
+
+// Started reports whether phase was started, and so may have changed the host
+func (j *DeployJournal) Started(phase string) bool {
+	for _, entry := range j.Entries {
+		if entry.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// startedAt returns when phase was last started, if it was
+func (j *DeployJournal) startedAt(phase string) (time.Time, bool) {
+	for _, entry := range j.Entries {
+		if entry.Phase == phase {
+			started, err := time.Parse(time.RFC3339, entry.StartedAt)
+			return started, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// Last returns the last phase started, and whether it completed
+func (j *DeployJournal) Last() (string, bool) {
+	if len(j.Entries) == 0 {
+		return "", false
+	}
+	last := j.Entries[len(j.Entries)-1]
+	return last.Phase, last.Done
+}
+
+// overrides returns the overrides the deploy brings its services up with
+func (j *DeployJournal) overrides() map[string]containers.ComposeServiceOverride {
+	return overridesFromState(j.Services)
+}
+
+// overrideFile returns the override file of the deploy, which addresses the
+// services it keeps as well as those it brings up
+func (j *DeployJournal) overrideFile() *containers.ComposeOverride {
+	override := &containers.ComposeOverride{Services: overridesFromState(j.Kept)}
+	for name, service := range j.overrides() {
+		override.Services[name] = service
+	}
+	return override
+}
+
+// JournalStore keeps the deploy journal in the host's work directory
+type JournalStore struct {
+	executor core.Executor
+	path     string
+}
+
+func NewJournalStore(executor core.Executor, remoteWorkDir string) *JournalStore {
+	return &JournalStore{
+		executor: executor,
+		path:     filepath.Join(remoteWorkDir, journalFileName),
+	}
+}
+
+// Load returns the journal of an interrupted deploy, or nil when there is none
+func (s *JournalStore) Load() (*DeployJournal, error) {
+	if _, err := s.executor.Exec("test -f " + utils.ShellQuote(s.path)); err != nil {
+		return nil, nil
+	}
+	content, err := s.executor.Exec("cat " + utils.ShellQuote(s.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deploy journal: %w", err)
+	}
+	var journal DeployJournal
+	if err := yaml.Unmarshal([]byte(content), &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse deploy journal %s: %w", s.path, err)
+	}
+	return &journal, nil
+}
+
+// Save replaces the journal atomically, syncing it to disk before it counts
+// as written
+func (s *JournalStore) Save(journal *DeployJournal) error {
+	content, err := yaml.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deploy journal: %w", err)
+	}
+
+	path := utils.ShellQuote(s.path)
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	script.WriteString(fmt.Sprintf("mkdir -p %s\n", utils.ShellQuote(filepath.Dir(s.path))))
+	script.WriteString(fmt.Sprintf("tmp=$(mktemp %s.XXXXXX)\n", path))
+	script.WriteString(fmt.Sprintf("cat <<'UBERBASE_JOURNAL_EOF' > \"$tmp\"\n%s\nUBERBASE_JOURNAL_EOF\n", strings.TrimRight(string(content), "\n")))
+	script.WriteString("sync \"$tmp\" 2>/dev/null || sync\n")
+	script.WriteString(fmt.Sprintf("mv -f \"$tmp\" %s\n", path))
+	if _, err := s.executor.Exec(script.String()); err != nil {
+		return fmt.Errorf("failed to write deploy journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the journal once its deploy has completed or rolled back
+func (s *JournalStore) Remove() error {
+	if _, err := s.executor.Exec("rm -f " + utils.ShellQuote(s.path)); err != nil {
+		return fmt.Errorf("failed to remove deploy journal: %w", err)
+	}
+	return nil
+}
+
+// Location is the path of the journal on the host
+func (s *JournalStore) Location() string {
+	return s.path
+}
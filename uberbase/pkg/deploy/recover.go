@@ -0,0 +1,215 @@
+package deploy
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// Ways of recovering an interrupted deploy
+const (
+	// RecoverAuto completes deploys that have removed the containers they
+	// replace and rolls back the rest
+	RecoverAuto     = "auto"
+	RecoverComplete = "complete"
+	RecoverRollback = "rollback"
+)
+
+// Recovery completes or rolls back a deploy that was interrupted, from the
+// journal it left on the host.
+type Recovery struct {
+	remoteExecutor core.Executor
+	containerMgr   *containers.ContainerManager
+	stateManager   *state.StateManager
+	journal        *JournalStore
+	remoteWorkDir  string
+}
+
+func NewRecovery(remoteExecutor core.Executor, compose *containers.ComposeProject, remoteWorkDir string) (*Recovery, error) {
+	containerMgr, err := containers.NewContainerManager(remoteExecutor, compose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container manager: %w", err)
+	}
+	compose.RemoteFilePath = filepath.Join(remoteWorkDir, "docker-compose.yml")
+
+	return &Recovery{
+		remoteExecutor: remoteExecutor,
+		containerMgr:   containerMgr,
+		stateManager:   state.NewStateManager(remoteWorkDir, remoteExecutor),
+		journal:        NewJournalStore(remoteExecutor, remoteWorkDir),
+		remoteWorkDir:  remoteWorkDir,
+	}, nil
+}
+
+// SetStateBackend sets where the deployment state is kept, instead of the
+// host's work directory
+func (r *Recovery) SetStateBackend(backend state.StateBackend) {
+	r.stateManager.SetBackend(backend)
+}
+
+// SetStateKey sets the key the sensitive fields of the state are encrypted
+// with, or leaves them in plaintext when it is nil
+func (r *Recovery) SetStateKey(provider state.KeyProvider) {
+	r.stateManager.SetKeyProvider(provider)
+}
+
+// Recover completes or rolls back the interrupted deploy as mode says. It
+// returns the journal recovered from, nil when no deploy was interrupted.
+func (r *Recovery) Recover(mode string) (*DeployJournal, error) {
+	journal, err := r.journal.Load()
+	if err != nil || journal == nil {
+		return nil, err
+	}
+	current, err := r.stateManager.Load()
+	if err != nil {
+		return journal, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	phase, done := journal.Last()
+	logging.Logger.Info("Recovering interrupted deploy", "tag", journal.Tag, "owner", journal.Owner, "started", journal.StartedAt, "phase", phase, "done", fmt.Sprint(done))
+
+	switch mode {
+	case RecoverComplete:
+		return journal, r.Complete(journal, current)
+	case RecoverRollback:
+		return journal, r.Rollback(journal, current)
+	case RecoverAuto, "":
+		if journal.Started(PhaseCleanup) {
+			return journal, r.Complete(journal, current)
+		}
+		return journal, r.Rollback(journal, current)
+	default:
+		return journal, fmt.Errorf("unknown recovery mode %q", mode)
+	}
+}
+
+// Complete finishes a deploy that routed traffic to its containers: the
+// containers it replaces are removed and the state updated. A deploy that
+// had not routed traffic yet can only be resumed by deploying it again.
+func (r *Recovery) Complete(journal *DeployJournal, current state.DeploymentState) error {
+	if current.Traefik == nil || current.Traefik.Tag != journal.Tag {
+		return fmt.Errorf("traffic was never routed to %s, roll it back or resume it with deploy --resume", journal.Tag)
+	}
+
+	// the deploy was interrupted while sessions drained
+	if current.Traefik.Next != nil {
+		logging.Logger.Info("Activating routing left draining", "tag", journal.Tag)
+		if err := r.stateManager.Activate(journal.Tag, current.Traefik.Next); err != nil {
+			return fmt.Errorf("failed to activate traffic routing: %w", err)
+		}
+	}
+
+	overrideFilePath, err := r.writeOverride(journal)
+	if err != nil {
+		return err
+	}
+	if old := r.existingContainers(journal.OldContainers); len(old) > 0 {
+		for _, name := range old {
+			if _, err := r.containerMgr.Stop(name, defaultStopGracePeriod); err != nil {
+				logging.Logger.Warn("Failed to stop old container gracefully", "container", name, "error", err)
+			}
+		}
+		logging.Logger.Info("Cleaning up old containers", "containers", fmt.Sprint(old))
+		if _, err := r.containerMgr.Down(old, overrideFilePath); err != nil {
+			return fmt.Errorf("failed to bring down old containers: %w", err)
+		}
+	}
+
+	dynamicConfigs := make(map[string]*traefik.TraefikDynamicConfiguration)
+	for name, config := range current.Traefik.Configs {
+		config := config
+		dynamicConfigs[name] = &config
+	}
+	if err := r.stateManager.Update(journal.overrides(), dynamicConfigs, journal.Tag); err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+
+	if err := r.journal.Remove(); err != nil {
+		return err
+	}
+	logging.Logger.Info("Completed interrupted deploy", "tag", journal.Tag)
+	return nil
+}
+
+// Rollback undoes the phases an interrupted deploy started, in reverse:
+// traffic is routed back to the previous deployment and the new containers
+// removed. It refuses once the deploy has removed the containers it replaced.
+func (r *Recovery) Rollback(journal *DeployJournal, current state.DeploymentState) error {
+	if journal.Started(PhaseCleanup) {
+		return fmt.Errorf("the containers %s replaced may already be removed, it can only be completed", journal.Tag)
+	}
+
+	if current.Traefik != nil && current.Traefik.Tag == journal.Tag {
+		logging.Logger.Info("Routing traffic back", "to", journal.PreviousTag)
+		if current.Previous != nil && current.Previous.Tag == journal.PreviousTag {
+			if err := r.stateManager.RestorePrevious(); err != nil {
+				return fmt.Errorf("failed to route traffic back: %w", err)
+			}
+		} else if journal.PreviousTag == "" {
+			if err := r.stateManager.Activate("", nil); err != nil {
+				return fmt.Errorf("failed to route traffic back: %w", err)
+			}
+		} else {
+			return fmt.Errorf("the routing of %s is not in state, traffic cannot be routed back to it", journal.PreviousTag)
+		}
+	}
+
+	if journal.Started(PhaseUp) {
+		overrideFilePath, err := r.writeOverride(journal)
+		if err != nil {
+			return err
+		}
+		if created := r.existingContainers(containerNames(journal.Services)); len(created) > 0 {
+			logging.Logger.Info("Removing new containers", "containers", fmt.Sprint(created))
+			if _, err := r.containerMgr.Down(created, overrideFilePath); err != nil {
+				return fmt.Errorf("failed to bring down new containers: %w", err)
+			}
+		}
+	}
+
+	if journal.Started(PhaseOverride) {
+		if _, err := r.remoteExecutor.Exec("rm -f " + filepath.Join(r.remoteWorkDir, "docker-compose.override.yml")); err != nil {
+			return fmt.Errorf("failed to remove override file: %w", err)
+		}
+	}
+
+	if err := r.journal.Remove(); err != nil {
+		return err
+	}
+	logging.Logger.Info("Rolled back interrupted deploy", "tag", journal.Tag)
+	return nil
+}
+
+// writeOverride rewrites the override file of the journal's deploy, which
+// compose needs to address its containers
+func (r *Recovery) writeOverride(journal *DeployJournal) (string, error) {
+	overrideFilePath, err := journal.overrideFile().WriteToFile(r.remoteExecutor.(*core.RemoteExecutor), r.remoteWorkDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to write override file: %w", err)
+	}
+	return overrideFilePath, nil
+}
+
+// existingContainers returns those of names that exist on the host
+func (r *Recovery) existingContainers(names []string) []string {
+	existing := []string{}
+	for _, name := range names {
+		if _, err := r.containerMgr.Inspect(name); err == nil {
+			existing = append(existing, name)
+		}
+	}
+	return existing
+}
+
+func containerNames(services map[string]*state.ComposeServiceState) []string {
+	names := []string{}
+	for _, name := range sortedKeys(services) {
+		names = append(names, services[name].ContainerName)
+	}
+	return names
+}
@@ -0,0 +1,84 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+)
+
+// DeployReport summarizes a deploy: the version deployed, the health of its
+// servers and the outcome of its smoke tests.
+type DeployReport struct {
+	Tag        containers.ContainerTag
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Health     *health.HealthReport
+	SmokeTests []SmokeResult
+	RolledBack bool
+	// Unchanged is set when the services were already deployed
+	Unchanged bool
+	Err       error
+}
+
+// Status describes the outcome of the deploy in a word
+func (r *DeployReport) Status() string {
+	switch {
+	case r.Err == nil && r.Unchanged:
+		return "unchanged"
+	case r.Err == nil:
+		return "succeeded"
+	case r.RolledBack:
+		return "rolled back"
+	default:
+		return "failed"
+	}
+}
+
+// Log writes the report, including the output of failed smoke tests.
+func (r *DeployReport) Log() {
+	summary := [][2]string{
+		{"status", r.Status()},
+		{"tag", string(r.Tag)},
+		{"duration", r.FinishedAt.Sub(r.StartedAt).Round(time.Second).String()},
+	}
+	if r.Health != nil {
+		healthy, total := 0, 0
+		r.Health.Servers(func(service, server string, result *health.ServerHealth) {
+			total++
+			if result.Healthy {
+				healthy++
+			}
+		})
+		summary = append(summary, [2]string{"healthy", fmt.Sprintf("%d/%d", healthy, total)})
+	}
+	if len(r.SmokeTests) > 0 {
+		passed := 0
+		for _, test := range r.SmokeTests {
+			if test.Passed {
+				passed++
+			}
+		}
+		summary = append(summary, [2]string{"smoke tests", fmt.Sprintf("%d/%d passed", passed, len(r.SmokeTests))})
+	}
+	if r.Err != nil {
+		summary = append(summary, [2]string{"error", r.Err.Error()})
+	}
+	logging.LogKeyValues("Deploy report", summary)
+
+	for _, test := range r.SmokeTests {
+		status := "passed"
+		if !test.Passed {
+			status = "FAILED"
+		}
+		logging.Logger.Infof("  %s %s (%s)", status, test.Name, test.Duration)
+		if !test.Passed && test.Output != "" {
+			for _, line := range strings.Split(test.Output, "\n") {
+				logging.Logger.Infof("    %s", line)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// createdResources are the volumes and networks a deploy created, which
+// rolling it back removes
+type createdResources struct {
+	volumes  []string
+	networks []string
+}
+
+// remove removes the created networks, then the volumes, returning an error
+// naming those that could not be removed
+func (c *createdResources) remove(containerMgr *containers.ContainerManager) error {
+	var failed []string
+	for _, name := range c.networks {
+		if _, err := containerMgr.RemoveNetwork(name); err != nil {
+			logging.Logger.Warn("Failed to remove network", "network", name, "error", err)
+			failed = append(failed, "network "+name)
+		}
+	}
+	for _, name := range c.volumes {
+		if _, err := containerMgr.RemoveVolume(name); err != nil {
+			logging.Logger.Warn("Failed to remove volume", "volume", name, "error", err)
+			failed = append(failed, "volume "+name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ensureResources creates the compose project's volumes and networks that
+// are missing from the host, adding them to created, and returns them all
+// for recording in state. Their configuration is compared with that
+// recorded by the last deploy, as an existing volume or network keeps the
+// configuration it was created with and changes to it need recreating by hand.
+func (d *Deployer) ensureResources(current state.DeploymentState, created *createdResources) (map[string]*state.ComposeVolumeState, map[string]*state.ComposeNetworkState, error) {
+	var recordedVolumes map[string]*state.ComposeVolumeState
+	var recordedNetworks map[string]*state.ComposeNetworkState
+	if current.Compose != nil {
+		recordedVolumes = current.Compose.Volumes
+		recordedNetworks = current.Compose.Networks
+	}
+
+	volumes := make(map[string]*state.ComposeVolumeState)
+	for _, key := range sortedKeys(d.compose.Project.Volumes) {
+		config := d.compose.Project.Volumes[key]
+		if recorded, ok := recordedVolumes[key]; ok && recorded.Config != nil {
+			for _, change := range volumeDrift(recorded.Config, &config) {
+				logging.Logger.Warn("Volume configuration changed since it was created, recreate it to apply", "volume", config.Name, "change", change)
+			}
+		}
+
+		if !d.remoteContainerMgr.VolumeExists(config.Name) {
+			if config.External {
+				return nil, nil, fmt.Errorf("external volume %s does not exist", config.Name)
+			}
+			if _, err := d.remoteContainerMgr.CreateVolume(key, config); err != nil {
+				return nil, nil, fmt.Errorf("failed to create volume %s: %w", config.Name, err)
+			}
+			created.volumes = append(created.volumes, config.Name)
+			logging.Logger.Info("Created volume", "volume", config.Name)
+		}
+		volumes[key] = &state.ComposeVolumeState{Name: config.Name, Config: &config}
+	}
+
+	networks := make(map[string]*state.ComposeNetworkState)
+	for _, key := range sortedKeys(d.compose.Project.Networks) {
+		config := d.compose.Project.Networks[key]
+		if recorded, ok := recordedNetworks[key]; ok && recorded.Config != nil {
+			for _, change := range networkDrift(recorded.Config, &config) {
+				logging.Logger.Warn("Network configuration changed since it was created, recreate it to apply", "network", config.Name, "change", change)
+			}
+		}
+
+		if !d.remoteContainerMgr.NetworkExists(config.Name) {
+			if config.External {
+				return nil, nil, fmt.Errorf("external network %s does not exist", config.Name)
+			}
+			if _, err := d.remoteContainerMgr.CreateNetwork(key, config); err != nil {
+				return nil, nil, fmt.Errorf("failed to create network %s: %w", config.Name, err)
+			}
+			created.networks = append(created.networks, config.Name)
+			logging.Logger.Info("Created network", "network", config.Name)
+		}
+		networks[key] = &state.ComposeNetworkState{Name: config.Name, Config: &config}
+	}
+
+	// volumes hold data, so those dropped from compose are kept, and kept
+	// in state, unless pruning was asked for
+	for _, key := range sortedKeys(recordedVolumes) {
+		recorded := recordedVolumes[key]
+		if _, ok := volumes[key]; ok || (recorded.Config != nil && bool(recorded.Config.External)) {
+			continue
+		}
+		if !d.pruneVolumes {
+			logging.Logger.Warn("Volume is no longer in compose, keeping it (deploy with --prune-volumes to remove it)", "volume", recorded.Name)
+			volumes[key] = recorded
+		}
+	}
+
+	return volumes, networks, nil
+}
+
+// pruneResources removes the volumes and networks recorded by the last
+// deploy that are no longer in compose, once the containers using them are
+// gone. Named volumes are only removed when pruning volumes was asked for.
+func (d *Deployer) pruneResources(current state.DeploymentState, volumes map[string]*state.ComposeVolumeState, networks map[string]*state.ComposeNetworkState) {
+	if current.Compose == nil {
+		return
+	}
+
+	if d.pruneVolumes {
+		for _, key := range sortedKeys(current.Compose.Volumes) {
+			recorded := current.Compose.Volumes[key]
+			if _, ok := volumes[key]; ok || (recorded.Config != nil && bool(recorded.Config.External)) {
+				continue
+			}
+			if _, err := d.remoteContainerMgr.RemoveVolume(recorded.Name); err != nil {
+				logging.Logger.Warn("Failed to remove volume", "volume", recorded.Name, "error", err)
+				continue
+			}
+			logging.Logger.Info("Removed volume", "volume", recorded.Name)
+		}
+	}
+
+	for _, key := range sortedKeys(current.Compose.Networks) {
+		recorded := current.Compose.Networks[key]
+		if _, ok := networks[key]; ok || (recorded.Config != nil && bool(recorded.Config.External)) {
+			continue
+		}
+		if _, err := d.remoteContainerMgr.RemoveNetwork(recorded.Name); err != nil {
+			logging.Logger.Warn("Failed to remove network", "network", recorded.Name, "error", err)
+			continue
+		}
+		logging.Logger.Info("Removed network", "network", recorded.Name)
+	}
+}
+
+// volumeDrift describes how config differs from the recorded configuration
+func volumeDrift(recorded, config *types.VolumeConfig) []string {
+	var changes []string
+	changes = appendChange(changes, "driver", recorded.Driver, config.Driver)
+	changes = appendChange(changes, "external", fmt.Sprint(recorded.External), fmt.Sprint(config.External))
+	changes = appendMapChanges(changes, "driver_opts", recorded.DriverOpts, config.DriverOpts)
+	changes = appendMapChanges(changes, "labels", recorded.Labels, config.Labels)
+	return changes
+}
+
+// networkDrift describes how config differs from the recorded configuration
+func networkDrift(recorded, config *types.NetworkConfig) []string {
+	var changes []string
+	changes = appendChange(changes, "driver", recorded.Driver, config.Driver)
+	changes = appendChange(changes, "external", fmt.Sprint(recorded.External), fmt.Sprint(config.External))
+	changes = appendChange(changes, "internal", fmt.Sprint(recorded.Internal), fmt.Sprint(config.Internal))
+	changes = appendChange(changes, "attachable", fmt.Sprint(recorded.Attachable), fmt.Sprint(config.Attachable))
+	changes = appendChange(changes, "enable_ipv6", fmt.Sprint(recorded.EnableIPv6 != nil && *recorded.EnableIPv6), fmt.Sprint(config.EnableIPv6 != nil && *config.EnableIPv6))
+	changes = appendChange(changes, "ipam.driver", recorded.Ipam.Driver, config.Ipam.Driver)
+	changes = appendChange(changes, "ipam.config", ipamPools(recorded.Ipam), ipamPools(config.Ipam))
+	changes = appendMapChanges(changes, "driver_opts", recorded.DriverOpts, config.DriverOpts)
+	changes = appendMapChanges(changes, "labels", recorded.Labels, config.Labels)
+	return changes
+}
+
+func appendChange(changes []string, field, recorded, config string) []string {
+	if recorded == config {
+		return changes
+	}
+	return append(changes, fmt.Sprintf("%s %q -> %q", field, recorded, config))
+}
+
+func appendMapChanges(changes []string, field string, recorded, config map[string]string) []string {
+	keys := make(map[string]bool)
+	for key := range recorded {
+		keys[key] = true
+	}
+	for key := range config {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		before, hadBefore := recorded[key]
+		after, hasAfter := config[key]
+		switch {
+		case !hadBefore:
+			changes = append(changes, fmt.Sprintf("%s.%s added", field, key))
+		case !hasAfter:
+			changes = append(changes, fmt.Sprintf("%s.%s removed", field, key))
+		case before != after:
+			changes = append(changes, fmt.Sprintf("%s.%s %q -> %q", field, key, before, after))
+		}
+	}
+	return changes
+}
+
+func ipamPools(ipam types.IPAMConfig) string {
+	var pools []string
+	for _, pool := range ipam.Config {
+		if pool != nil {
+			pools = append(pools, fmt.Sprintf("%s/%s/%s", pool.Subnet, pool.Gateway, pool.IPRange))
+		}
+	}
+	return fmt.Sprint(pools)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -23,6 +23,15 @@ type RollbackStep struct {
 type RollbackManager struct {
 	steps []RollbackStep
 	state *state.StateManager // Add state manager reference
+	// baseline is the state from before the deploy, which a complete
+	// rollback returns to
+	baseline *state.DeploymentState
+}
+
+// RollbackResult records the steps a rollback undid and those it could not
+type RollbackResult struct {
+	RolledBack []string
+	Failed     []string
 }
 
 // NewRollbackManager creates a new RollbackManager
@@ -33,6 +42,12 @@ func NewRollbackManager(state *state.StateManager) *RollbackManager {
 	}
 }
 
+// SetBaseline sets the state the deploy started from, which the state must
+// match again once rolled back
+func (rm *RollbackManager) SetBaseline(baseline state.DeploymentState) {
+	rm.baseline = &baseline
+}
+
 // AddRollbackStep registers a new rollback function
 func (rm *RollbackManager) AddRollbackStep(name string, fn RollbackFunc, verify func(context.Context) error) {
 	rm.steps = append(rm.steps, RollbackStep{
@@ -42,13 +57,22 @@ func (rm *RollbackManager) AddRollbackStep(name string, fn RollbackFunc, verify
 	})
 }
 
+// Pending returns the names of the registered steps, in the order they roll
+// back
+func (rm *RollbackManager) Pending() []string {
+	names := []string{}
+	for i := len(rm.steps) - 1; i >= 0; i-- {
+		names = append(names, rm.steps[i].name)
+	}
+	return names
+}
+
 // Rollback executes all registered rollback functions in reverse order
-func (rm *RollbackManager) Rollback(ctx context.Context) error {
+func (rm *RollbackManager) Rollback(ctx context.Context) (*RollbackResult, error) {
 	logging.Logger.Info("Rolling back deployment")
+	result := &RollbackResult{}
 	var errors []error
 
-	initialState, _ := rm.state.Load()
-
 	// Execute rollbacks in reverse order
 	for i := len(rm.steps) - 1; i >= 0; i-- {
 		step := rm.steps[i]
@@ -59,6 +83,7 @@ func (rm *RollbackManager) Rollback(ctx context.Context) error {
 
 		if err := step.rollback(timeoutCtx); err != nil {
 			errors = append(errors, fmt.Errorf("rollback step '%s' failed: %w", step.name, err))
+			result.Failed = append(result.Failed, step.name)
 			continue
 		}
 
@@ -66,20 +91,25 @@ func (rm *RollbackManager) Rollback(ctx context.Context) error {
 		if step.verify != nil {
 			if err := step.verify(timeoutCtx); err != nil {
 				errors = append(errors, fmt.Errorf("rollback verification '%s' failed: %w", step.name, err))
+				result.Failed = append(result.Failed, step.name)
+				continue
 			}
 		}
+		result.RolledBack = append(result.RolledBack, step.name)
 	}
 
-	// Verify final state matches initial state
-	finalState, err := rm.state.Load()
-	if err != nil {
-		errors = append(errors, fmt.Errorf("failed to load final state: %w", err))
-	} else if !initialState.Equal(&finalState) {
-		errors = append(errors, fmt.Errorf("final state does not match initial state"))
+	// Verify final state matches the state the deploy started from
+	if rm.baseline != nil {
+		finalState, err := rm.state.Load()
+		if err != nil {
+			errors = append(errors, fmt.Errorf("failed to load final state: %w", err))
+		} else if !rm.baseline.Equal(&finalState) {
+			errors = append(errors, fmt.Errorf("final state does not match initial state"))
+		}
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("rollback failed with %d errors: %v", len(errors), errors)
+		return result, fmt.Errorf("rollback failed with %d errors: %v", len(errors), errors)
 	}
-	return nil
+	return result, nil
 }
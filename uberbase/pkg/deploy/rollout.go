@@ -0,0 +1,261 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Strategies for rolling a deploy out to several hosts
+const (
+	// StrategySerial deploys to one host at a time
+	StrategySerial = "serial"
+	// StrategyBatch deploys to a batch of hosts at a time
+	StrategyBatch = "batch"
+	// StrategyParallel deploys to every host at once
+	StrategyParallel = "parallel"
+)
+
+// RolloutHost is a host to roll a deploy out to, with its deployer
+type RolloutHost struct {
+	Host     string
+	Deployer *Deployer
+}
+
+// HostResult is the outcome of a rollout on one host
+type HostResult struct {
+	Host string
+	// Report is the host's deploy report, nil when the rollout stopped
+	// before reaching the host
+	Report *DeployReport
+	// RolledBack is set when the host's completed deploy was rolled back
+	// because another host failed
+	RolledBack  bool
+	RollbackErr error
+}
+
+// Status describes the outcome on the host in a word
+func (h *HostResult) Status() string {
+	switch {
+	case h.Report == nil:
+		return "skipped"
+	case h.RolledBack:
+		return "rolled back"
+	case h.RollbackErr != nil:
+		return "rollback failed"
+	default:
+		return h.Report.Status()
+	}
+}
+
+// RolloutReport summarizes a rollout across its hosts
+type RolloutReport struct {
+	Tag        containers.ContainerTag
+	Strategy   string
+	BatchSize  int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Hosts      []*HostResult
+	Err        error
+}
+
+// Rollout deploys one build to several hosts, a batch at a time. When a host
+// fails the rollout stops and the hosts already deployed to are rolled back.
+type Rollout struct {
+	hosts        []RolloutHost
+	strategy     string
+	batchSize    int
+	skipRollback bool
+	report       *RolloutReport
+}
+
+// NewRollout creates a rollout to hosts with strategy, deploying batchSize
+// hosts at a time with StrategyBatch
+func NewRollout(hosts []RolloutHost, strategy string, batchSize int) (*Rollout, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts to deploy to")
+	}
+	switch strategy {
+	case StrategySerial, "":
+		strategy, batchSize = StrategySerial, 1
+	case StrategyBatch:
+		if batchSize < 1 {
+			return nil, fmt.Errorf("batch size must be at least 1")
+		}
+	case StrategyParallel:
+		batchSize = len(hosts)
+	default:
+		return nil, fmt.Errorf("unknown rollout strategy %q, expected serial, batch or parallel", strategy)
+	}
+	return &Rollout{
+		hosts:     hosts,
+		strategy:  strategy,
+		batchSize: batchSize,
+	}, nil
+}
+
+// SetSkipRollback sets whether hosts are left as they are when the rollout
+// fails, for debugging, rather than rolled back
+func (r *Rollout) SetSkipRollback(skip bool) {
+	r.skipRollback = skip
+	for _, host := range r.hosts {
+		host.Deployer.SetSkipRollback(skip)
+	}
+}
+
+// Report returns the report of the last rollout
+func (r *Rollout) Report() *RolloutReport {
+	return r.report
+}
+
+// Run builds and pushes the images once, then deploys them to each batch of
+// hosts in turn
+func (r *Rollout) Run(ctx context.Context) (err error) {
+	r.report = &RolloutReport{
+		Strategy:  r.strategy,
+		BatchSize: r.batchSize,
+		StartedAt: time.Now(),
+	}
+	for _, host := range r.hosts {
+		r.report.Hosts = append(r.report.Hosts, &HostResult{Host: host.Host})
+	}
+	defer func() {
+		r.report.FinishedAt = time.Now()
+		r.report.Err = err
+	}()
+
+	tag, err := r.hosts[0].Deployer.BuildImages()
+	if err != nil {
+		return err
+	}
+	r.report.Tag = tag
+	for _, host := range r.hosts {
+		host.Deployer.SetPrebuilt(true)
+	}
+
+	for start := 0; start < len(r.hosts); start += r.batchSize {
+		end := min(start+r.batchSize, len(r.hosts))
+		logging.Logger.Info("Deploying to batch", "hosts", fmt.Sprint(hostNames(r.hosts[start:end])))
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				deployer := r.hosts[i].Deployer
+				if err := deployer.DeployProject(); err != nil {
+					logging.Logger.Error("Deployment failed", "host", r.hosts[i].Host, "error", err)
+				}
+				r.report.Hosts[i].Report = deployer.Report()
+			}(i)
+		}
+		wg.Wait()
+
+		failed := []string{}
+		for _, result := range r.report.Hosts[start:end] {
+			if result.Report.Err != nil {
+				failed = append(failed, result.Host)
+			}
+		}
+		if len(failed) > 0 {
+			err := fmt.Errorf("deploy failed on %v, rollout stopped", failed)
+			if rollbackErr := r.rollback(ctx, end); rollbackErr != nil {
+				err = fmt.Errorf("%v: %w", err, rollbackErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback rolls back the hosts before end whose deploys completed, most
+// recent first. Hosts deployed to for the first time are left with nothing
+// deployed.
+func (r *Rollout) rollback(ctx context.Context, end int) error {
+	if r.skipRollback {
+		logging.Logger.Warn("Rollback skipped, leaving the hosts already deployed to in place")
+		return nil
+	}
+	var errs []error
+	for i := end - 1; i >= 0; i-- {
+		result := r.report.Hosts[i]
+		if result.Report == nil || result.Report.Err != nil || result.Report.Unchanged {
+			continue
+		}
+		logging.Logger.Info("Rolling back host", "host", result.Host)
+		if err := r.hosts[i].Deployer.Rollback(ctx); err != nil {
+			result.RollbackErr = err
+			errs = append(errs, fmt.Errorf("failed to roll back %s: %w", result.Host, err))
+			continue
+		}
+		result.RolledBack = true
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes the report as text
+func (r *RolloutReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "Tag:      %s\n", r.Tag)
+	if r.Strategy == StrategyBatch {
+		fmt.Fprintf(w, "Strategy: %s of %d\n", r.Strategy, r.BatchSize)
+	} else {
+		fmt.Fprintf(w, "Strategy: %s\n", r.Strategy)
+	}
+	fmt.Fprintf(w, "Duration: %s\n", r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+
+	fmt.Fprintf(w, "\nHosts:\n")
+	for _, result := range r.Hosts {
+		duration := "-"
+		if result.Report != nil {
+			duration = result.Report.FinishedAt.Sub(result.Report.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "  %-32s %-16s %s\n", result.Host, result.Status(), duration)
+		if result.Report != nil && result.Report.Err != nil {
+			fmt.Fprintf(w, "    %v\n", result.Report.Err)
+		}
+		if result.RollbackErr != nil {
+			fmt.Fprintf(w, "    %v\n", result.RollbackErr)
+		}
+	}
+}
+
+// Inventory lists the hosts to deploy to, for example:
+//
+//	hosts:
+//	  - web1.example.com
+//	  - web2.example.com
+type Inventory struct {
+	Hosts []string `yaml:"hosts"`
+}
+
+// LoadInventory reads the hosts of an inventory file
+func LoadInventory(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory %s: %w", path, err)
+	}
+	var inventory Inventory
+	if err := yaml.Unmarshal(data, &inventory); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	if len(inventory.Hosts) == 0 {
+		return nil, fmt.Errorf("inventory %s lists no hosts", path)
+	}
+	return inventory.Hosts, nil
+}
+
+func hostNames(hosts []RolloutHost) []string {
+	names := []string{}
+	for _, host := range hosts {
+		names = append(names, host.Host)
+	}
+	return names
+}
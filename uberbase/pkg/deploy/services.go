@@ -0,0 +1,80 @@
+package deploy
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+)
+
+// selectServices returns the services the deploy rolls, nil for all of them
+func (d *Deployer) selectServices() ([]string, error) {
+	if len(d.services) == 0 {
+		return nil, nil
+	}
+	selected, err := d.compose.SelectServices(d.services, d.withDependencies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select services: %w", err)
+	}
+	return selected, nil
+}
+
+// keepServices points the overrides of the services the deploy does not
+// roll at their current containers, and routes to those containers.
+// It returns the services kept and the overrides of those rolled.
+func (d *Deployer) keepServices(override *containers.ComposeOverride, selected []string, current state.DeploymentState) (map[string]bool, *containers.ComposeOverride, error) {
+	kept := make(map[string]bool)
+	rolled := &containers.ComposeOverride{Services: make(map[string]containers.ComposeServiceOverride)}
+	hostTags := make(map[string]containers.ContainerTag)
+
+	for name, service := range override.Services {
+		if selected == nil || slices.Contains(selected, name) {
+			rolled.Services[name] = service
+			continue
+		}
+
+		var recorded *state.ComposeServiceState
+		if current.Compose != nil {
+			recorded = current.Compose.Services[name]
+		}
+		if recorded == nil {
+			return nil, nil, fmt.Errorf("service %s has never been deployed, deploy it too with --service %s", name, name)
+		}
+		override.Services[name] = containers.ComposeServiceOverride{
+			RefName:  name,
+			Name:     recorded.ContainerName,
+			Hostname: recorded.Hostname,
+			Image:    recorded.Image,
+		}
+		kept[name] = true
+
+		tag := containers.ContainerTag(recorded.Tag)
+		if tag == "" {
+			tag = current.Tag
+		}
+		config := d.compose.Project.Services[name]
+		hostTags[config.Name] = tag
+		if config.Hostname != "" {
+			hostTags[config.Hostname] = tag
+		}
+	}
+
+	d.trafficManager.SetHostTags(hostTags)
+	return kept, rolled, nil
+}
+
+// deployed reports whether the services rolled already run tag, with
+// traffic routed to them
+func deployed(rolled *containers.ComposeOverride, current state.DeploymentState, tag containers.ContainerTag) bool {
+	if len(rolled.Services) == 0 || current.Traefik == nil || current.Traefik.Tag != tag || current.Compose == nil {
+		return false
+	}
+	for name, service := range rolled.Services {
+		recorded := current.Compose.Services[name]
+		if recorded == nil || recorded.Tag != string(tag) || recorded.ContainerName != service.Name {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+)
+
+// defaultSmokeTimeout bounds each smoke test that does not set a timeout
+const defaultSmokeTimeout = 30 * time.Second
+
+// SmokeSuite is a user-defined set of tests run against the live routes
+// after cut-over, for example:
+//
+//	http:
+//	  - name: homepage
+//	    url: https://example.com/
+//	    status: 200
+//	    body: Welcome
+//	run:
+//	  - name: e2e
+//	    service: smoke
+//	    command: [npm, run, smoke]
+type SmokeSuite struct {
+	HTTP []SmokeHTTPTest `yaml:"http"`
+	Run  []SmokeRunTest  `yaml:"run"`
+}
+
+// SmokeHTTPTest passes when a request to URL answers with Status, or any 2xx
+// status when Status is unset, and its body satisfies the assertions.
+type SmokeHTTPTest struct {
+	Name    string            `yaml:"name"`
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	// Body is a regular expression the response body must match
+	Body string `yaml:"body"`
+	// JSON asserts a value of a JSON response body, as path=value
+	JSON    string        `yaml:"json"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// SmokeRunTest passes when Command exits zero in a one-off container of the
+// compose service, which may be one kept out of deploys by a profile.
+type SmokeRunTest struct {
+	Name    string   `yaml:"name"`
+	Service string   `yaml:"service"`
+	Command []string `yaml:"command"`
+}
+
+// SmokeResult is the outcome of a single smoke test.
+type SmokeResult struct {
+	Name     string        `yaml:"name"`
+	Passed   bool          `yaml:"passed"`
+	Duration time.Duration `yaml:"duration"`
+	Output   string        `yaml:"output,omitempty"`
+}
+
+// LoadSmokeSuite reads a smoke suite from a YAML file.
+func LoadSmokeSuite(path string) (*SmokeSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read smoke tests: %w", err)
+	}
+	var suite SmokeSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse smoke tests: %w", err)
+	}
+	for i, test := range suite.HTTP {
+		if test.URL == "" {
+			return nil, fmt.Errorf("http smoke test %d has no url", i)
+		}
+		if _, err := test.probe(); err != nil {
+			return nil, fmt.Errorf("invalid http smoke test %s: %w", test.name(), err)
+		}
+	}
+	for i, test := range suite.Run {
+		if test.Service == "" {
+			return nil, fmt.Errorf("run smoke test %d has no service", i)
+		}
+	}
+	return &suite, nil
+}
+
+func (t SmokeHTTPTest) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.URL
+}
+
+func (t SmokeHTTPTest) probe() (*health.HTTPProbe, error) {
+	probe := &health.HTTPProbe{
+		URL:     t.URL,
+		Method:  strings.ToUpper(t.Method),
+		Status:  t.Status,
+		Headers: t.Headers,
+		Timeout: t.Timeout,
+	}
+	if probe.Timeout == 0 {
+		probe.Timeout = defaultSmokeTimeout
+	}
+	if t.Body != "" {
+		body, err := regexp.Compile(t.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex: %w", err)
+		}
+		probe.BodyRegex = body
+	}
+	if t.JSON != "" {
+		path, value, found := strings.Cut(t.JSON, "=")
+		if !found || path == "" {
+			return nil, fmt.Errorf("json must be path=value, got %q", t.JSON)
+		}
+		probe.JSONPath, probe.JSONValue = path, value
+	}
+	return probe, nil
+}
+
+func (t SmokeRunTest) name() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return strings.TrimSpace(t.Service + " " + strings.Join(t.Command, " "))
+}
+
+// runSmokeTests runs every test of the suite, returning their results and
+// an error naming the tests that failed
+func (d *Deployer) runSmokeTests(ctx context.Context, composeOverrideFilePath string) ([]SmokeResult, error) {
+	var results []SmokeResult
+	var failed []string
+
+	record := func(name string, started time.Time, output string, err error) {
+		result := SmokeResult{Name: name, Passed: err == nil, Duration: time.Since(started).Round(time.Millisecond), Output: output}
+		if err != nil {
+			result.Output = strings.TrimSpace(output + "\n" + err.Error())
+			failed = append(failed, name)
+			logging.Logger.Warn("Smoke test failed", "test", name, "error", err)
+		} else {
+			logging.Logger.Info("Smoke test passed", "test", name)
+		}
+		results = append(results, result)
+	}
+
+	for _, test := range d.smokeSuite.HTTP {
+		started := time.Now()
+		probe, err := test.probe()
+		if err == nil {
+			err = probe.Check(ctx)
+		}
+		record(test.name(), started, "", err)
+	}
+
+	for _, test := range d.smokeSuite.Run {
+		started := time.Now()
+		service, ok := d.compose.Project.Services[test.Service]
+		if !ok {
+			service, ok = d.compose.Project.DisabledServices[test.Service]
+		}
+		if !ok {
+			record(test.name(), started, "", fmt.Errorf("no compose service %s", test.Service))
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			record(test.name(), started, "", err)
+			continue
+		}
+		output, err := d.remoteContainerMgr.Run(&service, test.Command, false, composeOverrideFilePath)
+		record(test.name(), started, strings.TrimSpace(output), err)
+	}
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d of %d smoke tests failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return results, nil
+}
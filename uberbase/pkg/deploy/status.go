@@ -0,0 +1,316 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// statusCheckTimeout bounds the health checks of the routed servers
+const statusCheckTimeout = 10 * time.Second
+
+// HostStatus is what is deployed on a host and how it is doing
+type HostStatus struct {
+	Host string                  `json:"host"`
+	Tag  containers.ContainerTag `json:"tag"`
+	// Commit is the git commit the deployed images were built from, which
+	// is also their tag
+	Commit      string          `json:"commit"`
+	DeployedAt  string          `json:"deployed_at,omitempty"`
+	PreviousTag string          `json:"previous_tag,omitempty"`
+	Maintenance string          `json:"maintenance,omitempty"`
+	Services    []ServiceStatus `json:"services"`
+	Routes      []RouteStatus   `json:"routes"`
+}
+
+// ServiceStatus is the container running a compose service
+type ServiceStatus struct {
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	// Tag is the tag the service was last deployed with
+	Tag string `json:"tag"`
+	// State is the container's status, "missing" when it does not exist
+	State string `json:"state"`
+	// Health is the status of the container's healthcheck, if it has one
+	Health    string `json:"health,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+	Uptime    string `json:"uptime,omitempty"`
+}
+
+// RouteStatus is a router of the active Traefik configuration
+type RouteStatus struct {
+	Router      string   `json:"router"`
+	Protocol    string   `json:"protocol"`
+	Rule        string   `json:"rule,omitempty"`
+	EntryPoints []string `json:"entry_points,omitempty"`
+	Service     string   `json:"service"`
+	// Status is the router's status reported by Traefik's API, "unknown"
+	// when the API cannot be reached
+	Status  string         `json:"status"`
+	Servers []ServerStatus `json:"servers,omitempty"`
+}
+
+// ServerStatus is the outcome of checking one server of a routed service
+type ServerStatus struct {
+	Server  string `json:"server"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StatusInspector gathers the status of a host's deployment from its
+// recorded state, its containers and Traefik.
+type StatusInspector struct {
+	remoteExecutor core.Executor
+	containerMgr   *containers.ContainerManager
+	stateManager   *state.StateManager
+	healthChecker  *health.HealthChecker
+	traefikAPIURL  string
+}
+
+// NewStatusInspector creates an inspector that queries routers through
+// Traefik's API at traefikAPIURL, as reached from the host
+func NewStatusInspector(remoteExecutor core.Executor, compose *containers.ComposeProject, remoteWorkDir, traefikAPIURL string) (*StatusInspector, error) {
+	containerMgr, err := containers.NewContainerManager(remoteExecutor, compose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container manager: %w", err)
+	}
+	compose.RemoteFilePath = filepath.Join(remoteWorkDir, "docker-compose.yml")
+
+	// each server is checked once, a status is a snapshot, from the host
+	// where the servers' containers can be reached
+	healthChecker := health.NewHealthChecker(containerMgr)
+	healthChecker.SetThresholds(health.HealthThresholds{Success: 1, Failure: 1})
+	healthChecker.SetHostExecutor(remoteExecutor)
+
+	return &StatusInspector{
+		remoteExecutor: remoteExecutor,
+		containerMgr:   containerMgr,
+		stateManager:   state.NewStateManager(remoteWorkDir, remoteExecutor),
+		healthChecker:  healthChecker,
+		traefikAPIURL:  strings.TrimSuffix(traefikAPIURL, "/"),
+	}, nil
+}
+
+// SetStateBackend sets where the deployment state is kept, instead of the
+// host's work directory
+func (s *StatusInspector) SetStateBackend(backend state.StateBackend) {
+	s.stateManager.SetBackend(backend)
+}
+
+// SetStateKey sets the key the sensitive fields of the state are encrypted
+// with, or leaves them in plaintext when it is nil
+func (s *StatusInspector) SetStateKey(provider state.KeyProvider) {
+	s.stateManager.SetKeyProvider(provider)
+}
+
+// Status gathers the status of host's deployment
+func (s *StatusInspector) Status(ctx context.Context, host string) (*HostStatus, error) {
+	current, err := s.stateManager.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	status := &HostStatus{
+		Host:       host,
+		Tag:        current.Tag,
+		Commit:     string(current.Tag),
+		DeployedAt: current.DeployedAt,
+		Services:   []ServiceStatus{},
+		Routes:     []RouteStatus{},
+	}
+	if current.Previous != nil {
+		status.PreviousTag = string(current.Previous.Tag)
+	}
+	if current.Maintenance != nil {
+		status.Maintenance = current.Maintenance.Mode
+	}
+
+	if current.Compose != nil {
+		for _, name := range sortedKeys(current.Compose.Services) {
+			status.Services = append(status.Services, s.serviceStatus(current.Compose.Services[name]))
+		}
+	}
+
+	if current.Traefik != nil && current.Traefik.Active != nil {
+		status.Routes = s.routeStatuses(ctx, current.Traefik.Active)
+	}
+	return status, nil
+}
+
+func (s *StatusInspector) serviceStatus(service *state.ComposeServiceState) ServiceStatus {
+	status := ServiceStatus{
+		Service:   service.ServiceName,
+		Container: service.ContainerName,
+		Image:     service.Image,
+		Tag:       service.Tag,
+	}
+
+	info, err := s.containerMgr.Inspect(service.ContainerName)
+	if err != nil {
+		status.State = "missing"
+		return status
+	}
+	status.State = info.State.Status
+	if info.State.Health != nil {
+		status.Health = info.State.Health.Status
+	}
+	if info.State.Running {
+		status.StartedAt = info.State.StartedAt
+		if started, err := time.Parse(time.RFC3339Nano, info.State.StartedAt); err == nil {
+			status.Uptime = time.Since(started).Round(time.Second).String()
+		}
+	}
+	return status
+}
+
+// routeStatuses lists the routers of the active configuration with their
+// status in Traefik and the health of their services' servers
+func (s *StatusInspector) routeStatuses(ctx context.Context, active *traefik.TraefikDynamicConfiguration) []RouteStatus {
+	routers, err := s.traefikRouters()
+	if err != nil {
+		logging.Logger.Warn("Cannot query Traefik routers", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusCheckTimeout)
+	defer cancel()
+	report := health.NewHealthReport()
+
+	routes := []RouteStatus{}
+	if active.HTTP != nil {
+		httpReport, _ := s.healthChecker.WaitForHTTPHealthChecks(ctx, active.HTTP.Services)
+		report.Merge(httpReport)
+		for _, name := range sortedKeys(active.HTTP.Routers) {
+			router := active.HTTP.Routers[name]
+			routes = append(routes, RouteStatus{Router: name, Protocol: "http", Rule: router.Rule, EntryPoints: router.EntryPoints, Service: router.Service})
+		}
+	}
+	if active.TCP != nil {
+		tcpReport, _ := s.healthChecker.WaitForTCPHealthChecks(ctx, active.TCP.Services)
+		report.Merge(tcpReport)
+		for _, name := range sortedKeys(active.TCP.Routers) {
+			router := active.TCP.Routers[name]
+			routes = append(routes, RouteStatus{Router: name, Protocol: "tcp", Rule: router.Rule, EntryPoints: router.EntryPoints, Service: router.Service})
+		}
+	}
+	if active.UDP != nil {
+		// UDP has no handshake to probe, so its servers go unchecked
+		for _, name := range sortedKeys(active.UDP.Routers) {
+			router := active.UDP.Routers[name]
+			routes = append(routes, RouteStatus{Router: name, Protocol: "udp", EntryPoints: router.EntryPoints, Service: router.Service})
+		}
+	}
+
+	for i := range routes {
+		route := &routes[i]
+		route.Status = "unknown"
+		if status, ok := routers[route.Protocol+" "+route.Router]; ok {
+			route.Status = status
+		}
+		service, ok := report.Services[route.Service]
+		if !ok {
+			continue
+		}
+		for _, server := range sortedKeys(service.Servers) {
+			result := service.Servers[server]
+			serverStatus := ServerStatus{Server: server, Healthy: result.Healthy}
+			if !result.Healthy {
+				serverStatus.Error = result.LastError
+			}
+			route.Servers = append(route.Servers, serverStatus)
+		}
+	}
+	return routes
+}
+
+// traefikRouter is a router as listed by Traefik's API
+type traefikRouter struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// traefikRouters returns the status of Traefik's routers, keyed by protocol
+// and name without the provider suffix
+func (s *StatusInspector) traefikRouters() (map[string]string, error) {
+	statuses := make(map[string]string)
+	for _, protocol := range []string{"http", "tcp", "udp"} {
+		url := s.traefikAPIURL + "/" + protocol + "/routers"
+		output, err := s.remoteExecutor.Exec("curl -sf " + url)
+		if err != nil {
+			return statuses, fmt.Errorf("failed to fetch routers from %s: %w", url, err)
+		}
+		var routers []traefikRouter
+		if err := json.Unmarshal([]byte(output), &routers); err != nil {
+			return statuses, fmt.Errorf("failed to parse routers from %s: %w", url, err)
+		}
+		for _, router := range routers {
+			name, _, _ := strings.Cut(router.Name, "@")
+			statuses[protocol+" "+name] = router.Status
+		}
+	}
+	return statuses, nil
+}
+
+// Print writes the status as text
+func (h *HostStatus) Print(w io.Writer) {
+	if h.Tag == "" {
+		fmt.Fprintf(w, "%s has no deployment\n", h.Host)
+		return
+	}
+	fmt.Fprintf(w, "Host:        %s\n", h.Host)
+	fmt.Fprintf(w, "Tag:         %s\n", h.Tag)
+	fmt.Fprintf(w, "Commit:      %s\n", h.Commit)
+	if h.DeployedAt != "" {
+		fmt.Fprintf(w, "Deployed:    %s\n", h.DeployedAt)
+	}
+	if h.PreviousTag != "" {
+		fmt.Fprintf(w, "Previous:    %s\n", h.PreviousTag)
+	}
+	if h.Maintenance != "" {
+		fmt.Fprintf(w, "Maintenance: %s\n", h.Maintenance)
+	}
+
+	fmt.Fprintf(w, "\nServices:\n")
+	for _, service := range h.Services {
+		health := service.Health
+		if health == "" {
+			health = "-"
+		}
+		uptime := service.Uptime
+		if uptime == "" {
+			uptime = "-"
+		}
+		fmt.Fprintf(w, "  %-16s %-32s %-10s %-10s %-10s %-12s %s\n", service.Service, service.Container, service.State, health, uptime, shortTag(service.Tag), service.Image)
+	}
+
+	fmt.Fprintf(w, "\nRoutes:\n")
+	for _, route := range h.Routes {
+		fmt.Fprintf(w, "  %-4s %-24s %-9s %s -> %s\n", route.Protocol, route.Router, route.Status, route.Rule, route.Service)
+		for _, server := range route.Servers {
+			if server.Healthy {
+				fmt.Fprintf(w, "         %s healthy\n", server.Server)
+			} else {
+				fmt.Fprintf(w, "         %s unhealthy: %s\n", server.Server, server.Error)
+			}
+		}
+	}
+}
+
+// shortTag abbreviates a commit tag as git does
+func shortTag(tag string) string {
+	if len(tag) > 12 {
+		return tag[:12]
+	}
+	return tag
+}
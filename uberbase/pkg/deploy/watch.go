@@ -0,0 +1,316 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/events"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+)
+
+const (
+	defaultWatchInterval         = 30 * time.Second
+	defaultWatchFailureThreshold = 3
+)
+
+// WatchOptions configures a Watchdog.
+type WatchOptions struct {
+	Host string
+	// Interval is how often the active deployment is probed
+	Interval time.Duration
+	// FailureThreshold is how many consecutive rounds a server must fail
+	// before the deployment is considered unhealthy
+	FailureThreshold int
+	// Window is how long after a deploy an unhealthy deployment is rolled
+	// back; later it is only reported. Zero rolls back at any time.
+	Window  time.Duration
+	Emitter events.Emitter
+}
+
+// Watchdog keeps probing a host's active deployment after it is deployed,
+// rolling it back to the previous deployment when it becomes unhealthy.
+type Watchdog struct {
+	remoteExecutor core.Executor
+	containerMgr   *containers.ContainerManager
+	stateManager   *state.StateManager
+	healthChecker  *health.HealthChecker
+	remoteWorkDir  string
+	options        WatchOptions
+
+	tag       containers.ContainerTag
+	failures  map[string]int
+	unhealthy bool
+}
+
+func NewWatchdog(remoteExecutor core.Executor, compose *containers.ComposeProject, remoteWorkDir string, options WatchOptions) (*Watchdog, error) {
+	containerMgr, err := containers.NewContainerManager(remoteExecutor, compose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container manager: %w", err)
+	}
+	compose.RemoteFilePath = filepath.Join(remoteWorkDir, "docker-compose.yml")
+
+	if options.Interval <= 0 {
+		options.Interval = defaultWatchInterval
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = defaultWatchFailureThreshold
+	}
+	if options.Emitter == nil {
+		options.Emitter = events.LogEmitter{}
+	}
+
+	// each round checks every server once, the watchdog counts failed rounds
+	healthChecker := health.NewHealthChecker(containerMgr)
+	healthChecker.SetThresholds(health.HealthThresholds{Success: 1, Failure: 1})
+	healthChecker.SetHostExecutor(remoteExecutor)
+
+	return &Watchdog{
+		remoteExecutor: remoteExecutor,
+		containerMgr:   containerMgr,
+		stateManager:   state.NewStateManager(remoteWorkDir, remoteExecutor),
+		healthChecker:  healthChecker,
+		remoteWorkDir:  remoteWorkDir,
+		options:        options,
+		failures:       make(map[string]int),
+	}, nil
+}
+
+// SetStateBackend sets where the deployment state is kept, instead of the
+// host's work directory
+func (w *Watchdog) SetStateBackend(backend state.StateBackend) {
+	w.stateManager.SetBackend(backend)
+}
+
+// SetStateKey sets the key the sensitive fields of the state are encrypted
+// with, or leaves them in plaintext when it is nil
+func (w *Watchdog) SetStateKey(provider state.KeyProvider) {
+	w.stateManager.SetKeyProvider(provider)
+}
+
+// Run probes the active deployment every interval until ctx is done.
+func (w *Watchdog) Run(ctx context.Context) error {
+	logging.Logger.Info("Watching deployment", "host", w.options.Host, "interval", w.options.Interval.String())
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.watch(ctx); err != nil {
+			logging.Logger.Warn("Watch round failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch runs one round of probes, rolling back when the active deployment
+// has breached its failure threshold
+func (w *Watchdog) watch(ctx context.Context) error {
+	current, err := w.stateManager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	// a new deploy starts its failure count afresh
+	if current.Tag != w.tag {
+		w.tag = current.Tag
+		w.failures = make(map[string]int)
+		w.unhealthy = false
+	}
+	// nothing is served to probe while in maintenance
+	if current.Tag == "" || current.Maintenance != nil {
+		return nil
+	}
+
+	report := w.check(ctx, current)
+	var breached []string
+	report.Servers(func(service, server string, result *health.ServerHealth) {
+		key := service + " " + server
+		if result.Healthy {
+			delete(w.failures, key)
+			return
+		}
+		w.failures[key]++
+		logging.Logger.Debug("Watched server failed", "service", service, "server", server, "failures", w.failures[key], "error", result.LastError)
+		if w.failures[key] >= w.options.FailureThreshold {
+			breached = append(breached, fmt.Sprintf("%s: %s: %s", service, server, result.LastError))
+		}
+	})
+
+	if len(breached) == 0 {
+		w.unhealthy = false
+		return nil
+	}
+	// report each spell of ill health once
+	if w.unhealthy {
+		return nil
+	}
+	w.unhealthy = true
+
+	sort.Strings(breached)
+	message := fmt.Sprintf("%d servers failed %d consecutive checks: %s", len(breached), w.options.FailureThreshold, strings.Join(breached, "; "))
+
+	if current.Previous == nil || !w.withinWindow(current.DeployedAt) {
+		return w.emit(events.EventUnhealthy, current, message)
+	}
+
+	logging.Logger.Warn("Deployment unhealthy, rolling back", "tag", current.Tag, "previous", current.Previous.Tag)
+	if err := w.RollbackToPrevious(ctx, current); err != nil {
+		w.emit(events.EventRollbackFailed, current, fmt.Sprintf("%s; rollback failed: %v", message, err))
+		return err
+	}
+	return w.emit(events.EventRolledBack, current, message)
+}
+
+// check probes the containers, label probes and routed servers of the
+// active deployment once
+func (w *Watchdog) check(ctx context.Context, current state.DeploymentState) *health.HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, w.options.Interval)
+	defer cancel()
+
+	services := map[string]containers.ComposeServiceOverride{}
+	if current.Compose != nil {
+		services = overridesFromState(current.Compose.Services)
+	}
+	report := w.healthChecker.CheckContainers(services)
+
+	probes := make(map[string][]health.Probe)
+	overrideFilePath := filepath.Join(w.remoteWorkDir, "docker-compose.override.yml")
+	for _, service := range services {
+		serviceProbes, err := w.healthChecker.Probes(service, overrideFilePath)
+		if err != nil {
+			logging.Logger.Warn("Skipping probes", "service", service.RefName, "error", err)
+			continue
+		}
+		if len(serviceProbes) > 0 {
+			probes[service.Name] = serviceProbes
+		}
+	}
+	report.Merge(w.healthChecker.RunProbes(ctx, probes))
+
+	if current.Traefik != nil && current.Traefik.Active != nil {
+		active := current.Traefik.Active
+		if active.HTTP != nil {
+			httpReport, _ := w.healthChecker.WaitForHTTPHealthChecks(ctx, active.HTTP.Services)
+			report.Merge(httpReport)
+		}
+		if active.TCP != nil {
+			tcpReport, _ := w.healthChecker.WaitForTCPHealthChecks(ctx, active.TCP.Services)
+			report.Merge(tcpReport)
+		}
+	}
+	return report
+}
+
+func (w *Watchdog) withinWindow(deployedAt string) bool {
+	if w.options.Window == 0 {
+		return true
+	}
+	deployed, err := time.Parse(time.RFC3339, deployedAt)
+	if err != nil {
+		return false
+	}
+	return time.Since(deployed) <= w.options.Window
+}
+
+// RollbackToPrevious brings the previous deployment's containers back up,
+// routes traffic to them once they are healthy and removes the current
+// deployment's containers.
+func (w *Watchdog) RollbackToPrevious(ctx context.Context, current state.DeploymentState) error {
+	previous := current.Previous
+	if previous == nil {
+		return fmt.Errorf("no previous deployment to roll back to")
+	}
+
+	override := &containers.ComposeOverride{Services: overridesFromState(previous.Services)}
+	overrideFilePath, err := override.WriteToFile(w.remoteExecutor.(*core.RemoteExecutor), w.remoteWorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to write override file: %w", err)
+	}
+
+	if _, err := w.containerMgr.PullImages(recordedImages(previous.Services)...); err != nil {
+		return fmt.Errorf("failed to pull previous containers: %w", err)
+	}
+	if _, err := w.containerMgr.Up(overrideFilePath); err != nil {
+		return fmt.Errorf("failed to bring up previous containers: %w", err)
+	}
+
+	// the previous containers get the same allowance to start as a deploy
+	healthChecker := health.NewHealthChecker(w.containerMgr)
+	healthChecker.SetHostExecutor(w.remoteExecutor)
+	if _, err := healthChecker.WaitForContainers(ctx, override.Services, overrideFilePath); err != nil {
+		return fmt.Errorf("previous containers are not healthy: %w", err)
+	}
+
+	if err := w.stateManager.RestorePrevious(); err != nil {
+		return fmt.Errorf("failed to restore previous deployment: %w", err)
+	}
+
+	restored := make(map[string]bool)
+	for _, service := range previous.Services {
+		restored[service.ContainerName] = true
+	}
+	unhealthy := []string{}
+	for _, service := range current.Compose.Services {
+		if restored[service.ContainerName] {
+			continue
+		}
+		unhealthy = append(unhealthy, service.ContainerName)
+		if _, err := w.containerMgr.Stop(service.ContainerName, stopGracePeriod(w.containerMgr.Compose, service.ServiceName)); err != nil {
+			logging.Logger.Warn("Failed to stop unhealthy container gracefully", "container", service.ContainerName, "error", err)
+		}
+	}
+	if len(unhealthy) > 0 {
+		if _, err := w.containerMgr.Down(unhealthy, overrideFilePath); err != nil {
+			return fmt.Errorf("failed to bring down unhealthy containers: %w", err)
+		}
+	}
+
+	logging.Logger.Info("Rolled back deployment", "from", current.Tag, "to", previous.Tag)
+	return nil
+}
+
+func (w *Watchdog) emit(eventType string, current state.DeploymentState, message string) error {
+	event := events.NewEvent(eventType, w.options.Host, message)
+	event.Tag = string(current.Tag)
+	if current.Previous != nil {
+		event.PreviousTag = string(current.Previous.Tag)
+	}
+	if err := w.options.Emitter.Emit(event); err != nil {
+		return fmt.Errorf("failed to emit %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// recordedImages returns the images the services were deployed with, which
+// are on different tags after a deploy of some services
+func recordedImages(services map[string]*state.ComposeServiceState) []string {
+	images := []string{}
+	for _, name := range sortedKeys(services) {
+		images = append(images, services[name].Image)
+	}
+	return images
+}
+
+// overridesFromState recreates the overrides that deployed the services
+func overridesFromState(services map[string]*state.ComposeServiceState) map[string]containers.ComposeServiceOverride {
+	overrides := make(map[string]containers.ComposeServiceOverride, len(services))
+	for _, service := range services {
+		overrides[service.ServiceName] = containers.ComposeServiceOverride{
+			RefName:  service.ServiceName,
+			Name:     service.ContainerName,
+			Hostname: service.Hostname,
+			Image:    service.Image,
+		}
+	}
+	return overrides
+}
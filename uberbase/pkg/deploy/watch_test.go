@@ -0,0 +1,24 @@
+package deploy
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+)
+
+// TestRecordedImages checks that services left on different tags by a
+// deploy of some services are pulled at their own tags
+func TestRecordedImages(t *testing.T) {
+	services := map[string]*state.ComposeServiceState{
+		"api":    {ServiceName: "api", Image: "registry.example.com/app/api:b2", Tag: "b2"},
+		"worker": {ServiceName: "worker", Image: "registry.example.com/app/worker:a1", Tag: "a1"},
+		"cache":  {ServiceName: "cache", Image: "redis:7"},
+	}
+
+	images := recordedImages(services)
+	want := []string{"registry.example.com/app/api:b2", "redis:7", "registry.example.com/app/worker:a1"}
+	if !slices.Equal(images, want) {
+		t.Errorf("recordedImages = %v, want %v", images, want)
+	}
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+)
+
+const (
+	// EventUnhealthy is emitted when a deployment breaches its health thresholds
+	EventUnhealthy = "unhealthy"
+	// EventRolledBack is emitted when a deployment is rolled back to its predecessor
+	EventRolledBack = "rolled_back"
+	// EventRollbackFailed is emitted when rolling a deployment back fails
+	EventRollbackFailed = "rollback_failed"
+)
+
+// Event records something that happened to a host's deployment.
+type Event struct {
+	Type        string `json:"type"`
+	Host        string `json:"host"`
+	Tag         string `json:"tag,omitempty"`
+	PreviousTag string `json:"previous_tag,omitempty"`
+	Message     string `json:"message"`
+	Time        string `json:"time"`
+}
+
+// NewEvent creates an event of the given type, timestamped now
+func NewEvent(eventType, host, message string) Event {
+	return Event{
+		Type:    eventType,
+		Host:    host,
+		Message: message,
+		Time:    time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Emitter publishes events.
+type Emitter interface {
+	Emit(event Event) error
+}
+
+// Emitters publishes each event to all of its emitters.
+type Emitters []Emitter
+
+func (e Emitters) Emit(event Event) error {
+	var errs []error
+	for _, emitter := range e {
+		if err := emitter.Emit(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogEmitter writes events to the log.
+type LogEmitter struct{}
+
+func (LogEmitter) Emit(event Event) error {
+	entry := logging.Logger.WithFields(map[string]interface{}{
+		"event": event.Type,
+		"host":  event.Host,
+		"tag":   event.Tag,
+	})
+	if event.Type == EventUnhealthy || event.Type == EventRollbackFailed {
+		entry.Warn(event.Message)
+	} else {
+		entry.Info(event.Message)
+	}
+	return nil
+}
+
+// FileEmitter appends events as JSON lines to a file on a host.
+type FileEmitter struct {
+	Executor core.Executor
+	Path     string
+}
+
+// NewFileEmitter appends events to events.log in the work directory
+func NewFileEmitter(executor core.Executor, workDir string) *FileEmitter {
+	return &FileEmitter{
+		Executor: executor,
+		Path:     filepath.Join(workDir, "events.log"),
+	}
+}
+
+func (f *FileEmitter) Emit(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	cmd := fmt.Sprintf("cat <<'EOF' >> %s\n%s\nEOF", f.Path, strings.TrimSpace(string(line)))
+	if _, err := f.Executor.Exec(cmd); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// WebhookEmitter posts events as JSON to a URL.
+type WebhookEmitter struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (w *WebhookEmitter) Emit(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
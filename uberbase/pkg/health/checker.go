@@ -2,28 +2,20 @@ package health
 
 import (
 	"context"
-	"fmt"
-	"net/http"
-	"sync"
 	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
 )
 
-// Add HTTP health check configuration
-type HTTPHealthCheck struct {
-	URL      string
-	Method   string
-	Path     string
-	Status   int
-	Interval time.Duration
-	Timeout  time.Duration
-	Headers  map[string]string
-}
+// defaultServerTimeout bounds each attempt to reach a routed server
+const defaultServerTimeout = 5 * time.Second
 
 type HealthChecker struct {
 	containerMgr *containers.ContainerManager
+	hostExecutor core.Executor
+	thresholds   HealthThresholds
 }
 
 func NewHealthChecker(containerMgr *containers.ContainerManager) *HealthChecker {
@@ -32,123 +24,68 @@ func NewHealthChecker(containerMgr *containers.ContainerManager) *HealthChecker
 	}
 }
 
-func (h *HealthChecker) WaitForContainers(ctx context.Context, services map[string]containers.ComposeServiceOverride) (chan bool, error) {
-	checks := make([]func() bool, 0, len(services))
-
-	for _, service := range services {
-		svc := service // Create a new variable to avoid closure issues
-		checks = append(checks, func() bool {
-			info, err := h.containerMgr.Inspect(svc.Name)
-			return err == nil && info.State.Status == "running"
-		})
-	}
-
-	return h.waitForAll(checks...)
+// SetThresholds sets when the servers of subsequent checks are settled as
+// healthy or unhealthy.
+func (h *HealthChecker) SetThresholds(thresholds HealthThresholds) {
+	h.thresholds = thresholds
 }
 
-func (h *HealthChecker) WaitForHTTPHealthChecks(ctx context.Context, services map[string]traefik.TraefikService) (chan bool, error) {
-	checks := make([]func() bool, 0)
-
-	for _, service := range services {
-		if service.LoadBalancer != nil && service.LoadBalancer.HealthCheck.Path != "" {
-			for _, server := range service.LoadBalancer.Servers {
-				check := h.createHTTPHealthCheck(HTTPHealthCheck{
-					URL:      server.URL,
-					Method:   service.LoadBalancer.HealthCheck.Method,
-					Path:     service.LoadBalancer.HealthCheck.Path,
-					Status:   service.LoadBalancer.HealthCheck.Status,
-					Headers:  service.LoadBalancer.HealthCheck.Headers,
-					Interval: 1 * time.Second, // Default interval
-					Timeout:  5 * time.Second, // Default timeout
-				})
-				checks = append(checks, check)
-			}
-		}
-	}
+// SetHostExecutor runs the probes that reach the containers over the network
+// on the host the containers run on, instead of from this machine
+func (h *HealthChecker) SetHostExecutor(executor core.Executor) {
+	h.hostExecutor = executor
+}
 
-	if len(checks) == 0 {
-		// If no health checks defined, return immediately successful
-		ch := make(chan bool, 1)
-		ch <- true
-		close(ch)
-		return ch, nil
+// onHost returns what runs probes of the container on the host, or nil when
+// they run from this machine. An empty container is named by the address.
+func (h *HealthChecker) onHost(container string) *hostRunner {
+	if h.hostExecutor == nil {
+		return nil
 	}
-
-	return h.waitForAll(checks...)
+	return &hostRunner{executor: h.hostExecutor, containerMgr: h.containerMgr, container: container}
 }
 
-func (h *HealthChecker) waitForAll(fs ...func() bool) (chan bool, error) {
-	healthy := make(chan bool)
-
-	go func() {
-		for {
-			var wg sync.WaitGroup
-			results := make(chan bool, len(fs))
-
-			// Run all checks in parallel
-			for _, f := range fs {
-				wg.Add(1)
-				go func(check func() bool) {
-					defer wg.Done()
-					results <- check()
-				}(f)
-			}
-
-			// Close results after all checks complete
-			go func() {
-				wg.Wait()
-				close(results)
-			}()
+// WaitForHTTPHealthChecks probes the servers of every service that declares a
+// Traefik health check until each is healthy, fails or ctx is done. The
+// report is returned with an error describing any unhealthy servers.
+func (h *HealthChecker) WaitForHTTPHealthChecks(ctx context.Context, services map[string]traefik.TraefikService) (*HealthReport, error) {
+	probes := make(map[string][]Probe)
 
-			// Check if all results are true
-			allHealthy := true
-			for result := range results {
-				if !result {
-					allHealthy = false
-					break
-				}
-			}
-
-			if allHealthy {
-				healthy <- true
-				close(healthy)
-				return
-			}
-
-			time.Sleep(1 * time.Second)
+	for name, service := range services {
+		if service.LoadBalancer == nil || service.LoadBalancer.HealthCheck == nil || service.LoadBalancer.HealthCheck.Path == "" {
+			continue
+		}
+		healthCheck := service.LoadBalancer.HealthCheck
+		for _, server := range service.LoadBalancer.Servers {
+			probes[name] = append(probes[name], &HTTPProbe{
+				URL:     server.URL + healthCheck.Path,
+				Method:  healthCheck.Method,
+				Status:  healthCheck.Status,
+				Headers: healthCheck.Headers,
+				Timeout: defaultServerTimeout,
+				host:    h.onHost(""),
+			})
 		}
-	}()
-
-	return healthy, nil
-}
-
-func (h *HealthChecker) createHTTPHealthCheck(config HTTPHealthCheck) func() bool {
-	client := &http.Client{
-		Timeout: config.Timeout,
 	}
 
-	return func() bool {
-		req, err := http.NewRequest(config.Method, fmt.Sprintf("%s%s", config.URL, config.Path), nil)
-		if err != nil {
-			return false
-		}
+	report := h.RunProbes(ctx, probes)
+	return report, report.Err()
+}
 
-		// Add headers if specified
-		for key, value := range config.Headers {
-			req.Header.Add(key, value)
-		}
+// WaitForTCPHealthChecks waits until a TCP connection can be opened to every
+// server of the given services, reporting as WaitForHTTPHealthChecks does.
+func (h *HealthChecker) WaitForTCPHealthChecks(ctx context.Context, services map[string]traefik.TraefikTCPService) (*HealthReport, error) {
+	probes := make(map[string][]Probe)
 
-		resp, err := client.Do(req)
-		if err != nil {
-			return false
+	for name, service := range services {
+		if service.LoadBalancer == nil {
+			continue
 		}
-		defer resp.Body.Close()
-
-		// If no specific status is required, accept any 2xx status
-		if config.Status == 0 {
-			return resp.StatusCode >= 200 && resp.StatusCode < 300
+		for _, server := range service.LoadBalancer.Servers {
+			probes[name] = append(probes[name], &TCPProbe{Address: server.Address, Timeout: defaultServerTimeout, host: h.onHost("")})
 		}
-
-		return resp.StatusCode == config.Status
 	}
+
+	report := h.RunProbes(ctx, probes)
+	return report, report.Err()
 }
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+const (
+	// compose defaults for healthchecks that leave them unset
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 30 * time.Second
+	defaultHealthCheckRetries  = 3
+
+	// runningTimeout bounds the wait for containers without a healthcheck
+	runningTimeout        = 10 * time.Second
+	containerPollInterval = 1 * time.Second
+
+	// probeTimeout bounds the wait for a ready container's label probes to settle
+	probeTimeout = 60 * time.Second
+)
+
+// WaitForContainers waits until every container is ready. Containers whose
+// compose service declares a healthcheck must report healthy, others need
+// only be running. The wait for a healthcheck allows for its start_period,
+// interval, timeout and retries before giving up. Once ready, any probes the
+// service selects through its uberbase.health labels must pass too. The
+// report is returned with an error describing any unhealthy containers.
+func (h *HealthChecker) WaitForContainers(ctx context.Context, services map[string]containers.ComposeServiceOverride, composeOverrideFilePath string) (*HealthReport, error) {
+	// read every service's probes before waiting on any, so that a bad
+	// probe fails the check without leaving goroutines behind
+	serviceProbes := make(map[string][]Probe, len(services))
+	for _, service := range services {
+		probes, err := h.Probes(service, composeOverrideFilePath)
+		if err != nil {
+			return nil, err
+		}
+		serviceProbes[service.Name] = probes
+	}
+
+	report := NewHealthReport()
+	probed := make(chan *HealthReport, len(services))
+	var wg sync.WaitGroup
+
+	for _, service := range services {
+		probes := serviceProbes[service.Name]
+		container := report.server(service.Name, "container")
+
+		wg.Add(1)
+		go func(service containers.ComposeServiceOverride) {
+			defer wg.Done()
+			if err := h.waitForContainer(ctx, service.Name, h.composeHealthCheck(service.RefName), container); err != nil {
+				container.LastError = err.Error()
+				return
+			}
+			container.Healthy = true
+			if len(probes) == 0 {
+				return
+			}
+
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			probed <- h.RunProbes(probeCtx, map[string][]Probe{service.Name: probes})
+		}(service)
+	}
+
+	wg.Wait()
+	close(probed)
+
+	for probeReport := range probed {
+		report.Merge(probeReport)
+	}
+	report.summarize()
+	return report, report.Err()
+}
+
+// CheckContainers inspects each container once, reporting those that are not
+// running or that their healthcheck reports as unhealthy.
+func (h *HealthChecker) CheckContainers(services map[string]containers.ComposeServiceOverride) *HealthReport {
+	report := NewHealthReport()
+	for _, service := range services {
+		result := report.server(service.Name, "container")
+		result.Attempts = 1
+
+		info, err := h.containerMgr.Inspect(service.Name)
+		switch {
+		case err != nil:
+			result.LastError = err.Error()
+		case !info.State.Running:
+			result.LastError = fmt.Sprintf("container is %s", info.State.Status)
+		case info.State.Health != nil && info.State.Health.Status == "unhealthy":
+			result.LastError = "container is unhealthy" + healthOutput(info)
+		default:
+			result.Healthy = true
+		}
+	}
+	report.summarize()
+	return report
+}
+
+// composeHealthCheck returns the healthcheck the compose service declares,
+// or nil when it has none or has it disabled.
+func (h *HealthChecker) composeHealthCheck(serviceName string) *types.HealthCheckConfig {
+	if h.containerMgr.Compose == nil || h.containerMgr.Compose.Project == nil {
+		return nil
+	}
+	service, ok := h.containerMgr.Compose.Project.Services[serviceName]
+	if !ok || service.HealthCheck == nil || service.HealthCheck.Disable {
+		return nil
+	}
+	if len(service.HealthCheck.Test) > 0 && service.HealthCheck.Test[0] == "NONE" {
+		return nil
+	}
+	return service.HealthCheck
+}
+
+func (h *HealthChecker) waitForContainer(ctx context.Context, name string, healthCheck *types.HealthCheckConfig, result *ServerHealth) error {
+	timeout := runningTimeout
+	if healthCheck != nil {
+		timeout = healthCheckTimeout(healthCheck)
+	}
+	deadline := time.Now().Add(timeout)
+
+	var last containers.ContainerInspectInfo
+	for {
+		result.Attempts++
+		info, err := h.containerMgr.Inspect(name)
+		if err == nil {
+			last = info
+			if info.State.Status == "exited" || info.State.Dead {
+				return fmt.Errorf("container %s exited with code %d%s", name, info.State.ExitCode, healthOutput(info))
+			}
+			if healthCheck == nil && info.State.Running {
+				return nil
+			}
+			if healthCheck != nil && info.State.Health != nil {
+				switch info.State.Health.Status {
+				case "healthy":
+					return nil
+				case "unhealthy":
+					return fmt.Errorf("container %s is unhealthy after %d failed checks%s", name, info.State.Health.FailingStreak, healthOutput(info))
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if healthCheck == nil {
+				return fmt.Errorf("timed out after %s waiting for container %s to run", timeout, name)
+			}
+			return fmt.Errorf("timed out after %s waiting for container %s to become healthy%s", timeout, name, healthOutput(last))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled waiting for container %s: %w", name, ctx.Err())
+		case <-time.After(containerPollInterval):
+		}
+	}
+}
+
+// healthCheckTimeout is how long a healthcheck can take to report healthy:
+// its start period plus every retry at its interval, each allowed to run
+// for its full timeout.
+func healthCheckTimeout(healthCheck *types.HealthCheckConfig) time.Duration {
+	interval := durationOr(healthCheck.Interval, defaultHealthCheckInterval)
+	timeout := durationOr(healthCheck.Timeout, defaultHealthCheckTimeout)
+	startPeriod := durationOr(healthCheck.StartPeriod, 0)
+	retries := uint64(defaultHealthCheckRetries)
+	if healthCheck.Retries != nil {
+		retries = *healthCheck.Retries
+	}
+	return startPeriod + time.Duration(retries+1)*(interval+timeout)
+}
+
+func durationOr(duration *types.Duration, fallback time.Duration) time.Duration {
+	if duration == nil {
+		return fallback
+	}
+	return time.Duration(*duration)
+}
+
+// healthOutput formats the output of the container's last healthcheck run
+func healthOutput(info containers.ContainerInspectInfo) string {
+	health := info.State.Health
+	if health == nil || len(health.Log) == 0 {
+		return ""
+	}
+	last := health.Log[len(health.Log)-1]
+	return fmt.Sprintf(": healthcheck exited %d: %s", last.ExitCode, strings.TrimSpace(last.Output))
+}
@@ -0,0 +1,164 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	grpcHealthPath = "/grpc.health.v1.Health/Check"
+	// grpcServing is HealthCheckResponse.ServingStatus.SERVING
+	grpcServing = 1
+)
+
+var grpcServingStatus = map[uint64]string{0: "UNKNOWN", 1: "SERVING", 2: "NOT_SERVING", 3: "SERVICE_UNKNOWN"}
+
+// GRPCProbe passes when the gRPC health service at Address reports Service,
+// or the server as a whole when Service is empty, as SERVING. It speaks the
+// grpc.health.v1 protocol over plaintext HTTP/2 directly, as the messages
+// are small enough not to need the gRPC and protobuf runtimes.
+type GRPCProbe struct {
+	Address string
+	Service string
+	Timeout time.Duration
+
+	host *hostRunner
+}
+
+func (p *GRPCProbe) Name() string {
+	if p.Service == "" {
+		return "grpc " + p.Address
+	}
+	return fmt.Sprintf("grpc %s %s", p.Address, p.Service)
+}
+
+func (p *GRPCProbe) Check(ctx context.Context) error {
+	var body []byte
+	var grpcStatus, grpcMessage string
+	var err error
+	if p.host != nil {
+		body, grpcStatus, grpcMessage, err = p.host.checkGRPC(ctx, p)
+	} else {
+		body, grpcStatus, grpcMessage, err = p.call(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if grpcStatus != "" && grpcStatus != "0" {
+		return fmt.Errorf("grpc status %s: %s", grpcStatus, grpcMessage)
+	}
+
+	if len(body) < 5 {
+		return fmt.Errorf("empty grpc response")
+	}
+	status, err := decodeHealthCheckResponse(body[5:])
+	if err != nil {
+		return err
+	}
+	if status != grpcServing {
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = fmt.Sprintf("%d", status)
+		}
+		return fmt.Errorf("service is %s", name)
+	}
+	return nil
+}
+
+// call calls the health service from this machine
+func (p *GRPCProbe) call(ctx context.Context) ([]byte, string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(p.Timeout))
+	defer cancel()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+p.Address+grpcHealthPath, bytes.NewReader(grpcFrame(encodeHealthCheckRequest(p.Service))))
+	if err != nil {
+		return nil, "", "", err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read response: %w", err)
+	}
+	// errors arrive in the trailers, or in the headers of a trailers-only response
+	grpcStatus := resp.Trailer.Get("Grpc-Status")
+	if grpcStatus == "" {
+		grpcStatus = resp.Header.Get("Grpc-Status")
+	}
+	return body, grpcStatus, resp.Trailer.Get("Grpc-Message") + resp.Header.Get("Grpc-Message"), nil
+}
+
+// grpcFrame prefixes an uncompressed message with its gRPC length header
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// encodeHealthCheckRequest encodes HealthCheckRequest{service = 1}
+func encodeHealthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	message := []byte{0x0a}
+	message = binary.AppendUvarint(message, uint64(len(service)))
+	return append(message, service...)
+}
+
+// decodeHealthCheckResponse reads the status of HealthCheckResponse{status = 1},
+// skipping any fields added to the message since
+func decodeHealthCheckResponse(message []byte) (uint64, error) {
+	var status uint64
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, fmt.Errorf("malformed health response")
+		}
+		message = message[n:]
+
+		switch key & 0x7 {
+		case 0:
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return 0, fmt.Errorf("malformed health response")
+			}
+			if key>>3 == 1 {
+				status = value
+			}
+			message = message[n:]
+		case 2:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return 0, fmt.Errorf("malformed health response")
+			}
+			message = message[n+int(length):]
+		default:
+			return 0, fmt.Errorf("unexpected field in health response")
+		}
+	}
+	return status, nil
+}
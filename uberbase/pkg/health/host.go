@@ -0,0 +1,193 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+)
+
+// hostRunner runs network probes with curl on the host the containers run
+// on, where their addresses can be reached
+type hostRunner struct {
+	executor     core.Executor
+	containerMgr *containers.ContainerManager
+	// container serves the probed address, whose host is pointed at the
+	// container's IP; the address's host names the container when empty
+	container string
+}
+
+// curl returns a curl command bounded by timeout and ctx's deadline, with
+// host:port resolving to the container serving it
+func (r *hostRunner) curl(ctx context.Context, timeout time.Duration, host, port string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	command := fmt.Sprintf("curl -s --max-time %.3f", timeout.Seconds())
+	if address := r.resolve(host); address != "" {
+		command += " --resolve " + utils.ShellQuote(host+":"+port+":"+address)
+	}
+	return command, nil
+}
+
+// resolve returns the IP of the container serving host, or nothing when no
+// container serves it, leaving the host to resolve it
+func (r *hostRunner) resolve(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	container := r.container
+	if container == "" {
+		container = host
+	}
+	info, err := r.containerMgr.Inspect(container)
+	if err != nil {
+		return ""
+	}
+	return info.IPAddress()
+}
+
+// dial passes when curl opens a connection to address, whatever is said on it
+func (r *hostRunner) dial(ctx context.Context, address string, timeout time.Duration) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	command, err := r.curl(ctx, timeout, host, port)
+	if err != nil {
+		return err
+	}
+	output, err := r.executor.Exec(command + " -o /dev/null -w '%{time_connect}' " + utils.ShellQuote("http://"+address) + " || true")
+	if err != nil {
+		return err
+	}
+	if connected, err := strconv.ParseFloat(strings.TrimSpace(output), 64); err != nil || connected == 0 {
+		return fmt.Errorf("cannot connect to %s", address)
+	}
+	return nil
+}
+
+// fetch requests the probe's URL, returning the status and body of the response
+func (r *hostRunner) fetch(ctx context.Context, p *HTTPProbe, method string) (int, []byte, error) {
+	target, err := url.Parse(p.URL)
+	if err != nil {
+		return 0, nil, err
+	}
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+	command, err := r.curl(ctx, timeoutOr(p.Timeout), target.Hostname(), port)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	command += " -X " + utils.ShellQuote(method)
+	keys := make([]string, 0, len(p.Headers))
+	for key := range p.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		command += " -H " + utils.ShellQuote(key+": "+p.Headers[key])
+	}
+	output, err := r.executor.Exec(command + ` -w '\n%{http_code}' ` + utils.ShellQuote(p.URL))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	separator := strings.LastIndex(output, "\n")
+	status, err := strconv.Atoi(strings.TrimSpace(output[separator+1:]))
+	if err != nil || status == 0 {
+		return 0, nil, fmt.Errorf("no response from %s", p.URL)
+	}
+	if separator < 0 {
+		return status, nil, nil
+	}
+	return status, []byte(output[:separator]), nil
+}
+
+// checkGRPC calls the probe's health service, returning the response message
+// with the gRPC status and message. curl prints the response as hex so the
+// binary message survives the remote shell.
+func (r *hostRunner) checkGRPC(ctx context.Context, p *GRPCProbe) ([]byte, string, string, error) {
+	host, port, err := net.SplitHostPort(p.Address)
+	if err != nil {
+		return nil, "", "", err
+	}
+	command, err := r.curl(ctx, timeoutOr(p.Timeout), host, port)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var request strings.Builder
+	for _, b := range grpcFrame(encodeHealthCheckRequest(p.Service)) {
+		fmt.Fprintf(&request, `\%03o`, b)
+	}
+	command = "printf " + utils.ShellQuote(request.String()) + " | " + command +
+		" -i --http2-prior-knowledge -X POST -H 'Content-Type: application/grpc' -H 'TE: trailers' --data-binary @- " +
+		utils.ShellQuote("http://"+p.Address+grpcHealthPath) + " | od -An -v -tx1"
+	output, err := r.executor.Exec(command)
+	if err != nil {
+		return nil, "", "", err
+	}
+	response, err := hex.DecodeString(strings.Join(strings.Fields(output), ""))
+	if err != nil || len(response) == 0 {
+		return nil, "", "", fmt.Errorf("no response from %s", p.Address)
+	}
+	body, status, message := parseGRPCResponse(response)
+	return body, status, message, nil
+}
+
+// parseGRPCResponse splits a response printed by curl -i into its message
+// and the gRPC status and message, from the trailers or, for a
+// trailers-only response, the headers
+func parseGRPCResponse(response []byte) ([]byte, string, string) {
+	head, rest, _ := bytes.Cut(response, []byte("\r\n\r\n"))
+	headers := parseHeaderLines(head)
+
+	var body []byte
+	if len(rest) >= 5 {
+		if length := int(binary.BigEndian.Uint32(rest[1:5])); 5+length <= len(rest) {
+			body, rest = rest[:5+length], rest[5+length:]
+		}
+	}
+	trailers := parseHeaderLines(rest)
+
+	for _, fields := range []map[string]string{trailers, headers} {
+		if status, ok := fields["grpc-status"]; ok {
+			return body, status, fields["grpc-message"]
+		}
+	}
+	return body, "", ""
+}
+
+// parseHeaderLines reads the name: value lines of a header block, keyed by
+// lower case name
+func parseHeaderLines(block []byte) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(block), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found {
+			fields[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
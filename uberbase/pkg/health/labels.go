@@ -0,0 +1,179 @@
+package health
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/mattn/go-shellwords"
+)
+
+// HealthLabelPrefix prefixes the compose labels that select a service's
+// probes, for example:
+//
+//	uberbase.health.http.port: 8080
+//	uberbase.health.http.path: /healthz
+//	uberbase.health.http.json: $.status=ok
+//	uberbase.health.grpc.port: 9090
+//	uberbase.health.tcp.port: 5432
+//	uberbase.health.exec.command: pg_isready -U postgres
+//
+// Every probe type also takes a timeout, such as uberbase.health.http.timeout: 2s.
+// The exec command is split into words as a shell would, so it may quote them.
+const HealthLabelPrefix = "uberbase.health."
+
+// Probes builds the probes selected by the labels of the compose service
+// behind the given override, addressed at the tagged container.
+func (h *HealthChecker) Probes(service containers.ComposeServiceOverride, composeOverrideFilePath string) ([]Probe, error) {
+	if h.containerMgr.Compose == nil || h.containerMgr.Compose.Project == nil {
+		return nil, nil
+	}
+	config, ok := h.containerMgr.Compose.Project.Services[service.RefName]
+	if !ok {
+		return nil, nil
+	}
+
+	// the container is reachable by its hostname when the service sets
+	// one, and by its container name otherwise
+	host := service.Name
+	if config.Hostname != "" {
+		host = service.Hostname
+	}
+
+	labels := healthLabels(config.Labels)
+	var probes []Probe
+	for _, kind := range sortedKinds(labels) {
+		probe, err := h.probeFromLabels(kind, labels[kind], host, service.Name, &config, composeOverrideFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s%s labels on %s: %w", HealthLabelPrefix, kind, service.RefName, err)
+		}
+		probes = append(probes, probe)
+	}
+	return probes, nil
+}
+
+func (h *HealthChecker) probeFromLabels(kind string, labels map[string]string, host, container string, service *types.ServiceConfig, composeOverrideFilePath string) (Probe, error) {
+	timeout, err := labelDuration(labels, "timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "tcp":
+		port, err := labelPort(labels)
+		if err != nil {
+			return nil, err
+		}
+		return &TCPProbe{Address: net.JoinHostPort(host, port), Timeout: timeout, host: h.onHost(container)}, nil
+	case "grpc":
+		port, err := labelPort(labels)
+		if err != nil {
+			return nil, err
+		}
+		return &GRPCProbe{Address: net.JoinHostPort(host, port), Service: labels["service"], Timeout: timeout, host: h.onHost(container)}, nil
+	case "http":
+		port, err := labelPort(labels)
+		if err != nil {
+			return nil, err
+		}
+		path := labels["path"]
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		probe := &HTTPProbe{
+			URL:     "http://" + net.JoinHostPort(host, port) + path,
+			Method:  strings.ToUpper(labels["method"]),
+			Timeout: timeout,
+			host:    h.onHost(container),
+		}
+		if status, ok := labels["status"]; ok {
+			if probe.Status, err = strconv.Atoi(status); err != nil {
+				return nil, fmt.Errorf("invalid status %q", status)
+			}
+		}
+		if body, ok := labels["body"]; ok {
+			if probe.BodyRegex, err = regexp.Compile(body); err != nil {
+				return nil, fmt.Errorf("invalid body regex: %w", err)
+			}
+		}
+		if assertion, ok := labels["json"]; ok {
+			path, value, found := strings.Cut(assertion, "=")
+			if !found || path == "" {
+				return nil, fmt.Errorf("json must be path=value, got %q", assertion)
+			}
+			probe.JSONPath, probe.JSONValue = path, value
+		}
+		return probe, nil
+	case "exec":
+		// the command is split as a shell would, without needing one in the container
+		command, err := shellwords.Parse(labels["command"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid command: %w", err)
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("command is required")
+		}
+		return &ExecProbe{
+			ContainerMgr:            h.containerMgr,
+			Service:                 service,
+			Command:                 command,
+			ComposeOverrideFilePath: composeOverrideFilePath,
+			Timeout:                 timeout,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown probe type %q", kind)
+}
+
+// healthLabels groups the health labels by probe type
+func healthLabels(labels types.Labels) map[string]map[string]string {
+	grouped := make(map[string]map[string]string)
+	for key, value := range labels {
+		kind, option, found := strings.Cut(strings.TrimPrefix(key, HealthLabelPrefix), ".")
+		if !strings.HasPrefix(key, HealthLabelPrefix) || !found {
+			continue
+		}
+		if grouped[kind] == nil {
+			grouped[kind] = make(map[string]string)
+		}
+		grouped[kind][option] = value
+	}
+	return grouped
+}
+
+func sortedKinds(labels map[string]map[string]string) []string {
+	kinds := make([]string, 0, len(labels))
+	for kind := range labels {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func labelPort(labels map[string]string) (string, error) {
+	port, ok := labels["port"]
+	if !ok {
+		return "", fmt.Errorf("port is required")
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return port, nil
+}
+
+func labelDuration(labels map[string]string, name string) (time.Duration, error) {
+	value, ok := labels[name]
+	if !ok {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return duration, nil
+}
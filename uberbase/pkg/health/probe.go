@@ -0,0 +1,235 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+// Probe checks one aspect of a service's health.
+type Probe interface {
+	// Name describes the probe in errors and reports
+	Name() string
+	// Check returns nil when the probe passes
+	Check(ctx context.Context) error
+}
+
+// TCPProbe passes when a TCP connection can be opened to Address.
+type TCPProbe struct {
+	Address string
+	Timeout time.Duration
+
+	host *hostRunner
+}
+
+func (p *TCPProbe) Name() string {
+	return "tcp " + p.Address
+}
+
+func (p *TCPProbe) Check(ctx context.Context) error {
+	if p.host != nil {
+		return p.host.dial(ctx, p.Address, timeoutOr(p.Timeout))
+	}
+	dialer := net.Dialer{Timeout: timeoutOr(p.Timeout)}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ExecProbe passes when Command exits zero inside the service's container.
+type ExecProbe struct {
+	ContainerMgr *containers.ContainerManager
+	Service      *types.ServiceConfig
+	Command      []string
+	// ComposeOverrideFilePath selects the containers of the deploy being checked
+	ComposeOverrideFilePath string
+	// Timeout bounds the command, which is killed once it passes
+	Timeout time.Duration
+}
+
+func (p *ExecProbe) Name() string {
+	return fmt.Sprintf("exec %s in %s", strings.Join(p.Command, " "), p.Service.Name)
+}
+
+// Check runs the command until it exits, its timeout or ctx's deadline
+// passes, or ctx is cancelled; a cancelled command is left to its timeout
+func (p *ExecProbe) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timeout := timeoutOr(p.Timeout)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.ContainerMgr.Exec(p.Service, p.Command, p.ComposeOverrideFilePath, timeout)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTPProbe passes when a request to URL answers with Status, or any 2xx
+// status when Status is zero, and its body satisfies the assertions.
+type HTTPProbe struct {
+	URL     string
+	Method  string
+	Status  int
+	Headers map[string]string
+	Timeout time.Duration
+	// BodyRegex must match the response body when set
+	BodyRegex *regexp.Regexp
+	// JSONPath selects a value of a JSON response body, such as
+	// $.checks[0].status, which must equal JSONValue when set
+	JSONPath  string
+	JSONValue string
+
+	host *hostRunner
+}
+
+func (p *HTTPProbe) Name() string {
+	return "http " + p.URL
+}
+
+func (p *HTTPProbe) Check(ctx context.Context) error {
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	fetch := p.fetch
+	if p.host != nil {
+		fetch = func(ctx context.Context, method string) (int, []byte, error) {
+			return p.host.fetch(ctx, p, method)
+		}
+	}
+	status, body, err := fetch(ctx, method)
+	if err != nil {
+		return err
+	}
+
+	if p.Status == 0 && (status < 200 || status >= 300) {
+		return fmt.Errorf("unexpected status %d", status)
+	}
+	if p.Status != 0 && status != p.Status {
+		return fmt.Errorf("unexpected status %d, expected %d", status, p.Status)
+	}
+	if p.BodyRegex != nil && !p.BodyRegex.Match(body) {
+		return fmt.Errorf("body does not match %s", p.BodyRegex)
+	}
+	if p.JSONPath != "" {
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return fmt.Errorf("body is not JSON: %w", err)
+		}
+		value, err := lookupJSONPath(document, p.JSONPath)
+		if err != nil {
+			return err
+		}
+		if actual := fmt.Sprint(value); actual != p.JSONValue {
+			return fmt.Errorf("%s is %q, expected %q", p.JSONPath, actual, p.JSONValue)
+		}
+	}
+	return nil
+}
+
+// fetch requests URL from this machine, returning the status of the
+// response and its body when the probe asserts on it
+func (p *HTTPProbe) fetch(ctx context.Context, method string) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(p.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, p.URL, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, value := range p.Headers {
+		req.Header.Add(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	if p.BodyRegex == nil && p.JSONPath == "" {
+		return resp.StatusCode, nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+var (
+	jsonPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+	jsonPathIndex   = regexp.MustCompile(`\d+`)
+)
+
+// lookupJSONPath resolves a dotted path with optional array indexes, such as
+// $.checks[0].status, in a decoded JSON document.
+func lookupJSONPath(document interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return document, nil
+	}
+
+	current := document
+	for _, segment := range strings.Split(path, ".") {
+		match := jsonPathSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return nil, fmt.Errorf("invalid json path segment %q", segment)
+		}
+		if match[1] != "" {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an object", match[1])
+			}
+			if current, ok = object[match[1]]; !ok {
+				return nil, fmt.Errorf("%s not found", match[1])
+			}
+		}
+		for _, index := range jsonPathIndex.FindAllString(match[2], -1) {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", segment)
+			}
+			i, _ := strconv.Atoi(index)
+			if i >= len(array) {
+				return nil, fmt.Errorf("%s is out of range", segment)
+			}
+			current = array[i]
+		}
+	}
+	return current, nil
+}
+
+func timeoutOr(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultProbeTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,182 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeInterval is how long to wait between attempts of a failing probe
+const probeInterval = 1 * time.Second
+
+// HealthThresholds decides when a probed server's health is settled.
+type HealthThresholds struct {
+	// Success is how many consecutive passes make a server healthy,
+	// one when unset
+	Success int
+	// Failure is how many consecutive failures make a server unhealthy;
+	// when unset the server is retried until the context is done
+	Failure int
+}
+
+// HealthReport records the outcome of a health check for every server of
+// every service checked.
+type HealthReport struct {
+	Healthy   bool                      `yaml:"healthy"`
+	CheckedAt string                    `yaml:"checked_at"`
+	Services  map[string]*ServiceHealth `yaml:"services"`
+}
+
+type ServiceHealth struct {
+	Healthy bool `yaml:"healthy"`
+	// Servers is keyed by the name of the probe that checked them
+	Servers map[string]*ServerHealth `yaml:"servers"`
+}
+
+type ServerHealth struct {
+	Healthy  bool `yaml:"healthy"`
+	Attempts int  `yaml:"attempts"`
+	// LastError is the most recent failure, which a server may have since
+	// recovered from
+	LastError string `yaml:"last_error,omitempty"`
+}
+
+func NewHealthReport() *HealthReport {
+	return &HealthReport{
+		Healthy:   true,
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+		Services:  make(map[string]*ServiceHealth),
+	}
+}
+
+// server returns the result for a server of a service, adding it when new
+func (r *HealthReport) server(service, server string) *ServerHealth {
+	if r.Services[service] == nil {
+		r.Services[service] = &ServiceHealth{Servers: make(map[string]*ServerHealth)}
+	}
+	if r.Services[service].Servers[server] == nil {
+		r.Services[service].Servers[server] = &ServerHealth{}
+	}
+	return r.Services[service].Servers[server]
+}
+
+// summarize derives service and overall health from the server results
+func (r *HealthReport) summarize() {
+	r.Healthy = true
+	for _, service := range r.Services {
+		service.Healthy = true
+		for _, server := range service.Servers {
+			service.Healthy = service.Healthy && server.Healthy
+		}
+		r.Healthy = r.Healthy && service.Healthy
+	}
+}
+
+// Merge adds the results of other to the report
+func (r *HealthReport) Merge(other *HealthReport) {
+	if other == nil {
+		return
+	}
+	for name, service := range other.Services {
+		for server, result := range service.Servers {
+			*r.server(name, server) = *result
+		}
+	}
+	r.summarize()
+}
+
+// Err describes every unhealthy server, or returns nil when all are healthy
+func (r *HealthReport) Err() error {
+	var errs []error
+	for _, name := range sortedKeys(r.Services) {
+		service := r.Services[name]
+		for _, server := range sortedKeys(service.Servers) {
+			result := service.Servers[server]
+			if !result.Healthy {
+				errs = append(errs, fmt.Errorf("%s: %s failed after %d attempts: %s", name, server, result.Attempts, result.LastError))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Servers calls f with each server result in name order
+func (r *HealthReport) Servers(f func(service, server string, result *ServerHealth)) {
+	for _, name := range sortedKeys(r.Services) {
+		service := r.Services[name]
+		for _, server := range sortedKeys(service.Servers) {
+			f(name, server, service.Servers[server])
+		}
+	}
+}
+
+// RunProbes probes every server of every service in parallel, each until
+// it meets a threshold or ctx is done.
+func (h *HealthChecker) RunProbes(ctx context.Context, probes map[string][]Probe) *HealthReport {
+	report := NewHealthReport()
+	var wg sync.WaitGroup
+
+	for service, serviceProbes := range probes {
+		for _, probe := range serviceProbes {
+			result := report.server(service, probe.Name())
+			wg.Add(1)
+			go func(probe Probe) {
+				defer wg.Done()
+				h.runProbe(ctx, probe, result)
+			}(probe)
+		}
+	}
+
+	wg.Wait()
+	report.summarize()
+	return report
+}
+
+func (h *HealthChecker) runProbe(ctx context.Context, probe Probe, result *ServerHealth) {
+	success := h.thresholds.Success
+	if success < 1 {
+		success = 1
+	}
+
+	successes, failures := 0, 0
+	for {
+		result.Attempts++
+		if err := probe.Check(ctx); err != nil {
+			result.LastError = err.Error()
+			successes = 0
+			failures++
+		} else {
+			successes++
+			failures = 0
+		}
+
+		if successes >= success {
+			result.Healthy = true
+			return
+		}
+		if h.thresholds.Failure > 0 && failures >= h.thresholds.Failure {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			if failures == 0 || result.LastError == "" {
+				result.LastError = ctx.Err().Error()
+			}
+			return
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
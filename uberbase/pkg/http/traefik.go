@@ -0,0 +1,26 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+	"github.com/gin-gonic/gin"
+)
+
+// TraefikConfigHandler serves the configuration returned by load to Traefik's
+// HTTP provider. An empty configuration is served until one is available.
+func TraefikConfigHandler(load func() (*traefik.TraefikDynamicConfiguration, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config, err := load()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if config == nil {
+			config = &traefik.TraefikDynamicConfiguration{}
+		}
+		c.JSON(http.StatusOK, config)
+	}
+}
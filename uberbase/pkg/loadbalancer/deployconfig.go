@@ -0,0 +1,276 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// createDeployConfigs renders a copy of each loaded configuration with its
+// HTTP, TCP and UDP services renamed and pointed at the containers for tag.
+func (t *TrafficManager) createDeployConfigs(tag containers.ContainerTag) (map[string]*traefik.TraefikDynamicConfiguration, error) {
+	deployConfigs := make(map[string]*traefik.TraefikDynamicConfiguration)
+	for configFile, config := range t.GetDynamicConfigs() {
+		tagConfig, err := config.Copy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy config %s: %w", configFile, err)
+		}
+		if tagConfig.HTTP != nil {
+			if err := tagHTTPConfig(tagConfig.HTTP, tag, t.hostTags); err != nil {
+				return nil, fmt.Errorf("failed to tag http config %s: %w", configFile, err)
+			}
+		}
+		if tagConfig.TCP != nil {
+			if err := tagTCPConfig(tagConfig.TCP, tag, t.hostTags); err != nil {
+				return nil, fmt.Errorf("failed to tag tcp config %s: %w", configFile, err)
+			}
+		}
+		if tagConfig.UDP != nil {
+			if err := tagUDPConfig(tagConfig.UDP, tag, t.hostTags); err != nil {
+				return nil, fmt.Errorf("failed to tag udp config %s: %w", configFile, err)
+			}
+		}
+		deployConfigs[configFile] = tagConfig
+	}
+	return deployConfigs, nil
+}
+
+// deployedConfigs returns the configs that route to a host of the compose
+// project, which deploys tag and record. The others, such as the platform
+// routes and issued certificates, are served as they are by ProviderConfig.
+// Every config is deployed when the project's hosts are unknown.
+func deployedConfigs(configs map[string]*traefik.TraefikDynamicConfiguration, hosts map[string]bool) map[string]*traefik.TraefikDynamicConfiguration {
+	if len(hosts) == 0 {
+		return configs
+	}
+	deployed := make(map[string]*traefik.TraefikDynamicConfiguration)
+	for name, config := range configs {
+		if routesToHosts(config, hosts) {
+			deployed[name] = config
+		}
+	}
+	return deployed
+}
+
+// projectHosts returns the hosts the services of compose are addressed by
+func projectHosts(compose *containers.ComposeProject, hostTags map[string]containers.ContainerTag) map[string]bool {
+	hosts := make(map[string]bool)
+	if compose != nil && compose.Project != nil {
+		for name, service := range compose.Project.Services {
+			hosts[name] = true
+			if service.Hostname != "" {
+				hosts[service.Hostname] = true
+			}
+		}
+	}
+	for host := range hostTags {
+		hosts[host] = true
+	}
+	return hosts
+}
+
+// routesToHosts reports whether a server of config is addressed by one of hosts
+func routesToHosts(config *traefik.TraefikDynamicConfiguration, hosts map[string]bool) bool {
+	addressed := func(address string) bool {
+		host, _, err := net.SplitHostPort(address)
+		return err == nil && hosts[host]
+	}
+	if config.HTTP != nil {
+		for _, service := range config.HTTP.Services {
+			if service.LoadBalancer == nil {
+				continue
+			}
+			for _, server := range service.LoadBalancer.Servers {
+				if serverURL, err := url.Parse(server.URL); err == nil && hosts[serverURL.Hostname()] {
+					return true
+				}
+			}
+		}
+	}
+	if config.TCP != nil {
+		for _, service := range config.TCP.Services {
+			if service.LoadBalancer == nil {
+				continue
+			}
+			for _, server := range service.LoadBalancer.Servers {
+				if addressed(server.Address) {
+					return true
+				}
+			}
+		}
+	}
+	if config.UDP != nil {
+		for _, service := range config.UDP.Services {
+			if service.LoadBalancer == nil {
+				continue
+			}
+			for _, server := range service.LoadBalancer.Servers {
+				if addressed(server.Address) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func tagHTTPConfig(config *traefik.TraefikHTTPConfiguration, tag containers.ContainerTag, hostTags map[string]containers.ContainerTag) error {
+	services, err := tagServices(config.Services, tag, func(service *traefik.TraefikService) error {
+		if service.LoadBalancer != nil {
+			for i, server := range service.LoadBalancer.Servers {
+				serverURL, err := tagURL(server.URL, tag, hostTags)
+				if err != nil {
+					return err
+				}
+				service.LoadBalancer.Servers[i].URL = serverURL
+			}
+		}
+		if service.Weighted != nil {
+			for i, weighted := range service.Weighted.Services {
+				service.Weighted.Services[i].Name = tagName(weighted.Name, tag)
+			}
+		}
+		if service.Mirroring != nil {
+			service.Mirroring.Service = tagName(service.Mirroring.Service, tag)
+			for i, mirror := range service.Mirroring.Mirrors {
+				service.Mirroring.Mirrors[i].Name = tagName(mirror.Name, tag)
+			}
+		}
+		if service.Failover != nil {
+			service.Failover.Service = tagName(service.Failover.Service, tag)
+			service.Failover.Fallback = tagName(service.Failover.Fallback, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	config.Services = services
+	tagRouters(config.Routers, tag, func(router *traefik.TraefikRouter) *string { return &router.Service })
+
+	for name, middleware := range config.Middlewares {
+		if middleware.Errors != nil {
+			middleware.Errors.Service = tagName(middleware.Errors.Service, tag)
+			config.Middlewares[name] = middleware
+		}
+	}
+	return nil
+}
+
+func tagTCPConfig(config *traefik.TraefikTCPConfiguration, tag containers.ContainerTag, hostTags map[string]containers.ContainerTag) error {
+	services, err := tagServices(config.Services, tag, func(service *traefik.TraefikTCPService) error {
+		if service.LoadBalancer != nil {
+			for i, server := range service.LoadBalancer.Servers {
+				address, err := tagAddress(server.Address, tag, hostTags)
+				if err != nil {
+					return err
+				}
+				service.LoadBalancer.Servers[i].Address = address
+			}
+		}
+		if service.Weighted != nil {
+			for i, weighted := range service.Weighted.Services {
+				service.Weighted.Services[i].Name = tagName(weighted.Name, tag)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	config.Services = services
+	tagRouters(config.Routers, tag, func(router *traefik.TraefikTCPRouter) *string { return &router.Service })
+	return nil
+}
+
+func tagUDPConfig(config *traefik.TraefikUDPConfiguration, tag containers.ContainerTag, hostTags map[string]containers.ContainerTag) error {
+	services, err := tagServices(config.Services, tag, func(service *traefik.TraefikUDPService) error {
+		if service.LoadBalancer != nil {
+			for i, server := range service.LoadBalancer.Servers {
+				address, err := tagAddress(server.Address, tag, hostTags)
+				if err != nil {
+					return err
+				}
+				service.LoadBalancer.Servers[i].Address = address
+			}
+		}
+		if service.Weighted != nil {
+			for i, weighted := range service.Weighted.Services {
+				service.Weighted.Services[i].Name = tagName(weighted.Name, tag)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	config.Services = services
+	tagRouters(config.Routers, tag, func(router *traefik.TraefikUDPRouter) *string { return &router.Service })
+	return nil
+}
+
+// tagServices returns services renamed for tag, each pointed at the
+// containers for tag by tagService
+func tagServices[S any](services map[string]S, tag containers.ContainerTag, tagService func(*S) error) (map[string]S, error) {
+	tagged := make(map[string]S, len(services))
+	for name, service := range services {
+		if err := tagService(&service); err != nil {
+			return nil, err
+		}
+		tagged[tagName(name, tag)] = service
+	}
+	return tagged, nil
+}
+
+// tagRouters points each of routers at its service renamed for tag
+func tagRouters[R any](routers map[string]R, tag containers.ContainerTag, service func(*R) *string) {
+	for name, router := range routers {
+		routed := service(&router)
+		*routed = tagName(*routed, tag)
+		routers[name] = router
+	}
+}
+
+// tagName suffixes a service name with tag. Services of other providers,
+// such as api@internal, are not deployed by uberbase and are left untouched.
+func tagName(name string, tag containers.ContainerTag) string {
+	if name == "" || strings.Contains(name, "@") {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, string(tag))
+}
+
+// tagURL points a server URL at the container for tag, keeping its scheme,
+// port and path.
+func tagURL(rawURL string, tag containers.ContainerTag, hostTags map[string]containers.ContainerTag) (string, error) {
+	serverURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server url %q: %w", rawURL, err)
+	}
+	host, err := tagAddress(serverURL.Host, tag, hostTags)
+	if err != nil {
+		return "", err
+	}
+	serverURL.Host = host
+	return serverURL.String(), nil
+}
+
+// tagAddress points a host:port address at the container for tag, or for
+// the host's own tag in hostTags.
+func tagAddress(address string, tag containers.ContainerTag, hostTags map[string]containers.ContainerTag) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address (expected host:port) %q: %w", address, err)
+	}
+	if host == "" || port == "" {
+		return "", fmt.Errorf("invalid address (expected host:port): %s", address)
+	}
+	if hostTag, ok := hostTags[host]; ok {
+		tag = hostTag
+	}
+	return net.JoinHostPort(fmt.Sprintf("%s-%s", host, string(tag)), port), nil
+}
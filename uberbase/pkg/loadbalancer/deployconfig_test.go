@@ -0,0 +1,142 @@
+package loadbalancer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// testHostTags leaves cache on the tag it was last deployed with
+var testHostTags = map[string]containers.ContainerTag{"cache": "old999"}
+
+func TestTagHTTPConfig(t *testing.T) {
+	config := &traefik.TraefikHTTPConfiguration{
+		Routers: map[string]traefik.TraefikRouter{
+			"web":       {Rule: "Host(`example.com`)", Service: "web"},
+			"dashboard": {Rule: "PathPrefix(`/dashboard`)", Service: "api@internal"},
+		},
+		Services: map[string]traefik.TraefikService{
+			"web":   traefik.NewLoadBalancerService("https://web:8443/app"),
+			"cache": traefik.NewLoadBalancerService("http://cache:6379"),
+			"canary": {Weighted: &traefik.TraefikServiceWeighted{
+				Services: []traefik.TraefikServiceWeightedService{{Name: "web"}, {Name: "legacy@file"}},
+			}},
+			"failover": {Failover: &traefik.TraefikServiceFailover{Service: "web", Fallback: "cache"}},
+		},
+		Middlewares: map[string]traefik.TraefikMiddleware{
+			"errors": {Errors: &traefik.TraefikMiddlewareErrors{Service: "web"}},
+		},
+	}
+	if err := tagHTTPConfig(config, "abc123", testHostTags); err != nil {
+		t.Fatal(err)
+	}
+
+	wantRouters := map[string]string{"web": "web-abc123", "dashboard": "api@internal"}
+	for name, service := range wantRouters {
+		if got := config.Routers[name].Service; got != service {
+			t.Errorf("router %s service = %q, want %q", name, got, service)
+		}
+	}
+	wantServices := []string{"cache-abc123", "canary-abc123", "failover-abc123", "web-abc123"}
+	if got := sortedServiceNames(config.Services); !reflect.DeepEqual(got, wantServices) {
+		t.Errorf("services = %v, want %v", got, wantServices)
+	}
+	if url := config.Services["web-abc123"].LoadBalancer.Servers[0].URL; url != "https://web-abc123:8443/app" {
+		t.Errorf("web server = %q, want its scheme, port and path kept", url)
+	}
+	if url := config.Services["cache-abc123"].LoadBalancer.Servers[0].URL; url != "http://cache-old999:6379" {
+		t.Errorf("cache server = %q, want the host's own tag", url)
+	}
+	weighted := config.Services["canary-abc123"].Weighted.Services
+	if weighted[0].Name != "web-abc123" || weighted[1].Name != "legacy@file" {
+		t.Errorf("weighted services = %+v, want web tagged and legacy@file untouched", weighted)
+	}
+	failover := config.Services["failover-abc123"].Failover
+	if failover.Service != "web-abc123" || failover.Fallback != "cache-abc123" {
+		t.Errorf("failover = %+v, want both tagged", failover)
+	}
+	if service := config.Middlewares["errors"].Errors.Service; service != "web-abc123" {
+		t.Errorf("errors middleware service = %q, want web-abc123", service)
+	}
+}
+
+func TestTagTCPConfig(t *testing.T) {
+	config := &traefik.TraefikTCPConfiguration{
+		Routers: map[string]traefik.TraefikTCPRouter{
+			"db":     {Rule: "HostSNI(`*`)", Service: "db"},
+			"legacy": {Rule: "HostSNI(`legacy.example.com`)", Service: "legacy@file"},
+		},
+		Services: map[string]traefik.TraefikTCPService{
+			"db":    {LoadBalancer: &traefik.TraefikTCPServiceLoadBalancer{Servers: []traefik.TraefikTCPServiceLoadBalancerServer{{Address: "db:5432"}}}},
+			"cache": {LoadBalancer: &traefik.TraefikTCPServiceLoadBalancer{Servers: []traefik.TraefikTCPServiceLoadBalancerServer{{Address: "cache:6379"}}}},
+			"split": {Weighted: &traefik.TraefikTCPServiceWeighted{Services: []traefik.TraefikServiceWeightedService{{Name: "db"}, {Name: "legacy@file"}}}},
+		},
+	}
+	if err := tagTCPConfig(config, "abc123", testHostTags); err != nil {
+		t.Fatal(err)
+	}
+
+	if service := config.Routers["db"].Service; service != "db-abc123" {
+		t.Errorf("db router service = %q, want db-abc123", service)
+	}
+	if service := config.Routers["legacy"].Service; service != "legacy@file" {
+		t.Errorf("legacy router service = %q, want it untouched", service)
+	}
+	if address := config.Services["db-abc123"].LoadBalancer.Servers[0].Address; address != "db-abc123:5432" {
+		t.Errorf("db server = %q, want db-abc123:5432", address)
+	}
+	if address := config.Services["cache-abc123"].LoadBalancer.Servers[0].Address; address != "cache-old999:6379" {
+		t.Errorf("cache server = %q, want the host's own tag", address)
+	}
+	weighted := config.Services["split-abc123"].Weighted.Services
+	if weighted[0].Name != "db-abc123" || weighted[1].Name != "legacy@file" {
+		t.Errorf("weighted services = %+v, want db tagged and legacy@file untouched", weighted)
+	}
+}
+
+func TestTagUDPConfig(t *testing.T) {
+	config := &traefik.TraefikUDPConfiguration{
+		Routers: map[string]traefik.TraefikUDPRouter{
+			"dns": {Service: "dns"},
+		},
+		Services: map[string]traefik.TraefikUDPService{
+			"dns": {LoadBalancer: &traefik.TraefikUDPServiceLoadBalancer{Servers: []traefik.TraefikUDPServiceLoadBalancerServer{{Address: "dns:53"}, {Address: "cache:53"}}}},
+		},
+	}
+	if err := tagUDPConfig(config, "abc123", testHostTags); err != nil {
+		t.Fatal(err)
+	}
+
+	if service := config.Routers["dns"].Service; service != "dns-abc123" {
+		t.Errorf("dns router service = %q, want dns-abc123", service)
+	}
+	servers := config.Services["dns-abc123"].LoadBalancer.Servers
+	if servers[0].Address != "dns-abc123:53" || servers[1].Address != "cache-old999:53" {
+		t.Errorf("dns servers = %+v, want dns-abc123:53 and cache-old999:53", servers)
+	}
+}
+
+func TestTagAddressInvalid(t *testing.T) {
+	for _, address := range []string{"db", "db:", ":5432"} {
+		config := &traefik.TraefikTCPConfiguration{
+			Services: map[string]traefik.TraefikTCPService{
+				"db": {LoadBalancer: &traefik.TraefikTCPServiceLoadBalancer{Servers: []traefik.TraefikTCPServiceLoadBalancerServer{{Address: address}}}},
+			},
+		}
+		if err := tagTCPConfig(config, "abc123", nil); err == nil {
+			t.Errorf("address %q tagged, want an error", address)
+		}
+	}
+}
+
+func sortedServiceNames[S any](services map[string]S) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
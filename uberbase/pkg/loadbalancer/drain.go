@@ -0,0 +1,125 @@
+package loadbalancer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+)
+
+const (
+	// ProviderPollInterval matches the pollInterval of the HTTP provider in
+	// the Traefik static config, the longest Traefik takes to see a cut-over
+	ProviderPollInterval = 5 * time.Second
+	drainPollInterval    = 2 * time.Second
+)
+
+// TCP states in /proc/net/tcp
+const (
+	tcpEstablished = "01"
+	tcpListen      = "0A"
+)
+
+// ConnectionDrainOptions configures waiting for in-flight requests to the
+// previous tag to finish before its containers are stopped.
+type ConnectionDrainOptions struct {
+	// Timeout bounds the wait. Zero skips waiting for connections to drain.
+	Timeout time.Duration
+}
+
+// WaitForConnectionDrain waits until no connections are open to the ports
+// containers listen on, or until the timeout expires. Connections are read
+// from each container's network namespace on the host through executor, so
+// long uploads and websockets keep the wait going, as do keep-alive
+// connections Traefik has not closed yet. It reports whether the connections
+// drained, and fails when they cannot be read.
+func WaitForConnectionDrain(ctx context.Context, executor core.Executor, containerMgr *containers.ContainerManager, options ConnectionDrainOptions, containerNames []string) (bool, error) {
+	if options.Timeout <= 0 || len(containerNames) == 0 {
+		return true, nil
+	}
+
+	timeout := time.After(options.Timeout)
+	wait := ProviderPollInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("cancelled while draining connections: %w", ctx.Err())
+		case <-timeout:
+			logging.Logger.Warn("Timed out draining connections", "timeout", options.Timeout.String())
+			return false, nil
+		case <-time.After(wait):
+		}
+		wait = drainPollInterval
+
+		open := 0
+		for _, name := range containerNames {
+			count, err := openConnections(executor, containerMgr, name)
+			if err != nil {
+				return false, err
+			}
+			open += count
+		}
+		if open == 0 {
+			return true, nil
+		}
+		logging.Logger.Debug("Waiting for connections to drain", "open", fmt.Sprintf("%d", open))
+	}
+}
+
+// openConnections counts the connections open to the ports container
+// listens on. A container that is not running has none.
+func openConnections(executor core.Executor, containerMgr *containers.ContainerManager, container string) (int, error) {
+	info, err := containerMgr.Inspect(container)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read connections of %s: %w", container, err)
+	}
+	if !info.State.Running || info.State.Pid == 0 {
+		return 0, nil
+	}
+
+	// tcp6 is missing when IPv6 is disabled
+	pid := info.State.Pid
+	output, err := executor.Exec(fmt.Sprintf("cat /proc/%d/net/tcp; cat /proc/%d/net/tcp6 2>/dev/null || true", pid, pid))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read connections of %s: %w", container, err)
+	}
+	return inboundConnections(output), nil
+}
+
+// inboundConnections counts the established connections of a /proc/net/tcp
+// table whose local port is one that is listened on
+func inboundConnections(table string) int {
+	type socket struct{ port, state string }
+	sockets := []socket{}
+	listening := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "sl" {
+			continue
+		}
+		_, port, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		sockets = append(sockets, socket{port, fields[3]})
+		if fields[3] == tcpListen {
+			listening[port] = true
+		}
+	}
+
+	open := 0
+	for _, s := range sockets {
+		if s.state == tcpEstablished && listening[s.port] {
+			open++
+		}
+	}
+	return open
+}
@@ -0,0 +1,22 @@
+package loadbalancer
+
+import "testing"
+
+func TestInboundConnections(t *testing.T) {
+	// listening on 8080 (1F90), with two requests in flight, one idle
+	// keep-alive closing, and an outbound connection to a database on 5432
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0A590002:1F90 0A590001:C350 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 0A590002:1F90 0A590001:C351 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0A590002:1F90 0A590001:C352 06 00000000:00000000 00:00000000 00000000     0        0 0 3 0000000000000000
+   4: 0A590002:D431 0A590003:1538 01 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 20 4 30 10 -1
+  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+`
+	if open := inboundConnections(table); open != 2 {
+		t.Errorf("inboundConnections = %d, want 2", open)
+	}
+	if open := inboundConnections(""); open != 0 {
+		t.Errorf("inboundConnections of an empty table = %d, want 0", open)
+	}
+}
@@ -0,0 +1,129 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+const (
+	// MaintenanceName names the service and middleware injected for maintenance
+	MaintenanceName = "uberbase-maintenance"
+)
+
+// EnableMaintenance returns a copy of config with the routers selected by
+// maintenance put into maintenance. The routers it changes are recorded in
+// maintenance so that DisableMaintenance can restore them.
+func EnableMaintenance(config *traefik.TraefikDynamicConfiguration, tag containers.ContainerTag, maintenance *state.MaintenanceState) (*traefik.TraefikDynamicConfiguration, error) {
+	if config == nil || config.HTTP == nil || len(config.HTTP.Routers) == 0 {
+		return nil, fmt.Errorf("no http routers to put into maintenance")
+	}
+	if maintenance.URL == "" {
+		return nil, fmt.Errorf("maintenance url cannot be empty")
+	}
+
+	maintained, err := config.Copy()
+	if err != nil {
+		return nil, err
+	}
+	switch maintenance.Mode {
+	case state.MaintenanceModeService, state.MaintenanceModeErrors:
+		maintained.HTTP.SetService(MaintenanceName, traefik.NewLoadBalancerService(maintenance.URL))
+		if maintenance.Mode == state.MaintenanceModeErrors {
+			maintained.HTTP.SetMiddleware(MaintenanceName, traefik.NewErrorsMiddleware(MaintenanceName, "/", "500-599"))
+		}
+	case state.MaintenanceModeRedirect:
+		maintained.HTTP.SetMiddleware(MaintenanceName, traefik.NewRedirectRegexMiddleware("^.*$", maintenance.URL, false))
+	default:
+		return nil, fmt.Errorf("unknown maintenance mode %q", maintenance.Mode)
+	}
+
+	maintenance.OriginalRouters = make(map[string]traefik.TraefikRouter)
+	for name, router := range maintained.HTTP.Routers {
+		if !routesToServices(router, tag, maintenance.Services) {
+			continue
+		}
+		maintenance.OriginalRouters[name] = router
+		if maintenance.Mode == state.MaintenanceModeService {
+			router.Service = MaintenanceName
+		} else {
+			router.UseMiddleware(MaintenanceName)
+		}
+		maintained.HTTP.Routers[name] = router
+	}
+
+	if len(maintenance.OriginalRouters) == 0 {
+		return nil, fmt.Errorf("no routers found for services %v", maintenance.Services)
+	}
+	if maintenance.Mode == state.MaintenanceModeRedirect {
+		if err := checkRedirectTarget(maintenance); err != nil {
+			return nil, err
+		}
+	}
+	return maintained, nil
+}
+
+// DisableMaintenance returns a copy of config with the routers recorded by
+// EnableMaintenance restored and the maintenance service and middleware removed.
+func DisableMaintenance(config *traefik.TraefikDynamicConfiguration, maintenance *state.MaintenanceState) (*traefik.TraefikDynamicConfiguration, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	restored, err := config.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if restored.HTTP == nil {
+		return restored, nil
+	}
+	for name, router := range maintenance.OriginalRouters {
+		if _, ok := restored.HTTP.Routers[name]; ok {
+			restored.HTTP.Routers[name] = router
+		}
+	}
+	delete(restored.HTTP.Services, MaintenanceName)
+	delete(restored.HTTP.Middlewares, MaintenanceName)
+	return restored, nil
+}
+
+// checkRedirectTarget fails when the maintenance URL is on a host of a router
+// put into maintenance, which would redirect requests for it to itself
+func checkRedirectTarget(maintenance *state.MaintenanceState) error {
+	target, err := url.Parse(maintenance.URL)
+	if err != nil {
+		return fmt.Errorf("invalid maintenance url %q: %w", maintenance.URL, err)
+	}
+	names := make([]string, 0, len(maintenance.OriginalRouters))
+	for name := range maintenance.OriginalRouters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, host := range traefik.RuleHosts(maintenance.OriginalRouters[name].Rule) {
+			if strings.EqualFold(host, target.Hostname()) {
+				return fmt.Errorf("maintenance url %s is served by router %q, which would redirect it to itself; serve it from another host or use the service mode", maintenance.URL, name)
+			}
+		}
+	}
+	return nil
+}
+
+// routesToServices reports whether router sends traffic to one of services,
+// given either with or without the deployed tag. No services selects all routers.
+func routesToServices(router traefik.TraefikRouter, tag containers.ContainerTag, services []string) bool {
+	if len(services) == 0 {
+		return true
+	}
+	for _, service := range services {
+		if router.Service == service || router.Service == tagName(service, tag) || router.Service == service+stickySuffix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package loadbalancer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+func TestEnableMaintenanceRedirect(t *testing.T) {
+	config := &traefik.TraefikDynamicConfiguration{HTTP: &traefik.TraefikHTTPConfiguration{
+		Routers: map[string]traefik.TraefikRouter{
+			"web": {Rule: "Host(`example.com`) && PathPrefix(`/`)", Service: "web-abc123"},
+			"api": {Rule: "Host(`api.example.com`)", Service: "api-abc123"},
+		},
+		Services: map[string]traefik.TraefikService{
+			"web-abc123": traefik.NewLoadBalancerService("http://web-abc123:8080"),
+			"api-abc123": traefik.NewLoadBalancerService("http://api-abc123:8080"),
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		url      string
+		services []string
+		wantErr  string
+	}{
+		{name: "another host", url: "https://status.example.com/"},
+		{name: "host of a router left alone", url: "https://example.com/maintenance", services: []string{"api"}},
+		{name: "host of a maintained router", url: "https://Example.com/maintenance", wantErr: `served by router "web"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			maintenance := &state.MaintenanceState{Mode: state.MaintenanceModeRedirect, URL: test.url, Services: test.services}
+			maintained, err := EnableMaintenance(config, "abc123", maintenance)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("err = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := maintained.HTTP.Middlewares[MaintenanceName]; !ok {
+				t.Errorf("no redirect middleware")
+			}
+		})
+	}
+}
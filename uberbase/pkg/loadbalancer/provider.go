@@ -0,0 +1,31 @@
+package loadbalancer
+
+import (
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// ProviderConfig returns the configuration served to Traefik's HTTP
+// provider: the active deployment's routing, merged with the configs of
+// the dynamic directory that no deploy recorded. Those are served untagged,
+// so the platform routes and issued certificates are routed from the first
+// start, before and between deploys.
+func ProviderConfig(current state.DeploymentState, configs map[string]*traefik.TraefikDynamicConfiguration) (*traefik.TraefikDynamicConfiguration, error) {
+	served := make(map[string]*traefik.TraefikDynamicConfiguration)
+	for name, config := range configs {
+		if current.Traefik != nil {
+			if _, ok := current.Traefik.Configs[name]; ok {
+				continue
+			}
+		}
+		served[name] = config
+	}
+	if current.Traefik != nil && current.Traefik.Active != nil {
+		// file names end in .yml, so the active routing cannot clash with one
+		served["active"] = current.Traefik.Active
+	}
+	if len(served) == 0 {
+		return nil, nil
+	}
+	return traefik.MergeDynamicConfigs(served)
+}
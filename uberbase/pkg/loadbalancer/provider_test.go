@@ -0,0 +1,109 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+func loadTestdataConfigs(t *testing.T) map[string]*traefik.TraefikDynamicConfiguration {
+	t.Helper()
+	configs, err := traefik.LoadTraefikDynamicConfigsFrom("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configs
+}
+
+// TestProviderConfigFreshHost checks that a host configured but never
+// deployed to still routes the platform services
+func TestProviderConfigFreshHost(t *testing.T) {
+	configs := map[string]*traefik.TraefikDynamicConfiguration{"uberbase.yml": loadTestdataConfigs(t)["uberbase.yml"]}
+
+	served, err := ProviderConfig(state.DeploymentState{}, configs)
+	if err != nil {
+		t.Fatalf("ProviderConfig failed: %v", err)
+	}
+	if served == nil || served.HTTP == nil {
+		t.Fatalf("no routing served on a fresh host")
+	}
+	for _, name := range []string{"auth", "api", "storage"} {
+		router, ok := served.HTTP.Routers[name]
+		if !ok {
+			t.Errorf("platform router %s is not served", name)
+			continue
+		}
+		if _, ok := served.HTTP.Services[router.Service]; !ok {
+			t.Errorf("router %s points at undefined service %s", name, router.Service)
+		}
+	}
+	if url := served.HTTP.Services["auth"].LoadBalancer.Servers[0].URL; url != "http://uberbase:9011/" {
+		t.Errorf("auth server = %s, want it untagged", url)
+	}
+}
+
+// TestProviderConfigDeployed checks that the platform routes are served
+// alongside the active routing, and that deployed configs are only served
+// tagged
+func TestProviderConfigDeployed(t *testing.T) {
+	configs := loadTestdataConfigs(t)
+	deployment := state.DeploymentState{
+		Traefik: &state.TraefikState{
+			Tag:     "abc123",
+			Configs: map[string]traefik.TraefikDynamicConfiguration{"app.yml": *configs["app.yml"]},
+			Active: &traefik.TraefikDynamicConfiguration{HTTP: &traefik.TraefikHTTPConfiguration{
+				Routers:  map[string]traefik.TraefikRouter{"web": {Rule: "Host(`www.example.com`)", Service: "web-abc123"}},
+				Services: map[string]traefik.TraefikService{"web-abc123": traefik.NewLoadBalancerService("http://web-abc123:8080/")},
+			}},
+		},
+	}
+
+	served, err := ProviderConfig(deployment, configs)
+	if err != nil {
+		t.Fatalf("ProviderConfig failed: %v", err)
+	}
+	if _, ok := served.HTTP.Routers["auth"]; !ok {
+		t.Errorf("platform routes are not served after a deploy")
+	}
+	if _, ok := served.HTTP.Services["web"]; ok {
+		t.Errorf("deployed config is served untagged")
+	}
+	if served.HTTP.Routers["web"].Service != "web-abc123" {
+		t.Errorf("web router = %+v, want the active routing", served.HTTP.Routers["web"])
+	}
+}
+
+func TestDeployedConfigs(t *testing.T) {
+	configs := loadTestdataConfigs(t)
+
+	deployed := deployedConfigs(configs, map[string]bool{"web": true, "db": true})
+	if _, ok := deployed["app.yml"]; !ok {
+		t.Errorf("config routing to a compose service is not deployed")
+	}
+	if _, ok := deployed["uberbase.yml"]; ok {
+		t.Errorf("platform config is deployed")
+	}
+}
+
+// TestProviderConfigCertificates checks that the certificate store written by
+// uberbase certs issue is served before and after a deploy
+func TestProviderConfigCertificates(t *testing.T) {
+	configs := loadTestdataConfigs(t)
+	configs["certs.yml"] = traefik.NewCertificateStoreConfig(traefik.TraefikCertificate{CertFile: "/certs/vault-cert.pem", KeyFile: "/certs/vault-key.pem"})
+
+	deployed := state.DeploymentState{Traefik: &state.TraefikState{
+		Tag:     "abc123",
+		Configs: map[string]traefik.TraefikDynamicConfiguration{"app.yml": *configs["app.yml"]},
+		Active:  &traefik.TraefikDynamicConfiguration{},
+	}}
+	for name, deployment := range map[string]state.DeploymentState{"fresh host": {}, "deployed": deployed} {
+		served, err := ProviderConfig(deployment, configs)
+		if err != nil {
+			t.Fatalf("%s: ProviderConfig failed: %v", name, err)
+		}
+		if served.TLS == nil || len(served.TLS.Certificates) != 1 || served.TLS.Certificates[0].CertFile != "/certs/vault-cert.pem" {
+			t.Errorf("%s: tls = %+v, want the issued certificate", name, served.TLS)
+		}
+	}
+}
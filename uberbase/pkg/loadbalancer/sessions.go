@@ -0,0 +1,140 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+const (
+	defaultStickyCookie = "uberbase"
+	stickySuffix        = "-sticky"
+	drainSuffix         = "-drain"
+)
+
+// SessionOptions configures session affinity for deployed HTTP services.
+type SessionOptions struct {
+	// Cookie pins each session to the tag that first served it. The cookie
+	// name is suffixed with the service name so each service tracks its own
+	// sessions. Sessions are not sticky when Cookie is nil.
+	Cookie *traefik.TraefikServiceWeightedStickyCookie
+	// DrainTimeout is how long the previous tag keeps serving the sessions
+	// pinned to it after a cut-over. Zero moves every session at once.
+	DrainTimeout time.Duration
+}
+
+// applySticky routes each router serving a service deployed for tag through
+// a weighted service with a sticky cookie, so the cookie records which tag a
+// session belongs to.
+func applySticky(config *traefik.TraefikDynamicConfiguration, tag containers.ContainerTag, cookie *traefik.TraefikServiceWeightedStickyCookie) {
+	if config.HTTP == nil {
+		return
+	}
+
+	for name, router := range config.HTTP.Routers {
+		base, ok := deployedService(config.HTTP, router.Service, tag)
+		if !ok {
+			continue
+		}
+		service := traefik.NewWeightedService(map[string]int{router.Service: 1})
+		service.Weighted.Sticky = &traefik.TraefikServiceWeightedSticky{Cookie: stickyCookie(cookie, base)}
+		config.HTTP.SetService(base+stickySuffix, service)
+
+		router.Service = base + stickySuffix
+		config.HTTP.Routers[name] = router
+	}
+}
+
+// createDrainConfig returns a copy of active in which requests carrying a
+// sticky cookie can still reach the services of previous, while requests
+// without one only reach tag. Returns nil when no service has sessions to
+// drain.
+func createDrainConfig(active *traefik.TraefikDynamicConfiguration, tag containers.ContainerTag, previous *traefik.TraefikDynamicConfiguration, previousTag containers.ContainerTag) (*traefik.TraefikDynamicConfiguration, error) {
+	if active.HTTP == nil || previous == nil || previous.HTTP == nil || previousTag == "" {
+		return nil, nil
+	}
+
+	drain, err := active.Copy()
+	if err != nil {
+		return nil, err
+	}
+	drained := 0
+	for name, router := range active.HTTP.Routers {
+		if !strings.HasSuffix(router.Service, stickySuffix) {
+			continue
+		}
+		sticky, ok := active.HTTP.Services[router.Service]
+		if !ok || sticky.Weighted == nil || sticky.Weighted.Sticky == nil || sticky.Weighted.Sticky.Cookie == nil {
+			continue
+		}
+		base := strings.TrimSuffix(router.Service, stickySuffix)
+		oldName := tagName(base, previousTag)
+		oldService, ok := previous.HTTP.Services[oldName]
+		if !ok || oldService.LoadBalancer == nil {
+			// a service new in this deploy has no sessions to drain
+			continue
+		}
+		cookie := *sticky.Weighted.Sticky.Cookie
+
+		drain.HTTP.SetService(oldName, oldService)
+		service := traefik.NewWeightedService(map[string]int{
+			oldName:            1,
+			tagName(base, tag): 1,
+		})
+		service.Weighted.Sticky = &traefik.TraefikServiceWeightedSticky{Cookie: &cookie}
+		drain.HTTP.SetService(base+drainSuffix, service)
+
+		// only sessions that already hold a cookie are balanced across both
+		// tags, new sessions fall through to the original router
+		drainRouter := router
+		drainRouter.Service = base + drainSuffix
+		drainRouter.Rule = fmt.Sprintf("(%s) && %s", router.Rule, cookieMatcher(router.RuleSyntax, cookie.Name))
+		if router.Priority > 0 {
+			drainRouter.Priority = router.Priority + 1
+		}
+		drain.HTTP.SetRouter(name+drainSuffix, drainRouter)
+		drained++
+	}
+
+	if drained == 0 {
+		return nil, nil
+	}
+	return drain, nil
+}
+
+// deployedService returns the untagged name of service when it is a service
+// of config deployed for tag.
+func deployedService(config *traefik.TraefikHTTPConfiguration, service string, tag containers.ContainerTag) (string, bool) {
+	suffix := "-" + string(tag)
+	if !strings.HasSuffix(service, suffix) {
+		return "", false
+	}
+	if _, ok := config.Services[service]; !ok {
+		return "", false
+	}
+	return strings.TrimSuffix(service, suffix), true
+}
+
+// stickyCookie returns a copy of cookie named for service.
+func stickyCookie(cookie *traefik.TraefikServiceWeightedStickyCookie, service string) *traefik.TraefikServiceWeightedStickyCookie {
+	named := *cookie
+	if named.Name == "" {
+		named.Name = defaultStickyCookie
+	}
+	named.Name = fmt.Sprintf("%s_%s", named.Name, service)
+	return &named
+}
+
+// cookieMatcher returns a rule matching requests that carry the named cookie,
+// in the rule syntax of the router.
+func cookieMatcher(ruleSyntax, name string) string {
+	matcher := "HeaderRegexp"
+	if ruleSyntax == "v2" {
+		matcher = "HeadersRegexp"
+	}
+	return fmt.Sprintf("%s(`Cookie`, `(^|;\\s*)%s=`)", matcher, regexp.QuoteMeta(name))
+}
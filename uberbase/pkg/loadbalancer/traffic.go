@@ -2,13 +2,13 @@ package loadbalancer
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/state"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
@@ -25,6 +25,14 @@ type TrafficManager struct {
 	dynamicConfigs map[string]*traefik.TraefikDynamicConfiguration
 	containerMgr   *containers.ContainerManager
 	healthChecker  *health.HealthChecker
+	activeTag      containers.ContainerTag
+	activeConfig   *traefik.TraefikDynamicConfiguration
+	drainConfig    *traefik.TraefikDynamicConfiguration
+	sessions       SessionOptions
+	tlsPolicy      *traefik.TraefikTLSPolicy
+	healthReport   *health.HealthReport
+	// hostTags are the tags of the hosts a deploy leaves on other tags
+	hostTags map[string]containers.ContainerTag
 }
 
 // NewTrafficManager creates a new TrafficManager instance with the provided container manager.
@@ -39,6 +47,36 @@ func NewTrafficManager(containerMgr *containers.ContainerManager) (*TrafficManag
 	}, nil
 }
 
+// SetHostExecutor checks the routed servers from the host the containers run
+// on, where their addresses can be reached.
+func (t *TrafficManager) SetHostExecutor(executor core.Executor) {
+	t.healthChecker.SetHostExecutor(executor)
+}
+
+// SetSessionOptions configures session affinity for subsequent deploys.
+func (t *TrafficManager) SetSessionOptions(options SessionOptions) {
+	t.sessions = options
+}
+
+// SetTLSPolicy sets the certificate resolvers used for the routers of
+// subsequent deploys.
+func (t *TrafficManager) SetTLSPolicy(policy traefik.TraefikTLSPolicy) {
+	t.tlsPolicy = &policy
+}
+
+// SetHealthThresholds sets when the routed servers of subsequent deploys are
+// settled as healthy or unhealthy.
+func (t *TrafficManager) SetHealthThresholds(thresholds health.HealthThresholds) {
+	t.healthChecker.SetThresholds(thresholds)
+}
+
+// SetHostTags sets the tags of the services a deploy leaves as they are,
+// keyed by the host their servers are addressed by, so their routes keep
+// pointing at their current containers.
+func (t *TrafficManager) SetHostTags(tags map[string]containers.ContainerTag) {
+	t.hostTags = tags
+}
+
 func (t *TrafficManager) Load() error {
 	staticConfig, err := traefik.LoadTraefikStaticConfig()
 	if err != nil {
@@ -49,13 +87,13 @@ func (t *TrafficManager) Load() error {
 		return fmt.Errorf("failed to load dynamic configs: %w", err)
 	}
 	t.staticConfig = staticConfig
-	t.dynamicConfigs = dynamicConfigs
+	t.dynamicConfigs = deployedConfigs(dynamicConfigs, projectHosts(t.containerMgr.Compose, t.hostTags))
 	return nil
 }
 
 // Deploy updates the traffic routing for a new deployment with the given tag.
-// Deploy updates the traffic routing for a new deployment with the given tag.
-// It ensures the new services are healthy before updating the routing configuration.
+// It ensures the new services are healthy before swapping in the configuration
+// returned by GetActiveConfig.
 func (t *TrafficManager) Deploy(ctx context.Context, state *state.DeploymentState, tag containers.ContainerTag) error {
 	if tag == "" {
 		return fmt.Errorf("container tag cannot be empty")
@@ -65,47 +103,60 @@ func (t *TrafficManager) Deploy(ctx context.Context, state *state.DeploymentStat
 		return fmt.Errorf("deployment state cannot be nil")
 	}
 
-	if state.Tag == tag {
-		return nil
-	}
+	t.healthReport = nil
 
-	oldTag := state.Tag
+	deployConfigs, err := t.createDeployConfigs(tag)
+	if err != nil {
+		return fmt.Errorf("failed to create deploy config: %w", err)
+	}
 
-	if t.hasDeployConfigs(tag) {
-		return fmt.Errorf("deploy config already exists for tag %s", tag)
+	active, err := traefik.MergeDynamicConfigs(deployConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to merge deploy configs: %w", err)
+	}
+	if t.tlsPolicy != nil && active.HTTP != nil {
+		active.HTTP.ApplyTLSPolicy(*t.tlsPolicy)
+	}
+	if problems := active.Validate(); len(problems) > 0 {
+		return fmt.Errorf("invalid deploy config: %w", errors.Join(problems...))
 	}
 
-	deployConfigs, err := t.createDeployConfigs(tag)
+	report, err := t.waitForHealthy(ctx, deployConfigs)
+	t.healthReport = report
 	if err != nil {
-		return fmt.Errorf("failed to create deploy config: %w", err)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	if t.sessions.Cookie != nil {
+		applySticky(active, tag, t.sessions.Cookie)
 	}
 
-	for _, config := range deployConfigs {
-		healthyChan, err := t.waitForHealthy(ctx, config)
+	// sessions are not drained while in maintenance, as nothing is served,
+	// nor when traffic is already routed by tag, as the routes would collide
+	var drain *traefik.TraefikDynamicConfiguration
+	if t.sessions.Cookie != nil && t.sessions.DrainTimeout > 0 && state.Maintenance == nil && state.Traefik != nil && state.Traefik.Tag != tag {
+		drain, err = createDrainConfig(active, tag, state.Traefik.Active, state.Traefik.Tag)
 		if err != nil {
-			return fmt.Errorf("failed to wait for health: %w", err)
+			return fmt.Errorf("failed to create drain config: %w", err)
 		}
-
-		select {
-		case isHealthy := <-healthyChan:
-			if !isHealthy {
-				return fmt.Errorf("health check failed for service")
+		if drain != nil {
+			if problems := drain.Validate(); len(problems) > 0 {
+				return fmt.Errorf("invalid drain config: %w", errors.Join(problems...))
 			}
-		case <-ctx.Done():
-			return fmt.Errorf("deployment cancelled: %w", ctx.Err())
-		case <-time.After(healthCheckTimeout):
-			return fmt.Errorf("timed out waiting for health")
 		}
 	}
 
-	if err := t.updateRouters(tag); err != nil {
-		return fmt.Errorf("failed to update routers: %w", err)
-	}
-
-	if err := t.removeDynamicConfigs(oldTag); err != nil {
-		return fmt.Errorf("failed to remove dynamic configs: %w", err)
+	// keep hosts in maintenance across deploys
+	if state.Maintenance != nil {
+		active, err = EnableMaintenance(active, tag, state.Maintenance)
+		if err != nil {
+			return fmt.Errorf("failed to keep maintenance mode: %w", err)
+		}
 	}
 
+	t.activeConfig = active
+	t.drainConfig = drain
+	t.activeTag = tag
 	return nil
 }
 
@@ -120,109 +171,43 @@ func (t *TrafficManager) GetDynamicConfigs() map[string]*traefik.TraefikDynamicC
 	return configs
 }
 
-func (t *TrafficManager) hasDeployConfigs(tag containers.ContainerTag) bool {
-	for configFile := range t.dynamicConfigs {
-		if strings.HasSuffix(configFile, fmt.Sprintf("%s-deploy.yml", string(tag))) {
-			return true
-		}
-	}
-	return false
+// GetActiveConfig returns the merged configuration routing traffic to the tag
+// of the last successful Deploy, ready to be served to Traefik.
+func (t *TrafficManager) GetActiveConfig() (containers.ContainerTag, *traefik.TraefikDynamicConfiguration) {
+	return t.activeTag, t.activeConfig
 }
 
-func (t *TrafficManager) createDeployConfigs(tag containers.ContainerTag) (map[string]*traefik.TraefikDynamicConfiguration, error) {
-	deployConfigs := make(map[string]*traefik.TraefikDynamicConfiguration)
-	for configFile, config := range t.dynamicConfigs {
-		if strings.HasSuffix(configFile, fmt.Sprintf("%s-deploy.yml", string(tag))) {
-			continue
-		}
-		tagConfig := config.Copy()
-		if tagConfig.HTTP == nil || tagConfig.HTTP.Services == nil {
-			return nil, fmt.Errorf("invalid configuration: HTTP or Services is nil")
-		}
-		for _, service := range tagConfig.HTTP.Services {
-			for _, server := range service.LoadBalancer.Servers {
-				host, port, err := parseURL(server.URL)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse URL: %w", err)
-				}
-				server.URL = fmt.Sprintf("http://%s-%s:%d", host, string(tag), port)
-			}
-		}
-		for _, router := range tagConfig.HTTP.Routers {
-			router.Service = fmt.Sprintf("%s-%s", router.Service, string(tag))
-		}
-		if err := tagConfig.WriteToFile(traefik.DynamicConfigPath, fmt.Sprintf("%s-deploy.yml", string(tag))); err != nil {
-			return nil, fmt.Errorf("failed to write tag config: %w", err)
-		}
-		deployConfigs[fmt.Sprintf("%s.yml", string(tag))] = tagConfig
-	}
-	return deployConfigs, nil
+// GetDrainConfig returns the configuration to serve while sessions pinned to
+// the previous tag drain, and how long to serve it before GetActiveConfig.
+// The configuration is nil when there is nothing to drain.
+func (t *TrafficManager) GetDrainConfig() (*traefik.TraefikDynamicConfiguration, time.Duration) {
+	return t.drainConfig, t.sessions.DrainTimeout
 }
 
-func (t *TrafficManager) updateRouters(tag containers.ContainerTag) error {
-	for configFile, config := range t.dynamicConfigs {
-		if !strings.HasSuffix(configFile, fmt.Sprintf("%s-deploy.yml", string(tag))) {
-			continue
-		}
-		if config.HTTP != nil && config.HTTP.Routers != nil {
-			for _, router := range config.HTTP.Routers {
-				router.Service = fmt.Sprintf("%s-%s", router.Service, string(tag))
-			}
-		}
-	}
-	return nil
+// GetHealthReport returns the health of the routed servers as checked by
+// the last Deploy, or nil when it did not get as far as checking them.
+func (t *TrafficManager) GetHealthReport() *health.HealthReport {
+	return t.healthReport
 }
 
-func (t *TrafficManager) waitForHealthy(ctx context.Context, config *traefik.TraefikDynamicConfiguration) (<-chan bool, error) {
-	healthyChan := make(chan bool, 1)
-
-	go func() {
-		defer close(healthyChan)
-
-		httpChan, err := t.healthChecker.WaitForHTTPHealthChecks(ctx, config.HTTP.Services)
-		if err != nil {
-			healthyChan <- false
-			return
+// waitForHealthy checks the routed servers of every deploy config, giving up
+// on those still unhealthy after healthCheckTimeout.
+func (t *TrafficManager) waitForHealthy(ctx context.Context, configs map[string]*traefik.TraefikDynamicConfiguration) (*health.HealthReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	report := health.NewHealthReport()
+	for _, config := range configs {
+		// UDP has no handshake to probe, so UDP services rely on their
+		// containers having been checked as running before cut-over
+		if config.HTTP != nil {
+			httpReport, _ := t.healthChecker.WaitForHTTPHealthChecks(ctx, config.HTTP.Services)
+			report.Merge(httpReport)
 		}
-
-		select {
-		case result := <-httpChan:
-			healthyChan <- result
-		case <-ctx.Done():
-			healthyChan <- false
+		if config.TCP != nil {
+			tcpReport, _ := t.healthChecker.WaitForTCPHealthChecks(ctx, config.TCP.Services)
+			report.Merge(tcpReport)
 		}
-	}()
-
-	return healthyChan, nil
-}
-
-// parseURL parses a URL string into host and port components.
-// The URL should be in the format "host:port".
-func parseURL(urlStr string) (string, int, error) {
-	urlStr = strings.TrimPrefix(strings.TrimPrefix(urlStr, "http://"), "https://")
-
-	split := strings.Split(urlStr, ":")
-	if len(split) != 2 {
-		return "", 0, fmt.Errorf("invalid URL format (expected host:port): %s", urlStr)
 	}
-
-	port, err := strconv.Atoi(split[1])
-	if err != nil {
-		return "", 0, fmt.Errorf("invalid port number %q: %w", split[1], err)
-	}
-
-	if port < 1 || port > 65535 {
-		return "", 0, fmt.Errorf("port number out of range (1-65535): %d", port)
-	}
-
-	return split[0], port, nil
-}
-
-func (t *TrafficManager) removeDynamicConfigs(tag containers.ContainerTag) error {
-	for configFile := range t.dynamicConfigs {
-		if strings.HasSuffix(configFile, fmt.Sprintf("%s-deploy.yml", string(tag))) {
-			os.Remove(traefik.DynamicConfigPath + configFile)
-		}
-	}
-	return nil
+	return report, report.Err()
 }
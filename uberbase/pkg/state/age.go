@@ -0,0 +1,85 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+)
+
+// AgeKeyProvider wraps data keys with age, to the recipient of an age
+// identity or to a passphrase.
+type AgeKeyProvider struct {
+	name      string
+	recipient age.Recipient
+	identity  age.Identity
+}
+
+// NewAgeKeyProvider wraps data keys to the first X25519 identity in the
+// identity file at path
+func NewAgeKeyProvider(path string) (*AgeKeyProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age identity: %w", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity %s: %w", path, err)
+	}
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			return &AgeKeyProvider{name: "age", recipient: x25519.Recipient(), identity: x25519}, nil
+		}
+	}
+	return nil, fmt.Errorf("no X25519 identity in %s", path)
+}
+
+// NewPassphraseKeyProvider wraps data keys with a key derived from
+// passphrase by scrypt
+func NewPassphraseKeyProvider(passphrase string) (*AgeKeyProvider, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create passphrase key: %w", err)
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create passphrase key: %w", err)
+	}
+	return &AgeKeyProvider{name: "passphrase", recipient: recipient, identity: identity}, nil
+}
+
+func (p *AgeKeyProvider) Name() string {
+	return p.name
+}
+
+func (p *AgeKeyProvider) WrapKey(dataKey []byte) (string, error) {
+	var wrapped bytes.Buffer
+	writer, err := age.Encrypt(&wrapped, p.recipient)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(dataKey); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(wrapped.Bytes()), nil
+}
+
+func (p *AgeKeyProvider) UnwrapKey(wrapped string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("malformed wrapped key: %w", err)
+	}
+	reader, err := age.Decrypt(bytes.NewReader(sealed), p.identity)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
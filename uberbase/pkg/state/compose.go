@@ -18,6 +18,8 @@ type ComposeServiceState struct {
 	Hostname      string `yaml:"hostname"`
 	Image         string `yaml:"image"`
 	Tag           string `yaml:"tag"`
+	// ImageID is the ID of the image the container ran when deployed
+	ImageID string `yaml:"image_id,omitempty"`
 }
 
 type ComposeState struct {
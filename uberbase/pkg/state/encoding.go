@@ -0,0 +1,92 @@
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateSchemaVersion is the version of the state format this build writes
+const StateSchemaVersion = 1
+
+// checksumHeader starts the first line of a state file, followed by the
+// SHA-256 of the rest of the file
+const checksumHeader = "# uberbase-state sha256:"
+
+// ErrStateCorrupt is returned when stored state fails its checksum or does
+// not parse
+var ErrStateCorrupt = errors.New("state is corrupt")
+
+// encodeState serializes state under a checksum header, encrypting its
+// sensitive fields when there is a cipher
+func encodeState(state DeploymentState, cipher *stateCipher) ([]byte, error) {
+	state.SchemaVersion = StateSchemaVersion
+	body, err := yaml.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if cipher != nil {
+		var document StateDocument
+		if err := yaml.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("failed to marshal state: %w", err)
+		}
+		if err := cipher.encrypt(document); err != nil {
+			return nil, err
+		}
+		if body, err = yaml.Marshal(document); err != nil {
+			return nil, fmt.Errorf("failed to marshal state: %w", err)
+		}
+	}
+	body = bytes.TrimRight(body, "\n")
+	sum := sha256.Sum256(body)
+
+	var data bytes.Buffer
+	data.WriteString(checksumHeader + hex.EncodeToString(sum[:]) + "\n")
+	data.Write(body)
+	data.WriteString("\n")
+	return data.Bytes(), nil
+}
+
+// decodeState verifies and parses serialized state, decrypting it and
+// migrating state written in earlier schema versions. State written before
+// checksums were added has no header and is only checked for syntax.
+func decodeState(data []byte, cipher *stateCipher) (DeploymentState, error) {
+	var state DeploymentState
+
+	body := data
+	if bytes.HasPrefix(data, []byte(checksumHeader)) {
+		header, rest, _ := bytes.Cut(data, []byte("\n"))
+		body = bytes.TrimRight(rest, "\n")
+		sum := sha256.Sum256(body)
+		if expected := string(bytes.TrimPrefix(header, []byte(checksumHeader))); expected != hex.EncodeToString(sum[:]) {
+			return state, fmt.Errorf("%w: checksum mismatch", ErrStateCorrupt)
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return state, fmt.Errorf("%w: empty", ErrStateCorrupt)
+	}
+
+	var document StateDocument
+	if err := yaml.Unmarshal(body, &document); err != nil {
+		return state, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
+	}
+	if document == nil {
+		return state, fmt.Errorf("%w: not a state document", ErrStateCorrupt)
+	}
+	if err := cipher.decrypt(document); err != nil {
+		return state, err
+	}
+	if _, err := migrateState(document); err != nil {
+		return state, err
+	}
+
+	state, err := decodeDocument(document)
+	if err != nil {
+		return state, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
+	}
+	return state, nil
+}
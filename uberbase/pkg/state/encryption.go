@@ -0,0 +1,249 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyProvider wraps and unwraps the data keys that encrypt the sensitive
+// fields of the state, so only wrapped keys are stored alongside them.
+type KeyProvider interface {
+	// Name identifies the kind of key, and is stored with the state
+	Name() string
+	WrapKey(dataKey []byte) (string, error)
+	UnwrapKey(wrapped string) ([]byte, error)
+}
+
+// ErrStateEncrypted is returned when loading encrypted state without a key
+var ErrStateEncrypted = errors.New("state is encrypted")
+
+// encryptionField holds the wrapped data key of an encrypted state document
+const encryptionField = "encryption"
+
+// encryptedPrefix marks an encrypted field value, followed by the base64 of
+// its nonce and ciphertext and a closing bracket
+const encryptedPrefix = "ENC[AES256_GCM,"
+
+// dynamicConfigPaths are where a state document holds Traefik dynamic
+// configs, "*" standing for any key
+var dynamicConfigPaths = [][]string{
+	{"traefik", "configs", "*"},
+	{"traefik", "active"},
+	{"traefik", "next"},
+	{"previous", "active"},
+}
+
+// sensitiveConfigFields are the sections of a dynamic config that can hold
+// credentials: basicAuth users, header tokens, client certificates and keys
+var sensitiveConfigFields = [][]string{
+	{"http", "middlewares"},
+	{"http", "serversTransports"},
+	{"tcp", "serversTransports"},
+	{"tls"},
+}
+
+// ParseKeyProvider parses a state key spec:
+//
+//	vault:name              Vault transit key name, under the transit mount
+//	vault:mount/name        Vault transit key name, under another mount
+//	age:/path/identity.txt  age identity file, as written by age-keygen
+//	passphrase              passphrase in UBERBASE_STATE_PASSPHRASE
+//	passphrase:VAR          passphrase in the environment variable VAR
+//
+// An empty spec leaves the state unencrypted and returns nil.
+func ParseKeyProvider(spec string) (KeyProvider, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "vault":
+		return NewVaultKeyProvider(value)
+	case "age":
+		if value == "" {
+			return nil, fmt.Errorf("age state key needs an identity file, as age:/path/identity.txt")
+		}
+		return NewAgeKeyProvider(value)
+	case "passphrase":
+		if value == "" {
+			value = "UBERBASE_STATE_PASSPHRASE"
+		}
+		passphrase := os.Getenv(value)
+		if passphrase == "" {
+			return nil, fmt.Errorf("passphrase state key needs a passphrase in %s", value)
+		}
+		return NewPassphraseKeyProvider(passphrase)
+	default:
+		return nil, fmt.Errorf("unknown state key %q, expected vault:, age: or passphrase", spec)
+	}
+}
+
+// stateCipher encrypts and decrypts the sensitive fields of state documents
+// with a data key wrapped by its provider. The data key is kept once
+// unwrapped, as unwrapping can be slow or need a round trip to Vault.
+type stateCipher struct {
+	provider   KeyProvider
+	dataKey    []byte
+	wrappedKey string
+}
+
+// encrypt replaces the sensitive fields of document with their ciphertext
+// and records the wrapped data key
+func (c *stateCipher) encrypt(document StateDocument) error {
+	if c.dataKey == nil {
+		dataKey := make([]byte, 32)
+		if _, err := rand.Read(dataKey); err != nil {
+			return fmt.Errorf("failed to generate state key: %w", err)
+		}
+		wrapped, err := c.provider.WrapKey(dataKey)
+		if err != nil {
+			return fmt.Errorf("failed to wrap state key: %w", err)
+		}
+		c.dataKey, c.wrappedKey = dataKey, wrapped
+	}
+
+	aead, err := newAEAD(c.dataKey)
+	if err != nil {
+		return err
+	}
+	err = visitSensitiveFields(document, func(fields StateDocument, key, path string) error {
+		plaintext, err := yaml.Marshal(fields[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", path, err)
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		// the path is authenticated so fields cannot be swapped
+		sealed := aead.Seal(nonce, nonce, plaintext, []byte(path))
+		fields[key] = encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + "]"
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	document[encryptionField] = map[string]interface{}{
+		"provider": c.provider.Name(),
+		"key":      c.wrappedKey,
+	}
+	return nil
+}
+
+// decrypt restores the sensitive fields of an encrypted document. Documents
+// written without encryption are left as they are.
+func (c *stateCipher) decrypt(document StateDocument) error {
+	envelope, ok := document[encryptionField].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	delete(document, encryptionField)
+
+	provider, _ := envelope["provider"].(string)
+	wrapped, _ := envelope["key"].(string)
+	if c == nil {
+		return fmt.Errorf("%w with a %s key, set --state-key to load it", ErrStateEncrypted, provider)
+	}
+	if provider != c.provider.Name() {
+		return fmt.Errorf("%w with a %s key, not %s", ErrStateEncrypted, provider, c.provider.Name())
+	}
+
+	if wrapped != c.wrappedKey {
+		dataKey, err := c.provider.UnwrapKey(wrapped)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap state key: %w", err)
+		}
+		c.dataKey, c.wrappedKey = dataKey, wrapped
+	}
+
+	aead, err := newAEAD(c.dataKey)
+	if err != nil {
+		return err
+	}
+	return visitSensitiveFields(document, func(fields StateDocument, key, path string) error {
+		value, ok := fields[key].(string)
+		if !ok || !strings.HasPrefix(value, encryptedPrefix) || !strings.HasSuffix(value, "]") {
+			return fmt.Errorf("%w: %s is not encrypted", ErrStateCorrupt, path)
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), "]"))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("%w: %s is malformed", ErrStateCorrupt, path)
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(path))
+		if err != nil {
+			return fmt.Errorf("%w: %s failed to decrypt", ErrStateCorrupt, path)
+		}
+		var decrypted interface{}
+		if err := yaml.Unmarshal(plaintext, &decrypted); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrStateCorrupt, path, err)
+		}
+		fields[key] = decrypted
+		return nil
+	})
+}
+
+// visitSensitiveFields calls visit with each sensitive field of document
+// that is set, the map holding it and its dotted path
+func visitSensitiveFields(document StateDocument, visit func(fields StateDocument, key, path string) error) error {
+	for _, configPath := range dynamicConfigPaths {
+		err := visitPath(document, configPath, "", func(config StateDocument, path string) error {
+			for _, field := range sensitiveConfigFields {
+				err := visitPath(config, field[:len(field)-1], path, func(parent StateDocument, parentPath string) error {
+					key := field[len(field)-1]
+					if parent[key] == nil {
+						return nil
+					}
+					return visit(parent, key, parentPath+"."+key)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitPath calls visit with each map found by following path from fields
+func visitPath(fields StateDocument, path []string, prefix string, visit func(fields StateDocument, path string) error) error {
+	if len(path) == 0 {
+		return visit(fields, strings.TrimPrefix(prefix, "."))
+	}
+	for key, value := range fields {
+		if path[0] != "*" && path[0] != key {
+			continue
+		}
+		if next, ok := value.(map[string]interface{}); ok {
+			if err := visitPath(next, path[1:], prefix+"."+key, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state cipher: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,169 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+	"gopkg.in/yaml.v3"
+)
+
+// sensitiveState returns state holding credentials in two configs, whose
+// encrypted fields can be swapped with each other
+func sensitiveState() DeploymentState {
+	config := func(user string) traefik.TraefikDynamicConfiguration {
+		return traefik.TraefikDynamicConfiguration{HTTP: &traefik.TraefikHTTPConfiguration{
+			Routers:     map[string]traefik.TraefikRouter{"web": {Rule: "Host(`example.com`)", Service: "web", Middlewares: []string{"auth"}}},
+			Middlewares: map[string]traefik.TraefikMiddleware{"auth": traefik.NewBasicAuthMiddleware("", user)},
+		}}
+	}
+	return DeploymentState{
+		Tag: "abc123",
+		Traefik: &TraefikState{
+			Tag: "abc123",
+			Configs: map[string]traefik.TraefikDynamicConfiguration{
+				"admin.yml": config("admin:$apr1$secret"),
+				"guest.yml": config("guest:$apr1$public"),
+			},
+		},
+	}
+}
+
+func newPassphraseCipher(t *testing.T) *stateCipher {
+	t.Helper()
+	provider, err := NewPassphraseKeyProvider("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stateCipher{provider: provider}
+}
+
+// rewriteState changes the document of encoded state and returns it without
+// a checksum, as state written before checksums were added
+func rewriteState(t *testing.T, data []byte, change func(document StateDocument)) []byte {
+	t.Helper()
+	_, body, _ := bytes.Cut(data, []byte("\n"))
+	var document StateDocument
+	if err := yaml.Unmarshal(body, &document); err != nil {
+		t.Fatal(err)
+	}
+	change(document)
+	rewritten, err := yaml.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rewritten
+}
+
+func httpSection(document StateDocument, config string) StateDocument {
+	configs := document["traefik"].(StateDocument)["configs"].(StateDocument)
+	return configs[config].(StateDocument)["http"].(StateDocument)
+}
+
+func TestEncryptedStateRoundTrip(t *testing.T) {
+	data, err := encodeState(sensitiveState(), newPassphraseCipher(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("encoded state holds a credential in plaintext:\n%s", data)
+	}
+	if !bytes.Contains(data, []byte(encryptedPrefix)) {
+		t.Fatalf("encoded state has no encrypted fields:\n%s", data)
+	}
+
+	// a new cipher unwraps the data key with the passphrase
+	state, err := decodeState(data, newPassphraseCipher(t))
+	if err != nil {
+		t.Fatalf("failed to decode encrypted state: %v", err)
+	}
+	users := state.Traefik.Configs["admin.yml"].HTTP.Middlewares["auth"].BasicAuth.Users
+	if len(users) != 1 || users[0] != "admin:$apr1$secret" {
+		t.Errorf("admin users = %v, want the decrypted credential", users)
+	}
+	if router := state.Traefik.Configs["admin.yml"].HTTP.Routers["web"]; router.Service != "web" {
+		t.Errorf("admin router = %+v, want it kept in plaintext", router)
+	}
+}
+
+func TestEncryptedStateTampered(t *testing.T) {
+	cipher := newPassphraseCipher(t)
+	data, err := encodeState(sensitiveState(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(document StateDocument)
+		want   string
+	}{
+		{
+			name: "swapped fields",
+			change: func(document StateDocument) {
+				admin, guest := httpSection(document, "admin.yml"), httpSection(document, "guest.yml")
+				admin["middlewares"], guest["middlewares"] = guest["middlewares"], admin["middlewares"]
+			},
+			want: "failed to decrypt",
+		},
+		{
+			name: "tampered ciphertext",
+			change: func(document StateDocument) {
+				fields := httpSection(document, "admin.yml")
+				value := fields["middlewares"].(string)
+				i := len(encryptedPrefix) + 24
+				flipped := "A"
+				if value[i] == 'A' {
+					flipped = "B"
+				}
+				fields["middlewares"] = value[:i] + flipped + value[i+1:]
+			},
+			want: "failed to decrypt",
+		},
+		{
+			name: "decrypted field",
+			change: func(document StateDocument) {
+				httpSection(document, "admin.yml")["middlewares"] = StateDocument{"auth": StateDocument{}}
+			},
+			want: "is not encrypted",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeState(rewriteState(t, data, test.change), cipher)
+			if !errors.Is(err, ErrStateCorrupt) || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("err = %v, want %v that %s", err, ErrStateCorrupt, test.want)
+			}
+		})
+	}
+}
+
+func TestEncryptedStateWithoutKey(t *testing.T) {
+	data, err := encodeState(sensitiveState(), newPassphraseCipher(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeState(data, nil); !errors.Is(err, ErrStateEncrypted) {
+		t.Fatalf("err = %v, want %v", err, ErrStateEncrypted)
+	}
+}
+
+// TestPlaintextStateWithKey checks state saved before a key was set still
+// loads once one is
+func TestPlaintextStateWithKey(t *testing.T) {
+	data, err := encodeState(sensitiveState(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := decodeState(data, newPassphraseCipher(t))
+	if err != nil {
+		t.Fatalf("failed to decode plaintext state: %v", err)
+	}
+	users := state.Traefik.Configs["guest.yml"].HTTP.Middlewares["auth"].BasicAuth.Users
+	if len(users) != 1 || users[0] != "guest:$apr1$public" {
+		t.Errorf("guest users = %v, want the plaintext credential", users)
+	}
+}
@@ -0,0 +1,21 @@
+package state
+
+import "github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+
+const (
+	// MaintenanceModeService routes requests to a static maintenance service
+	MaintenanceModeService = "service"
+	// MaintenanceModeErrors serves the maintenance page when the app errors
+	MaintenanceModeErrors = "errors"
+	// MaintenanceModeRedirect redirects requests to the maintenance URL
+	MaintenanceModeRedirect = "redirect"
+)
+
+type MaintenanceState struct {
+	Mode     string   `yaml:"mode"`
+	URL      string   `yaml:"url"`
+	Services []string `yaml:"services,omitempty"`
+	Since    string   `yaml:"since"`
+	// OriginalRouters holds the routers as they were before maintenance was applied
+	OriginalRouters map[string]traefik.TraefikRouter `yaml:"original_routers,omitempty"`
+}
@@ -1,23 +1,37 @@
 package state
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
 	"reflect"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/core"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
 	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
 )
 
 type DeploymentState struct {
+	// SchemaVersion is the version of the format the state was written in
+	SchemaVersion int `yaml:"schema_version"`
+
+	// Tag is the tag of the last deploy, which names its routes. Each service
+	// records the tag it runs, as a deploy of some services leaves the
+	// others on theirs.
 	Tag     containers.ContainerTag `yaml:"tag"`
 	Compose *ComposeState           `yaml:"compose"`
 	Traefik *TraefikState           `yaml:"traefik"`
 	Lock    *DeploymentLock         `yaml:"lock,omitempty"`
+	// Maintenance is set while the deployment is in maintenance mode
+	Maintenance *MaintenanceState `yaml:"maintenance,omitempty"`
+	// Health is the outcome of the last deploy's health checks
+	Health *health.HealthReport `yaml:"health,omitempty"`
+	// DeployedAt is when Tag was deployed
+	DeployedAt string `yaml:"deployed_at,omitempty"`
+	// Previous is the deployment Tag replaced, while it can be rolled back to
+	Previous *PreviousDeployment `yaml:"previous,omitempty"`
 }
 
 type DeploymentLock struct {
@@ -29,25 +43,42 @@ type DeploymentLock struct {
 
 type StateManager struct {
 	CurrentState DeploymentState
-	workDir      string
-	executor     core.Executor
+	backend      StateBackend
+	cipher       *stateCipher
+	// savedHealth is the health report last saved
+	savedHealth *health.HealthReport
 }
 
+// NewStateManager creates a manager for the state kept in the host's work
+// directory. SetBackend stores it elsewhere.
 func NewStateManager(workDir string, executor core.Executor) *StateManager {
 	return &StateManager{
-		workDir:  workDir,
-		executor: executor,
+		backend: NewRemoteFileBackend(executor, workDir),
+	}
+}
+
+// SetBackend sets where the state is loaded from and saved to.
+func (s *StateManager) SetBackend(backend StateBackend) {
+	s.backend = backend
+}
+
+// SetKeyProvider encrypts the sensitive fields of the state with keys
+// wrapped by provider, or leaves them in plaintext when it is nil. State
+// saved without encryption is still loaded, and encrypted when next saved.
+func (s *StateManager) SetKeyProvider(provider KeyProvider) {
+	s.cipher = nil
+	if provider != nil {
+		s.cipher = &stateCipher{provider: provider}
 	}
 }
 
 func (s *StateManager) Load() (DeploymentState, error) {
-	logging.Logger.Info("Loading existing deployment state")
+	logging.Logger.Debug("Loading existing deployment state")
 	var state DeploymentState
-	stateFile := filepath.Join(s.workDir, "deployment-state.yml")
 
-	_, err := s.executor.Exec(fmt.Sprintf("test -f %s", stateFile))
-	if err != nil {
-		logging.Logger.Info("State file not found, initializing empty state")
+	data, err := s.backend.Read()
+	if errors.Is(err, ErrStateNotFound) {
+		logging.Logger.Debug("State file not found, initializing empty state")
 		state = DeploymentState{
 			Compose: &ComposeState{
 				Services: make(map[string]*ComposeServiceState),
@@ -57,34 +88,83 @@ func (s *StateManager) Load() (DeploymentState, error) {
 				Configs: make(map[string]traefik.TraefikDynamicConfiguration),
 			},
 		}
+		s.CurrentState = state
 		return state, nil
 	}
-
-	data, err := s.executor.Exec(fmt.Sprintf("cat %s", stateFile))
 	if err != nil {
-		return DeploymentState{}, fmt.Errorf("failed to read state file: %w", err)
+		return DeploymentState{}, fmt.Errorf("failed to load state from %s: %w", s.backend.Location(), err)
 	}
 
-	if err := yaml.Unmarshal([]byte(data), &state); err != nil {
+	state, err = decodeState(data, s.cipher)
+	if errors.Is(err, ErrStateCorrupt) {
+		logging.Logger.Warn("State is corrupt, falling back to an earlier generation", "location", s.backend.Location(), "error", err)
+		state, err = s.loadEarlierGeneration(err)
+	}
+	if err != nil {
 		logging.Logger.Errorf("Failed to unmarshal state: %v", err)
-		return DeploymentState{}, fmt.Errorf("failed to unmarshal state: %w", err)
+		return DeploymentState{}, fmt.Errorf("failed to load state from %s: %w", s.backend.Location(), err)
 	}
 
+	s.CurrentState = state
 	return state, nil
 }
 
+// loadEarlierGeneration returns the most recent intact generation the
+// backend keeps, or corruption when none are
+func (s *StateManager) loadEarlierGeneration(corruption error) (DeploymentState, error) {
+	backend, ok := s.backend.(GenerationalBackend)
+	if !ok {
+		return DeploymentState{}, corruption
+	}
+
+	for n := 1; ; n++ {
+		data, err := backend.ReadGeneration(n)
+		if errors.Is(err, ErrStateNotFound) {
+			return DeploymentState{}, fmt.Errorf("%w, and no earlier generation is intact", corruption)
+		}
+		if err != nil {
+			return DeploymentState{}, err
+		}
+
+		state, err := decodeState(data, s.cipher)
+		if errors.Is(err, ErrStateCorrupt) {
+			continue
+		}
+		if err != nil {
+			return DeploymentState{}, err
+		}
+		logging.Logger.Warn("Recovered state from an earlier generation", "generation", n, "tag", state.Tag)
+		return state, nil
+	}
+}
+
 func (s *StateManager) Update(services map[string]containers.ComposeServiceOverride, dynamicConfigs map[string]*traefik.TraefikDynamicConfiguration, tag containers.ContainerTag) error {
-	// update the state's compose config with the new override services
-	for _, service := range s.CurrentState.Compose.Services {
-		if override, ok := services[service.ServiceName]; ok {
-			service.ServiceName = override.RefName + "-" + string(tag)
-			service.ContainerName = override.RefName + "-" + string(tag)
-			service.Image = override.Image
-			service.Hostname = override.Hostname
+	// record the new containers of each compose service, replacing rather
+	// than modifying entries, which Previous may share
+	if s.CurrentState.Compose == nil {
+		s.CurrentState.Compose = &ComposeState{}
+	}
+	updated := make(map[string]*ComposeServiceState, len(s.CurrentState.Compose.Services))
+	for name, service := range s.CurrentState.Compose.Services {
+		updated[name] = service
+	}
+	for _, override := range services {
+		updated[override.RefName] = &ComposeServiceState{
+			ServiceName:   override.RefName,
+			ContainerName: override.Name,
+			Hostname:      override.Hostname,
+			Image:         override.Image,
+			Tag:           string(tag),
 		}
 	}
+	s.CurrentState.Compose.Services = updated
 
 	// update the state's traefik config with the new dynamic configs
+	s.CurrentState.Tag = tag
+	s.CurrentState.DeployedAt = time.Now().UTC().Format(time.RFC3339)
+	if s.CurrentState.Traefik == nil {
+		s.CurrentState.Traefik = &TraefikState{}
+	}
 	s.CurrentState.Traefik.Tag = tag
 	s.CurrentState.Traefik.Configs = make(map[string]traefik.TraefikDynamicConfiguration)
 	for name, config := range dynamicConfigs {
@@ -94,59 +174,186 @@ func (s *StateManager) Update(services map[string]containers.ComposeServiceOverr
 	return s.Save()
 }
 
-func (s *StateManager) Save() error {
-	return s.write(s.CurrentState)
+// SetImageID records the ID of the image a service's container runs, to be
+// saved with the next Save.
+func (s *StateManager) SetImageID(serviceName, imageID string) {
+	if s.CurrentState.Compose == nil {
+		return
+	}
+	if service, ok := s.CurrentState.Compose.Services[serviceName]; ok {
+		service.ImageID = imageID
+	}
 }
 
-func (s *StateManager) write(state DeploymentState) error {
-	if s.workDir == "" {
-		logging.Logger.Error("Work directory not set")
-		return fmt.Errorf("work directory not set")
+// SetResources records the volumes and networks of the deployment, to be
+// saved with the next Update.
+func (s *StateManager) SetResources(volumes map[string]*ComposeVolumeState, networks map[string]*ComposeNetworkState) {
+	if s.CurrentState.Compose == nil {
+		s.CurrentState.Compose = &ComposeState{}
 	}
+	s.CurrentState.Compose.Volumes = volumes
+	s.CurrentState.Compose.Networks = networks
+}
 
-	logging.Logger.Info("Saving deployment state")
+// SetPrevious records the deployment being replaced, to be saved with the
+// next Update.
+func (s *StateManager) SetPrevious(previous *PreviousDeployment) {
+	s.CurrentState.Previous = previous
+}
 
-	// Marshal state to YAML
-	data, err := yaml.Marshal(state)
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+// RestorePrevious makes the previous deployment current again and persists
+// it. The restored deployment has no previous deployment of its own, so a
+// rollback is never itself rolled back.
+func (s *StateManager) RestorePrevious() error {
+	previous := s.CurrentState.Previous
+	if previous == nil {
+		return fmt.Errorf("no previous deployment to restore")
 	}
 
-	// Create the state directory if it doesn't exist
-	if _, err := s.executor.Exec(fmt.Sprintf("mkdir -p %s", filepath.Dir(s.workDir))); err != nil {
-		return fmt.Errorf("failed to create state directory: %w", err)
+	if s.CurrentState.Compose == nil {
+		s.CurrentState.Compose = &ComposeState{}
 	}
+	s.CurrentState.Compose.Services = previous.Services
+	s.CurrentState.Tag = previous.Tag
+	s.CurrentState.DeployedAt = previous.DeployedAt
+	s.CurrentState.Previous = nil
+	return s.Activate(previous.Tag, previous.Active)
+}
 
-	stateFile := filepath.Join(s.workDir, "deployment-state.yml")
+// Reset clears the recorded deployment and its routing and persists the
+// empty state, for removing a host's only deployment.
+func (s *StateManager) Reset() error {
+	s.CurrentState.Tag = ""
+	s.CurrentState.DeployedAt = ""
+	s.CurrentState.Health = nil
+	s.CurrentState.Maintenance = nil
+	s.CurrentState.Previous = nil
+	s.CurrentState.Compose = &ComposeState{
+		Services: make(map[string]*ComposeServiceState),
+	}
+	s.CurrentState.Traefik = &TraefikState{
+		Configs: make(map[string]traefik.TraefikDynamicConfiguration),
+	}
+	return s.Save()
+}
 
-	// Write data using heredoc approach
-	cmd := fmt.Sprintf("cat <<'EOF' > %s\n%s\nEOF", stateFile, string(data))
-	if _, err := s.executor.Exec(cmd); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+// Activate records config as the routing for tag and persists it. Traefik
+// polls the active configuration, so saving it is the traffic cut-over.
+func (s *StateManager) Activate(tag containers.ContainerTag, config *traefik.TraefikDynamicConfiguration) error {
+	if s.CurrentState.Traefik == nil {
+		s.CurrentState.Traefik = &TraefikState{
+			Configs: make(map[string]traefik.TraefikDynamicConfiguration),
+		}
+	}
+	s.CurrentState.Traefik.Tag = tag
+	s.CurrentState.Traefik.Active = config
+	s.CurrentState.Traefik.Next = nil
+	return s.Save()
+}
+
+// ActivateDraining records drain as the routing for tag while the sessions
+// of the previous tag drain, and next as the routing to activate after, so
+// an interrupted deploy can still be completed.
+func (s *StateManager) ActivateDraining(tag containers.ContainerTag, drain, next *traefik.TraefikDynamicConfiguration) error {
+	if s.CurrentState.Traefik == nil {
+		s.CurrentState.Traefik = &TraefikState{
+			Configs: make(map[string]traefik.TraefikDynamicConfiguration),
+		}
+	}
+	s.CurrentState.Traefik.Tag = tag
+	s.CurrentState.Traefik.Active = drain
+	s.CurrentState.Traefik.Next = next
+	return s.Save()
+}
+
+// SetMaintenance records the maintenance mode, or its absence when maintenance
+// is nil, along with the configuration to serve while it is in effect.
+func (s *StateManager) SetMaintenance(maintenance *MaintenanceState, config *traefik.TraefikDynamicConfiguration) error {
+	s.CurrentState.Maintenance = maintenance
+	var tag containers.ContainerTag
+	if s.CurrentState.Traefik != nil {
+		tag = s.CurrentState.Traefik.Tag
+	}
+	return s.Activate(tag, config)
+}
+
+// SetHealth records the outcome of a deploy's health checks, to be saved
+// with the next Save.
+func (s *StateManager) SetHealth(report *health.HealthReport) {
+	s.CurrentState.Health = report
+}
+
+// SaveHealth persists report onto the state as last saved, leaving the
+// other changes in memory unsaved, unless report was saved already. A
+// failed deploy's report outlives the rollback of its other changes.
+func (s *StateManager) SaveHealth(report *health.HealthReport) error {
+	if report == nil || s.savedHealth == report {
+		return nil
+	}
+	if _, err := s.Load(); err != nil {
+		return err
+	}
+	s.CurrentState.Health = report
+	return s.Save()
+}
+
+func (s *StateManager) Save() error {
+	return s.write(s.CurrentState)
+}
+
+func (s *StateManager) write(state DeploymentState) error {
+	logging.Logger.Info("Saving deployment state")
+
+	data, err := encodeState(state, s.cipher)
+	if err != nil {
+		return err
+	}
+
+	if err := s.backend.Write(data); err != nil {
+		return err
 	}
+	s.savedHealth = state.Health
 
-	logging.Logger.Info("State file updated successfully")
+	logging.Logger.Info("State file updated successfully", "location", s.backend.Location())
 	return nil
 }
 
-// Equal compares two states for equality
+// Equal reports whether two states deploy the same containers and route
+// traffic the same way
 func (s *DeploymentState) Equal(other *DeploymentState) bool {
 	if s.Tag != other.Tag {
 		return false
 	}
 
-	if len(s.Compose.Services) != len(other.Compose.Services) {
+	services, otherServices := s.services(), other.services()
+	if len(services) != len(otherServices) {
 		return false
 	}
 
 	// Compare services
-	for name, service := range s.Compose.Services {
-		otherService, exists := other.Compose.Services[name]
+	for name, service := range services {
+		otherService, exists := otherServices[name]
 		if !exists || service.ContainerName != otherService.ContainerName {
 			return false
 		}
 	}
 
-	// Compare Traefik configs
-	return reflect.DeepEqual(s.Traefik, other.Traefik)
+	// Compare the active routing, no Traefik state routing nothing
+	tag, active := s.routing()
+	otherTag, otherActive := other.routing()
+	return tag == otherTag && reflect.DeepEqual(active, otherActive)
+}
+
+func (s *DeploymentState) services() map[string]*ComposeServiceState {
+	if s.Compose == nil {
+		return nil
+	}
+	return s.Compose.Services
+}
+
+func (s *DeploymentState) routing() (containers.ContainerTag, *traefik.TraefikDynamicConfiguration) {
+	if s.Traefik == nil {
+		return "", nil
+	}
+	return s.Traefik.Tag, s.Traefik.Active
 }
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/health"
+)
+
+// TestSaveHealth checks a failed deploy's report is saved onto the state as
+// last saved, without the deploy's other unsaved changes, and only once.
+func TestSaveHealth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yml")
+	manager := &StateManager{backend: NewLocalFileBackend(path)}
+	if _, err := manager.Load(); err != nil {
+		t.Fatal(err)
+	}
+	manager.CurrentState.Tag = "abc123"
+	if err := manager.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := health.NewHealthReport()
+	report.Healthy = false
+	manager.SetHealth(report)
+	manager.SetPrevious(&PreviousDeployment{Tag: "abc123"})
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatalf("SetHealth saved the state")
+	}
+
+	if err := manager.SaveHealth(report); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := manager.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Health == nil || saved.Health.Healthy {
+		t.Errorf("health = %+v, want the unhealthy report", saved.Health)
+	}
+	if saved.Previous != nil {
+		t.Errorf("previous = %+v, want the unsaved change left out", saved.Previous)
+	}
+	if saved.Tag != "abc123" {
+		t.Errorf("tag = %q, want %q", saved.Tag, "abc123")
+	}
+
+	// saved once, so the generation before the deploy is still kept
+	if _, err := os.Stat(path + ".2"); err == nil {
+		t.Fatalf("state saved more than twice")
+	}
+	if err := manager.SaveHealth(report); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".2"); err == nil {
+		t.Errorf("SaveHealth saved a report that was saved already")
+	}
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/logging"
+)
+
+// StateDocument is state as parsed from YAML, before it is decoded into a
+// DeploymentState, for migrations to rewrite.
+type StateDocument = map[string]interface{}
+
+// Migration upgrades a state document from version From to From+1.
+type Migration struct {
+	From        int
+	Description string
+	Migrate     func(document StateDocument) error
+}
+
+var migrations = make(map[int]Migration)
+
+// RegisterMigration adds a migration to the registry. Every version below
+// StateSchemaVersion needs one for older state to load.
+func RegisterMigration(migration Migration) {
+	if _, ok := migrations[migration.From]; ok {
+		panic(fmt.Sprintf("state migration from version %d registered twice", migration.From))
+	}
+	migrations[migration.From] = migration
+}
+
+func init() {
+	RegisterMigration(Migration{
+		From:        0,
+		Description: "name compose services after their compose service rather than their tagged container",
+		Migrate:     migrateServiceNames,
+	})
+}
+
+// migrateState upgrades a document to StateSchemaVersion, returning the
+// version it was written in
+func migrateState(document StateDocument) (int, error) {
+	version, err := documentVersion(document)
+	if err != nil {
+		return 0, err
+	}
+	if version > StateSchemaVersion {
+		return version, fmt.Errorf("state schema version %d is newer than the %d this uberbase supports, upgrade uberbase", version, StateSchemaVersion)
+	}
+
+	for from := version; from < StateSchemaVersion; from++ {
+		migration, ok := migrations[from]
+		if !ok {
+			return version, fmt.Errorf("no migration from state schema version %d", from)
+		}
+		logging.Logger.Info("Migrating state", "from", from, "to", from+1, "migration", migration.Description)
+		if err := migration.Migrate(document); err != nil {
+			return version, fmt.Errorf("failed to migrate state from version %d: %w", from, err)
+		}
+		document["schema_version"] = from + 1
+	}
+	return version, nil
+}
+
+// documentVersion reads schema_version, which state written before it was
+// added lacks
+func documentVersion(document StateDocument) (int, error) {
+	value, ok := document["schema_version"]
+	if !ok || value == nil {
+		return 0, nil
+	}
+	version, ok := value.(int)
+	if !ok {
+		return 0, fmt.Errorf("invalid state schema version %v", value)
+	}
+	return version, nil
+}
+
+// decodeDocument decodes a migrated document into a DeploymentState
+func decodeDocument(document StateDocument) (DeploymentState, error) {
+	var state DeploymentState
+	data, err := yaml.Marshal(document)
+	if err != nil {
+		return state, err
+	}
+	err = yaml.Unmarshal(data, &state)
+	return state, err
+}
+
+// migrateServiceNames migrates version 0, which named each compose service
+// in state after its tagged container, so that service_name is the compose
+// service name that deploys and rollbacks look services up by.
+func migrateServiceNames(document StateDocument) error {
+	compose, ok := document["compose"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	services, ok := compose["services"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, value := range services {
+		service, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := service["service_name"].(string)
+		tag, _ := service["tag"].(string)
+		if tag == "" {
+			tag, _ = document["tag"].(string)
+		}
+		if tag != "" && strings.HasSuffix(name, "-"+tag) {
+			service["service_name"] = strings.TrimSuffix(name, "-"+tag)
+		}
+	}
+	return nil
+}
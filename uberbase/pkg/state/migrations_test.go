@@ -0,0 +1,78 @@
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+// TestDecodeStateFixtures decodes state written in each schema version and
+// checks it comes out migrated to the current one.
+func TestDecodeStateFixtures(t *testing.T) {
+	for _, fixture := range []string{"testdata/v0.yml", "testdata/v1.yml"} {
+		t.Run(fixture, func(t *testing.T) {
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, err := decodeState(data, nil)
+			if err != nil {
+				t.Fatalf("failed to decode state: %v", err)
+			}
+
+			if state.SchemaVersion != StateSchemaVersion {
+				t.Errorf("schema_version = %d, want %d", state.SchemaVersion, StateSchemaVersion)
+			}
+			if state.Compose == nil || state.Compose.Services["api"] == nil {
+				t.Fatalf("state has no api service")
+			}
+			api := state.Compose.Services["api"]
+			if api.ServiceName != "api" {
+				t.Errorf("service_name = %q, want %q", api.ServiceName, "api")
+			}
+			if api.ContainerName != "api-3f2a9c1" {
+				t.Errorf("container_name = %q, want %q", api.ContainerName, "api-3f2a9c1")
+			}
+		})
+	}
+}
+
+func TestMigrateServiceNames(t *testing.T) {
+	document := StateDocument{
+		"tag": "abc123",
+		"compose": map[string]interface{}{
+			"services": map[string]interface{}{
+				// tagged with the deployment's tag
+				"web": map[string]interface{}{"service_name": "web-abc123"},
+				// tagged with its own, left by a partial deploy
+				"worker": map[string]interface{}{"service_name": "worker-def456", "tag": "def456"},
+				// already named after its compose service
+				"db": map[string]interface{}{"service_name": "db", "tag": "abc123"},
+			},
+		},
+	}
+
+	version, err := migrateState(document)
+	if err != nil {
+		t.Fatalf("failed to migrate state: %v", err)
+	}
+	if version != 0 {
+		t.Errorf("migrateState returned version %d, want 0", version)
+	}
+	if document["schema_version"] != StateSchemaVersion {
+		t.Errorf("schema_version = %v, want %d", document["schema_version"], StateSchemaVersion)
+	}
+
+	services := document["compose"].(map[string]interface{})["services"].(map[string]interface{})
+	for name := range services {
+		if got := services[name].(map[string]interface{})["service_name"]; got != name {
+			t.Errorf("service_name of %s = %v, want %s", name, got, name)
+		}
+	}
+}
+
+func TestMigrateStateNewerVersion(t *testing.T) {
+	document := StateDocument{"schema_version": StateSchemaVersion + 1}
+	if _, err := migrateState(document); err == nil {
+		t.Errorf("migrating state from a newer schema version should fail")
+	}
+}
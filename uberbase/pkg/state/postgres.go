@@ -0,0 +1,133 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultStateTable      = "uberbase_state"
+	postgresRequestTimeout = 30 * time.Second
+)
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PostgresBackend stores state in a row per host of a Postgres table, which
+// it creates on first use. Each row keeps the state it last replaced as its
+// one earlier generation.
+type PostgresBackend struct {
+	db       *sql.DB
+	table    string
+	host     string
+	location string
+
+	mu          sync.Mutex
+	initialized bool
+}
+
+// NewPostgresBackendFromURL creates a backend from a postgres:// connection
+// URL, taking the table name from its table query parameter.
+func NewPostgresBackendFromURL(u *url.URL, host string) (*PostgresBackend, error) {
+	connURL := *u
+	query := connURL.Query()
+	table := query.Get("table")
+	if table == "" {
+		table = defaultStateTable
+	}
+	query.Del("table")
+	connURL.RawQuery = query.Encode()
+
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid state table name %q", table)
+	}
+
+	db, err := sql.Open("postgres", connURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+	return &PostgresBackend{
+		db:       db,
+		table:    pq.QuoteIdentifier(table),
+		host:     host,
+		location: fmt.Sprintf("%s (%s, host %s)", connURL.Redacted(), table, host),
+	}, nil
+}
+
+func (b *PostgresBackend) Location() string {
+	return b.location
+}
+
+// init creates the table unless an earlier call has
+func (b *PostgresBackend) init(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.initialized {
+		return nil
+	}
+
+	_, err := b.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	host TEXT PRIMARY KEY,
+	state TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, b.table))
+	if err != nil {
+		return fmt.Errorf("failed to create state table: %w", err)
+	}
+	if _, err := b.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS previous_state TEXT", b.table)); err != nil {
+		return fmt.Errorf("failed to update state table: %w", err)
+	}
+	b.initialized = true
+	return nil
+}
+
+func (b *PostgresBackend) Read() ([]byte, error) {
+	return b.read("state")
+}
+
+func (b *PostgresBackend) ReadGeneration(n int) ([]byte, error) {
+	if n != 1 {
+		return nil, ErrStateNotFound
+	}
+	return b.read("previous_state")
+}
+
+func (b *PostgresBackend) read(column string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresRequestTimeout)
+	defer cancel()
+	if err := b.init(ctx); err != nil {
+		return nil, err
+	}
+
+	var data sql.NullString
+	err := b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE host = $1", column, b.table), b.host).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !data.Valid) {
+		return nil, ErrStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state row: %w", err)
+	}
+	return []byte(data.String), nil
+}
+
+func (b *PostgresBackend) Write(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresRequestTimeout)
+	defer cancel()
+	if err := b.init(ctx); err != nil {
+		return err
+	}
+
+	_, err := b.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (host, state, updated_at) VALUES ($1, $2, now())
+ON CONFLICT (host) DO UPDATE SET previous_state = %[1]s.state, state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`, b.table), b.host, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write state row: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package state
+
+import (
+	"github.com/bluetongueai/uberbase/uberbase/pkg/containers"
+	"github.com/bluetongueai/uberbase/uberbase/pkg/traefik"
+)
+
+// PreviousDeployment is the deployment replaced by the current one, kept as
+// the target for rolling back a deploy that turns out to be unhealthy.
+type PreviousDeployment struct {
+	Tag        containers.ContainerTag         `yaml:"tag"`
+	DeployedAt string                          `yaml:"deployed_at,omitempty"`
+	Services   map[string]*ComposeServiceState `yaml:"services"`
+	// Active is the configuration that routed traffic to Tag
+	Active *traefik.TraefikDynamicConfiguration `yaml:"active,omitempty"`
+}
@@ -0,0 +1,262 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const s3RequestTimeout = 30 * time.Second
+
+// S3Backend stores state as an object in an S3 compatible bucket, such as
+// the MinIO uberbase runs. Objects are addressed path-style, which MinIO and
+// AWS both accept. Uploads replace objects atomically, and the replaced
+// object is copied aside first, as <key>.1 to <key>.3 like the file backends.
+type S3Backend struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Key       string
+	AccessKey string
+	SecretKey string
+	client    *http.Client
+}
+
+// NewS3BackendFromURL creates a backend from s3://bucket/prefix, with the
+// endpoint and region given as query parameters. Credentials are read from
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or else the MinIO root user.
+func NewS3BackendFromURL(u *url.URL, host string) (*S3Backend, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("s3 state backend requires a bucket")
+	}
+
+	query := u.Query()
+	region := query.Get("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := query.Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" {
+		accessKey, secretKey = os.Getenv("UBERBASE_MINIO_ROOT_USER"), os.Getenv("UBERBASE_MINIO_ROOT_PASSWORD")
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("s3 state backend requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	return NewS3Backend(endpoint, region, u.Host, stateKey(u.Path, host), accessKey, secretKey), nil
+}
+
+func NewS3Backend(endpoint, region, bucket, key, accessKey, secretKey string) *S3Backend {
+	return &S3Backend{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		Key:       key,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: s3RequestTimeout},
+	}
+}
+
+func (b *S3Backend) Location() string {
+	return fmt.Sprintf("s3://%s/%s", b.Bucket, b.Key)
+}
+
+func (b *S3Backend) Read() ([]byte, error) {
+	return b.get(b.Key)
+}
+
+func (b *S3Backend) ReadGeneration(n int) ([]byte, error) {
+	if n < 1 || n > stateGenerations {
+		return nil, ErrStateNotFound
+	}
+	return b.get(fmt.Sprintf("%s.%d", b.Key, n))
+}
+
+func (b *S3Backend) get(key string) ([]byte, error) {
+	resp, err := b.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrStateNotFound
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state object: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read state object: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func (b *S3Backend) Write(data []byte) error {
+	// keep the states being replaced, shifting each generation down
+	for n := stateGenerations - 1; n >= 1; n-- {
+		if err := b.copy(fmt.Sprintf("%s.%d", b.Key, n), fmt.Sprintf("%s.%d", b.Key, n+1)); err != nil {
+			return err
+		}
+	}
+	if err := b.copy(b.Key, b.Key+".1"); err != nil {
+		return err
+	}
+
+	resp, err := b.do(http.MethodPut, b.Key, data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to write state object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to write state object: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// copy copies the object at src to dst, doing nothing when there is no src
+// object, as before the first writes
+func (b *S3Backend) copy(src, dst string) error {
+	copySource := map[string]string{"X-Amz-Copy-Source": "/" + s3EscapePath(b.Bucket+"/"+src)}
+	resp, err := b.do(http.MethodPut, dst, nil, copySource)
+	if err != nil {
+		return fmt.Errorf("failed to keep earlier state object: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && bytes.Contains(body, []byte("<Code>NoSuchKey</Code>")):
+		return nil
+	// copies can fail after responding 200, with the error in the body
+	case resp.StatusCode != http.StatusOK || bytes.Contains(body, []byte("<Error>")):
+		return fmt.Errorf("failed to keep earlier state object: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (b *S3Backend) do(method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+
+	objectURL := b.Endpoint + "/" + s3EscapePath(b.Bucket+"/"+key)
+	req, err := http.NewRequestWithContext(ctx, method, objectURL, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	signS3Request(req, body, b.AccessKey, b.SecretKey, b.Region, time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's context once its response is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// signS3Request signs req with AWS Signature Version 4, covering its host,
+// content type, range and x-amz-* headers
+func signS3Request(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath escapes each segment of an object path as S3 expects
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -6,6 +6,10 @@ import (
 )
 
 type TraefikState struct {
-	Tag     containers.ContainerTag
-	Configs map[string]traefik.TraefikDynamicConfiguration
+	Tag     containers.ContainerTag                        `yaml:"tag"`
+	Configs map[string]traefik.TraefikDynamicConfiguration `yaml:"configs"`
+	// Active is the merged configuration served to Traefik's HTTP provider
+	Active *traefik.TraefikDynamicConfiguration `yaml:"active,omitempty"`
+	// Next replaces Active once the sessions of the previous tag drain
+	Next *traefik.TraefikDynamicConfiguration `yaml:"next,omitempty"`
 }
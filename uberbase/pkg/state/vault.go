@@ -0,0 +1,158 @@
+package state
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const vaultRequestTimeout = 30 * time.Second
+
+// VaultKeyProvider wraps data keys with a key of Vault's transit secrets
+// engine, which never leaves Vault.
+//
+// Vault is reached at VAULT_ADDR, trusting VAULT_CACERT when set, and is
+// authenticated with VAULT_TOKEN or by logging in with the AppRole of
+// VAULT_ROLE_ID and VAULT_SECRET_ID.
+type VaultKeyProvider struct {
+	address string
+	mount   string
+	key     string
+	token   string
+	client  *http.Client
+}
+
+// NewVaultKeyProvider wraps data keys with the transit key spec, given as
+// name or mount/name
+func NewVaultKeyProvider(spec string) (*VaultKeyProvider, error) {
+	mount, key := "transit", spec
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		mount, key = spec[:i], spec[i+1:]
+	}
+	if key == "" {
+		return nil, fmt.Errorf("vault state key needs a transit key name, as vault:name")
+	}
+
+	address := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if address == "" {
+		return nil, fmt.Errorf("vault state key needs VAULT_ADDR")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile := os.Getenv("VAULT_CACERT"); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_CACERT: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in VAULT_CACERT %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &VaultKeyProvider{
+		address: address,
+		mount:   strings.Trim(mount, "/"),
+		key:     key,
+		token:   os.Getenv("VAULT_TOKEN"),
+		client:  &http.Client{Timeout: vaultRequestTimeout, Transport: transport},
+	}, nil
+}
+
+func (p *VaultKeyProvider) Name() string {
+	return "vault"
+}
+
+func (p *VaultKeyProvider) WrapKey(dataKey []byte) (string, error) {
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	request := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}
+	if err := p.call("/v1/"+p.mount+"/encrypt/"+p.key, request, &response); err != nil {
+		return "", err
+	}
+	return response.Data.Ciphertext, nil
+}
+
+func (p *VaultKeyProvider) UnwrapKey(wrapped string) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := p.call("/v1/"+p.mount+"/decrypt/"+p.key, map[string]string{"ciphertext": wrapped}, &response); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+// call posts request to path, logging in first when there is no token
+func (p *VaultKeyProvider) call(path string, request interface{}, response interface{}) error {
+	if p.token == "" {
+		if err := p.login(); err != nil {
+			return err
+		}
+	}
+	return p.post(path, request, response)
+}
+
+// login exchanges the AppRole credentials for a token
+func (p *VaultKeyProvider) login() error {
+	roleID, secretID := os.Getenv("VAULT_ROLE_ID"), os.Getenv("VAULT_SECRET_ID")
+	if roleID == "" || secretID == "" {
+		return fmt.Errorf("vault state key needs VAULT_TOKEN, or VAULT_ROLE_ID and VAULT_SECRET_ID")
+	}
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := p.post("/v1/auth/approle/login", map[string]string{"role_id": roleID, "secret_id": secretID}, &response); err != nil {
+		return fmt.Errorf("failed to log in to vault: %w", err)
+	}
+	p.token = response.Auth.ClientToken
+	return nil
+}
+
+func (p *VaultKeyProvider) post(path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.address+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("X-Vault-Token", p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault %s returned %s: %s", path, resp.Status, strings.TrimSpace(string(content)))
+	}
+	if err := json.Unmarshal(content, response); err != nil {
+		return fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	return nil
+}
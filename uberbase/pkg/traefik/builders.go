@@ -0,0 +1,145 @@
+package traefik
+
+// NewAddPrefixMiddleware returns a middleware prefixing request paths with prefix.
+func NewAddPrefixMiddleware(prefix string) TraefikMiddleware {
+	return TraefikMiddleware{AddPrefix: &TraefikMiddlewareAddPrefix{Prefix: prefix}}
+}
+
+// NewStripPrefixMiddleware returns a middleware removing prefixes from request paths.
+func NewStripPrefixMiddleware(prefixes ...string) TraefikMiddleware {
+	return TraefikMiddleware{StripPrefix: &TraefikMiddlewareStripPrefix{Prefixes: prefixes}}
+}
+
+// NewBasicAuthMiddleware returns a middleware requiring one of the given
+// htpasswd formatted users.
+func NewBasicAuthMiddleware(realm string, users ...string) TraefikMiddleware {
+	return TraefikMiddleware{BasicAuth: &TraefikMiddlewareBasicAuth{Realm: realm, Users: users}}
+}
+
+// NewHeadersMiddleware returns a middleware setting custom request and response headers.
+func NewHeadersMiddleware(requestHeaders, responseHeaders map[string]string) TraefikMiddleware {
+	return TraefikMiddleware{Headers: &TraefikMiddlewareHeaders{
+		CustomRequestHeaders:  requestHeaders,
+		CustomResponseHeaders: responseHeaders,
+	}}
+}
+
+// NewErrorsMiddleware returns a middleware serving the page at query from
+// service whenever a response status matches one of status, e.g. "500-599".
+func NewErrorsMiddleware(service, query string, status ...string) TraefikMiddleware {
+	return TraefikMiddleware{Errors: &TraefikMiddlewareErrors{
+		Status:  status,
+		Service: service,
+		Query:   query,
+	}}
+}
+
+// NewRedirectSchemeMiddleware returns a middleware redirecting requests to scheme.
+func NewRedirectSchemeMiddleware(scheme string, permanent bool) TraefikMiddleware {
+	return TraefikMiddleware{RedirectScheme: &TraefikMiddlewareRedirectScheme{
+		Scheme:    scheme,
+		Permanent: permanent,
+	}}
+}
+
+// NewRedirectRegexMiddleware returns a middleware redirecting requests whose
+// URL matches regex to replacement.
+func NewRedirectRegexMiddleware(regex, replacement string, permanent bool) TraefikMiddleware {
+	return TraefikMiddleware{RedirectRegex: &TraefikMiddlewareRedirectRegex{
+		Regex:       regex,
+		Replacement: replacement,
+		Permanent:   permanent,
+	}}
+}
+
+// NewRateLimitMiddleware returns a middleware allowing average requests per
+// second per client with bursts of up to burst requests.
+func NewRateLimitMiddleware(average, burst int64) TraefikMiddleware {
+	return TraefikMiddleware{RateLimit: &TraefikMiddlewareRateLimit{
+		Average: average,
+		Burst:   burst,
+	}}
+}
+
+// NewIPAllowListMiddleware returns a middleware only accepting clients in sourceRange.
+func NewIPAllowListMiddleware(sourceRange ...string) TraefikMiddleware {
+	return TraefikMiddleware{IPAllowList: &TraefikMiddlewareIPAllowList{SourceRange: sourceRange}}
+}
+
+// NewRetryMiddleware returns a middleware retrying failed requests attempts times.
+func NewRetryMiddleware(attempts int, initialInterval string) TraefikMiddleware {
+	return TraefikMiddleware{Retry: &TraefikMiddlewareRetry{
+		Attempts:        attempts,
+		InitialInterval: initialInterval,
+	}}
+}
+
+// NewChainMiddleware returns a middleware applying the named middlewares in order.
+func NewChainMiddleware(middlewares ...string) TraefikMiddleware {
+	return TraefikMiddleware{Chain: &TraefikMiddlewareChain{Middlewares: middlewares}}
+}
+
+// NewLoadBalancerService returns a service balancing across the given server URLs.
+func NewLoadBalancerService(urls ...string) TraefikService {
+	servers := make([]TraefikServiceLoadBalancerServer, 0, len(urls))
+	for _, url := range urls {
+		servers = append(servers, TraefikServiceLoadBalancerServer{URL: url})
+	}
+	return TraefikService{LoadBalancer: &TraefikServiceLoadBalancer{Servers: servers}}
+}
+
+// NewWeightedService returns a service splitting traffic between the named
+// services according to weights.
+func NewWeightedService(weights map[string]int) TraefikService {
+	services := make([]TraefikServiceWeightedService, 0, len(weights))
+	for _, name := range sortedKeys(weights) {
+		services = append(services, TraefikServiceWeightedService{Name: name, Weight: weights[name]})
+	}
+	return TraefikService{Weighted: &TraefikServiceWeighted{Services: services}}
+}
+
+// SetMiddleware adds or replaces the named middleware.
+func (c *TraefikHTTPConfiguration) SetMiddleware(name string, middleware TraefikMiddleware) {
+	if c.Middlewares == nil {
+		c.Middlewares = make(map[string]TraefikMiddleware)
+	}
+	c.Middlewares[name] = middleware
+}
+
+// SetService adds or replaces the named service.
+func (c *TraefikHTTPConfiguration) SetService(name string, service TraefikService) {
+	if c.Services == nil {
+		c.Services = make(map[string]TraefikService)
+	}
+	c.Services[name] = service
+}
+
+// SetRouter adds or replaces the named router.
+func (c *TraefikHTTPConfiguration) SetRouter(name string, router TraefikRouter) {
+	if c.Routers == nil {
+		c.Routers = make(map[string]TraefikRouter)
+	}
+	c.Routers[name] = router
+}
+
+// UseMiddleware prepends the named middleware to the router's middlewares so
+// it runs before any the router already had. It is a no-op if already present.
+func (r *TraefikRouter) UseMiddleware(name string) {
+	for _, middleware := range r.Middlewares {
+		if middleware == name {
+			return
+		}
+	}
+	r.Middlewares = append([]string{name}, r.Middlewares...)
+}
+
+// RemoveMiddleware removes the named middleware from the router.
+func (r *TraefikRouter) RemoveMiddleware(name string) {
+	middlewares := make([]string, 0, len(r.Middlewares))
+	for _, middleware := range r.Middlewares {
+		if middleware != name {
+			middlewares = append(middlewares, middleware)
+		}
+	}
+	r.Middlewares = middlewares
+}
@@ -0,0 +1,127 @@
+package traefik
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bluetongueai/uberbase/uberbase/pkg/utils"
+)
+
+const (
+	// DefaultTLSStore is the store Traefik serves certificates from unless a
+	// router's TLS options say otherwise
+	DefaultTLSStore = "default"
+)
+
+var (
+	hostMatcher = regexp.MustCompile(`\bHost\(([^)]*)\)`)
+	ruleString  = regexp.MustCompile("[`\"]([^`\"]+)[`\"]")
+)
+
+// TraefikTLSPolicy chooses the certificate resolver for the domains served by
+// HTTP routers.
+type TraefikTLSPolicy struct {
+	// Resolvers maps a domain, or a wildcard such as *.example.com, to the
+	// certificate resolver issuing its certificates.
+	Resolvers map[string]string
+	// DefaultResolver is used for domains without a resolver of their own.
+	// Routers for such domains are left without TLS when it is empty.
+	DefaultResolver string
+	// EntryPoints limits the policy to routers on these entrypoints, so that
+	// routers on plain HTTP entrypoints are left alone. Empty means all.
+	EntryPoints []string
+}
+
+// ResolverFor returns the certificate resolver for domain, preferring an
+// exact match over a wildcard and a wildcard over the default.
+func (p TraefikTLSPolicy) ResolverFor(domain string) string {
+	if resolver, ok := p.Resolvers[domain]; ok {
+		return resolver
+	}
+	if _, parent, ok := strings.Cut(domain, "."); ok {
+		if resolver, ok := p.Resolvers["*."+parent]; ok {
+			return resolver
+		}
+	}
+	return p.DefaultResolver
+}
+
+// ApplyTLSPolicy sets the TLS settings of every router that serves a domain
+// with a certificate resolver. Routers with TLS settings of their own are
+// left untouched.
+func (c *TraefikHTTPConfiguration) ApplyTLSPolicy(policy TraefikTLSPolicy) {
+	for name, router := range c.Routers {
+		if router.TLS != nil || !policy.appliesTo(router) {
+			continue
+		}
+
+		resolver := ""
+		domains := []TraefikRouterTLSDomain{}
+		for _, domain := range RuleDomains(router.Rule) {
+			domainResolver := policy.ResolverFor(domain)
+			if domainResolver == "" {
+				continue
+			}
+			// a router has a single resolver, the first domain chooses it
+			if resolver == "" {
+				resolver = domainResolver
+			}
+			if domainResolver == resolver {
+				domains = append(domains, TraefikRouterTLSDomain{Main: domain})
+			}
+		}
+		if resolver == "" {
+			continue
+		}
+
+		router.TLS = &TraefikRouterTLS{CertResolver: resolver, Domains: domains}
+		c.Routers[name] = router
+	}
+}
+
+func (p TraefikTLSPolicy) appliesTo(router TraefikRouter) bool {
+	if len(p.EntryPoints) == 0 {
+		return true
+	}
+	for _, entryPoint := range router.EntryPoints {
+		if utils.Contains(p.EntryPoints, entryPoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleDomains returns the domains matched by the Host matchers of a router
+// rule. HostRegexp matchers are not domains and are skipped.
+func RuleDomains(rule string) []string {
+	domains := []string{}
+	for _, match := range hostMatcher.FindAllStringSubmatch(rule, -1) {
+		for _, domain := range ruleString.FindAllStringSubmatch(match[1], -1) {
+			if !utils.Contains(domains, domain[1]) {
+				domains = append(domains, domain[1])
+			}
+		}
+	}
+	return domains
+}
+
+// NewCertificateStoreConfig returns a dynamic configuration loading
+// certificates into the default store, with the first one served to clients
+// that do not match any of them.
+func NewCertificateStoreConfig(certificates ...TraefikCertificate) *TraefikDynamicConfiguration {
+	tls := &TraefikTLSConfiguration{}
+	for _, certificate := range certificates {
+		tls.Certificates = append(tls.Certificates, TraefikTLSCertificate{
+			CertFile: certificate.CertFile,
+			KeyFile:  certificate.KeyFile,
+			Stores:   []string{DefaultTLSStore},
+		})
+	}
+	if len(certificates) > 0 {
+		defaultCertificate := certificates[0]
+		tls.Stores = map[string]TraefikTLSStore{
+			DefaultTLSStore: {DefaultCertificate: &defaultCertificate},
+		}
+	}
+	return &TraefikDynamicConfiguration{TLS: tls}
+}
@@ -1,10 +1,13 @@
 package traefik
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 const (
@@ -28,22 +31,33 @@ func LoadTraefikStaticConfig() (*TraefikStaticConfiguration, error) {
 }
 
 func LoadTraefikDynamicConfigs() (map[string]*TraefikDynamicConfiguration, error) {
+	return LoadTraefikDynamicConfigsFrom(DynamicConfigPath)
+}
+
+// LoadTraefikDynamicConfigsFrom loads every YAML file in dir, keyed by file name.
+func LoadTraefikDynamicConfigsFrom(dir string) (map[string]*TraefikDynamicConfiguration, error) {
 	configs := make(map[string]*TraefikDynamicConfiguration)
 
-	files, err := os.ReadDir(DynamicConfigPath)
+	files, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, file := range files {
-		content, err := os.ReadFile(filepath.Join(DynamicConfigPath, file.Name()))
+		if file.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(file.Name()); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
 		if err != nil {
 			return nil, err
 		}
 		var config TraefikDynamicConfiguration
 		err = yaml.Unmarshal(content, &config)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Name(), err)
 		}
 		configs[file.Name()] = &config
 	}
@@ -66,3 +80,18 @@ func (c *TraefikDynamicConfiguration) WriteToFile(dir, name string) error {
 	}
 	return os.WriteFile(filepath.Join(dir, name), content, 0644)
 }
+
+// MarshalJSON renders the configuration as JSON, the format polled by
+// Traefik's HTTP provider. The yaml tags are the single source of field
+// names, so the configuration is rendered to YAML first and re-encoded.
+func (c *TraefikDynamicConfiguration) MarshalJSON() ([]byte, error) {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	document := make(map[string]interface{})
+	if err := yamlv3.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
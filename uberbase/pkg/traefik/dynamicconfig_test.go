@@ -0,0 +1,65 @@
+package traefik
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestDynamicConfigRoundTrip decodes the reference dynamic configuration into
+// the named types and checks that encoding them again drops no options.
+func TestDynamicConfigRoundTrip(t *testing.T) {
+	content, err := os.ReadFile("testdata/dynamic.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config TraefikDynamicConfiguration
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		t.Fatalf("failed to decode reference config: %v", err)
+	}
+	encoded, err := yaml.Marshal(&config)
+	if err != nil {
+		t.Fatalf("failed to encode config: %v", err)
+	}
+
+	var want, got map[string]interface{}
+	if err := yaml.Unmarshal(content, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip changed the config:\n%s", encoded)
+	}
+}
+
+func TestDynamicConfigCopy(t *testing.T) {
+	content, err := os.ReadFile("testdata/dynamic.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config TraefikDynamicConfiguration
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	copy, err := config.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if !reflect.DeepEqual(&config, copy) {
+		t.Fatalf("copy differs from the original")
+	}
+
+	router := copy.HTTP.Routers["Router0"]
+	router.Service = "changed"
+	copy.HTTP.Routers["Router0"] = router
+	copy.HTTP.Services["Service02"].LoadBalancer.Servers[0].URL = "changed"
+	if config.HTTP.Routers["Router0"].Service == "changed" || config.HTTP.Services["Service02"].LoadBalancer.Servers[0].URL == "changed" {
+		t.Errorf("changing the copy changed the original")
+	}
+}
@@ -0,0 +1,92 @@
+package traefik
+
+import "fmt"
+
+// MergeDynamicConfigs combines the given configurations into a single
+// configuration, as Traefik's file provider does for a directory. Configs are
+// merged in file name order and any name defined twice is reported as an error.
+func MergeDynamicConfigs(configs map[string]*TraefikDynamicConfiguration) (*TraefikDynamicConfiguration, error) {
+	merged := &TraefikDynamicConfiguration{}
+	for _, name := range sortedKeys(configs) {
+		config := configs[name]
+		if config == nil {
+			continue
+		}
+
+		if config.HTTP != nil {
+			if merged.HTTP == nil {
+				merged.HTTP = &TraefikHTTPConfiguration{}
+			}
+			if err := mergeMap(&merged.HTTP.Routers, config.HTTP.Routers, "http router", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.HTTP.Services, config.HTTP.Services, "http service", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.HTTP.Middlewares, config.HTTP.Middlewares, "http middleware", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.HTTP.ServersTransports, config.HTTP.ServersTransports, "http servers transport", name); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.TCP != nil {
+			if merged.TCP == nil {
+				merged.TCP = &TraefikTCPConfiguration{}
+			}
+			if err := mergeMap(&merged.TCP.Routers, config.TCP.Routers, "tcp router", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.TCP.Services, config.TCP.Services, "tcp service", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.TCP.Middlewares, config.TCP.Middlewares, "tcp middleware", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.TCP.ServersTransports, config.TCP.ServersTransports, "tcp servers transport", name); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.UDP != nil {
+			if merged.UDP == nil {
+				merged.UDP = &TraefikUDPConfiguration{}
+			}
+			if err := mergeMap(&merged.UDP.Routers, config.UDP.Routers, "udp router", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.UDP.Services, config.UDP.Services, "udp service", name); err != nil {
+				return nil, err
+			}
+		}
+
+		if config.TLS != nil {
+			if merged.TLS == nil {
+				merged.TLS = &TraefikTLSConfiguration{}
+			}
+			merged.TLS.Certificates = append(merged.TLS.Certificates, config.TLS.Certificates...)
+			if err := mergeMap(&merged.TLS.Options, config.TLS.Options, "tls options", name); err != nil {
+				return nil, err
+			}
+			if err := mergeMap(&merged.TLS.Stores, config.TLS.Stores, "tls store", name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+func mergeMap[V any](dst *map[string]V, src map[string]V, kind, source string) error {
+	for key, value := range src {
+		if *dst == nil {
+			*dst = make(map[string]V)
+		}
+		if _, exists := (*dst)[key]; exists {
+			return fmt.Errorf("%s %q in %s is already defined", kind, key, source)
+		}
+		(*dst)[key] = value
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package traefik
+
+type TraefikMiddlewareAddPrefix struct {
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
+type TraefikMiddlewareBasicAuth struct {
+	Users        []string `yaml:"users,omitempty"`
+	UsersFile    string   `yaml:"usersFile,omitempty"`
+	Realm        string   `yaml:"realm,omitempty"`
+	RemoveHeader bool     `yaml:"removeHeader,omitempty"`
+	HeaderField  string   `yaml:"headerField,omitempty"`
+}
+
+type TraefikMiddlewareBuffering struct {
+	MaxRequestBodyBytes  int64  `yaml:"maxRequestBodyBytes,omitempty"`
+	MemRequestBodyBytes  int64  `yaml:"memRequestBodyBytes,omitempty"`
+	MaxResponseBodyBytes int64  `yaml:"maxResponseBodyBytes,omitempty"`
+	MemResponseBodyBytes int64  `yaml:"memResponseBodyBytes,omitempty"`
+	RetryExpression      string `yaml:"retryExpression,omitempty"`
+}
+
+type TraefikMiddlewareChain struct {
+	Middlewares []string `yaml:"middlewares,omitempty"`
+}
+
+type TraefikMiddlewareCircuitBreaker struct {
+	Expression       string `yaml:"expression,omitempty"`
+	CheckPeriod      string `yaml:"checkPeriod,omitempty"`
+	FallbackDuration string `yaml:"fallbackDuration,omitempty"`
+	RecoveryDuration string `yaml:"recoveryDuration,omitempty"`
+	ResponseCode     int    `yaml:"responseCode,omitempty"`
+}
+
+type TraefikMiddlewareCompress struct {
+	ExcludedContentTypes []string `yaml:"excludedContentTypes,omitempty"`
+	IncludedContentTypes []string `yaml:"includedContentTypes,omitempty"`
+	MinResponseBodyBytes int      `yaml:"minResponseBodyBytes,omitempty"`
+	Encodings            []string `yaml:"encodings,omitempty"`
+	DefaultEncoding      string   `yaml:"defaultEncoding,omitempty"`
+}
+
+type TraefikMiddlewareContentType struct {
+	AutoDetect *bool `yaml:"autoDetect,omitempty"`
+}
+
+type TraefikMiddlewareDigestAuth struct {
+	Users        []string `yaml:"users,omitempty"`
+	UsersFile    string   `yaml:"usersFile,omitempty"`
+	RemoveHeader bool     `yaml:"removeHeader,omitempty"`
+	Realm        string   `yaml:"realm,omitempty"`
+	HeaderField  string   `yaml:"headerField,omitempty"`
+}
+
+type TraefikMiddlewareErrors struct {
+	Status         []string       `yaml:"status,omitempty"`
+	StatusRewrites map[string]int `yaml:"statusRewrites,omitempty"`
+	Service        string         `yaml:"service,omitempty"`
+	Query          string         `yaml:"query,omitempty"`
+}
+
+type TraefikClientTLS struct {
+	CA                 string `yaml:"ca,omitempty"`
+	Cert               string `yaml:"cert,omitempty"`
+	Key                string `yaml:"key,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+	CAOptional         *bool  `yaml:"caOptional,omitempty"`
+}
+
+type TraefikMiddlewareForwardAuth struct {
+	Address                  string            `yaml:"address,omitempty"`
+	TLS                      *TraefikClientTLS `yaml:"tls,omitempty"`
+	TrustForwardHeader       bool              `yaml:"trustForwardHeader,omitempty"`
+	AuthResponseHeaders      []string          `yaml:"authResponseHeaders,omitempty"`
+	AuthResponseHeadersRegex string            `yaml:"authResponseHeadersRegex,omitempty"`
+	AuthRequestHeaders       []string          `yaml:"authRequestHeaders,omitempty"`
+	AddAuthCookiesToResponse []string          `yaml:"addAuthCookiesToResponse,omitempty"`
+	HeaderField              string            `yaml:"headerField,omitempty"`
+	ForwardBody              bool              `yaml:"forwardBody,omitempty"`
+	MaxBodySize              *int64            `yaml:"maxBodySize,omitempty"`
+	PreserveLocationHeader   bool              `yaml:"preserveLocationHeader,omitempty"`
+	PreserveRequestMethod    bool              `yaml:"preserveRequestMethod,omitempty"`
+}
+
+type TraefikMiddlewareGrpcWeb struct {
+	AllowOrigins []string `yaml:"allowOrigins,omitempty"`
+}
+
+type TraefikMiddlewareHeaders struct {
+	CustomRequestHeaders              map[string]string `yaml:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders             map[string]string `yaml:"customResponseHeaders,omitempty"`
+	AccessControlAllowCredentials     bool              `yaml:"accessControlAllowCredentials,omitempty"`
+	AccessControlAllowHeaders         []string          `yaml:"accessControlAllowHeaders,omitempty"`
+	AccessControlAllowMethods         []string          `yaml:"accessControlAllowMethods,omitempty"`
+	AccessControlAllowOriginList      []string          `yaml:"accessControlAllowOriginList,omitempty"`
+	AccessControlAllowOriginListRegex []string          `yaml:"accessControlAllowOriginListRegex,omitempty"`
+	AccessControlExposeHeaders        []string          `yaml:"accessControlExposeHeaders,omitempty"`
+	AccessControlMaxAge               int64             `yaml:"accessControlMaxAge,omitempty"`
+	AddVaryHeader                     bool              `yaml:"addVaryHeader,omitempty"`
+	AllowedHosts                      []string          `yaml:"allowedHosts,omitempty"`
+	HostsProxyHeaders                 []string          `yaml:"hostsProxyHeaders,omitempty"`
+	SSLProxyHeaders                   map[string]string `yaml:"sslProxyHeaders,omitempty"`
+	STSSeconds                        int64             `yaml:"stsSeconds,omitempty"`
+	STSIncludeSubdomains              bool              `yaml:"stsIncludeSubdomains,omitempty"`
+	STSPreload                        bool              `yaml:"stsPreload,omitempty"`
+	ForceSTSHeader                    bool              `yaml:"forceSTSHeader,omitempty"`
+	FrameDeny                         bool              `yaml:"frameDeny,omitempty"`
+	CustomFrameOptionsValue           string            `yaml:"customFrameOptionsValue,omitempty"`
+	ContentTypeNosniff                bool              `yaml:"contentTypeNosniff,omitempty"`
+	BrowserXSSFilter                  bool              `yaml:"browserXssFilter,omitempty"`
+	CustomBrowserXSSValue             string            `yaml:"customBrowserXSSValue,omitempty"`
+	ContentSecurityPolicy             string            `yaml:"contentSecurityPolicy,omitempty"`
+	ContentSecurityPolicyReportOnly   string            `yaml:"contentSecurityPolicyReportOnly,omitempty"`
+	PublicKey                         string            `yaml:"publicKey,omitempty"`
+	ReferrerPolicy                    string            `yaml:"referrerPolicy,omitempty"`
+	PermissionsPolicy                 string            `yaml:"permissionsPolicy,omitempty"`
+	IsDevelopment                     bool              `yaml:"isDevelopment,omitempty"`
+	FeaturePolicy                     string            `yaml:"featurePolicy,omitempty"`
+	SSLRedirect                       bool              `yaml:"sslRedirect,omitempty"`
+	SSLTemporaryRedirect              bool              `yaml:"sslTemporaryRedirect,omitempty"`
+	SSLHost                           string            `yaml:"sslHost,omitempty"`
+	SSLForceHost                      bool              `yaml:"sslForceHost,omitempty"`
+}
+
+type TraefikIPStrategy struct {
+	Depth       int      `yaml:"depth,omitempty"`
+	ExcludedIPs []string `yaml:"excludedIPs,omitempty"`
+	IPv6Subnet  *int     `yaml:"ipv6Subnet,omitempty"`
+}
+
+type TraefikMiddlewareIPAllowList struct {
+	SourceRange      []string           `yaml:"sourceRange,omitempty"`
+	IPStrategy       *TraefikIPStrategy `yaml:"ipStrategy,omitempty"`
+	RejectStatusCode int                `yaml:"rejectStatusCode,omitempty"`
+}
+
+// TraefikMiddlewareIPWhiteList is the deprecated predecessor of IPAllowList.
+type TraefikMiddlewareIPWhiteList struct {
+	SourceRange []string           `yaml:"sourceRange,omitempty"`
+	IPStrategy  *TraefikIPStrategy `yaml:"ipStrategy,omitempty"`
+}
+
+type TraefikSourceCriterion struct {
+	IPStrategy        *TraefikIPStrategy `yaml:"ipStrategy,omitempty"`
+	RequestHeaderName string             `yaml:"requestHeaderName,omitempty"`
+	RequestHost       bool               `yaml:"requestHost,omitempty"`
+}
+
+type TraefikMiddlewareInFlightReq struct {
+	Amount          int64                   `yaml:"amount,omitempty"`
+	SourceCriterion *TraefikSourceCriterion `yaml:"sourceCriterion,omitempty"`
+}
+
+type TraefikMiddlewarePassTLSClientCertSubject struct {
+	Country            bool `yaml:"country,omitempty"`
+	Province           bool `yaml:"province,omitempty"`
+	Locality           bool `yaml:"locality,omitempty"`
+	Organization       bool `yaml:"organization,omitempty"`
+	OrganizationalUnit bool `yaml:"organizationalUnit,omitempty"`
+	CommonName         bool `yaml:"commonName,omitempty"`
+	SerialNumber       bool `yaml:"serialNumber,omitempty"`
+	DomainComponent    bool `yaml:"domainComponent,omitempty"`
+}
+
+type TraefikMiddlewarePassTLSClientCertIssuer struct {
+	Country         bool `yaml:"country,omitempty"`
+	Province        bool `yaml:"province,omitempty"`
+	Locality        bool `yaml:"locality,omitempty"`
+	Organization    bool `yaml:"organization,omitempty"`
+	CommonName      bool `yaml:"commonName,omitempty"`
+	SerialNumber    bool `yaml:"serialNumber,omitempty"`
+	DomainComponent bool `yaml:"domainComponent,omitempty"`
+}
+
+type TraefikMiddlewarePassTLSClientCertInfo struct {
+	NotAfter     bool                                       `yaml:"notAfter,omitempty"`
+	NotBefore    bool                                       `yaml:"notBefore,omitempty"`
+	Sans         bool                                       `yaml:"sans,omitempty"`
+	SerialNumber bool                                       `yaml:"serialNumber,omitempty"`
+	Subject      *TraefikMiddlewarePassTLSClientCertSubject `yaml:"subject,omitempty"`
+	Issuer       *TraefikMiddlewarePassTLSClientCertIssuer  `yaml:"issuer,omitempty"`
+}
+
+type TraefikMiddlewarePassTLSClientCert struct {
+	PEM  bool                                    `yaml:"pem,omitempty"`
+	Info *TraefikMiddlewarePassTLSClientCertInfo `yaml:"info,omitempty"`
+}
+
+type TraefikMiddlewareRateLimit struct {
+	Average         int64                   `yaml:"average,omitempty"`
+	Period          string                  `yaml:"period,omitempty"`
+	Burst           int64                   `yaml:"burst,omitempty"`
+	SourceCriterion *TraefikSourceCriterion `yaml:"sourceCriterion,omitempty"`
+}
+
+type TraefikMiddlewareRedirectRegex struct {
+	Regex       string `yaml:"regex,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+	Permanent   bool   `yaml:"permanent,omitempty"`
+}
+
+type TraefikMiddlewareRedirectScheme struct {
+	Scheme    string `yaml:"scheme,omitempty"`
+	Port      string `yaml:"port,omitempty"`
+	Permanent bool   `yaml:"permanent,omitempty"`
+}
+
+type TraefikMiddlewareReplacePath struct {
+	Path string `yaml:"path,omitempty"`
+}
+
+type TraefikMiddlewareReplacePathRegex struct {
+	Regex       string `yaml:"regex,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+}
+
+type TraefikMiddlewareRetry struct {
+	Attempts        int    `yaml:"attempts,omitempty"`
+	InitialInterval string `yaml:"initialInterval,omitempty"`
+}
+
+type TraefikMiddlewareStripPrefix struct {
+	Prefixes   []string `yaml:"prefixes,omitempty"`
+	ForceSlash *bool    `yaml:"forceSlash,omitempty"`
+}
+
+type TraefikMiddlewareStripPrefixRegex struct {
+	Regex []string `yaml:"regex,omitempty"`
+}
+
+// TraefikMiddleware is an HTTP middleware; exactly one field should be set.
+type TraefikMiddleware struct {
+	AddPrefix         *TraefikMiddlewareAddPrefix         `yaml:"addPrefix,omitempty"`
+	BasicAuth         *TraefikMiddlewareBasicAuth         `yaml:"basicAuth,omitempty"`
+	Buffering         *TraefikMiddlewareBuffering         `yaml:"buffering,omitempty"`
+	Chain             *TraefikMiddlewareChain             `yaml:"chain,omitempty"`
+	CircuitBreaker    *TraefikMiddlewareCircuitBreaker    `yaml:"circuitBreaker,omitempty"`
+	Compress          *TraefikMiddlewareCompress          `yaml:"compress,omitempty"`
+	ContentType       *TraefikMiddlewareContentType       `yaml:"contentType,omitempty"`
+	DigestAuth        *TraefikMiddlewareDigestAuth        `yaml:"digestAuth,omitempty"`
+	Errors            *TraefikMiddlewareErrors            `yaml:"errors,omitempty"`
+	ForwardAuth       *TraefikMiddlewareForwardAuth       `yaml:"forwardAuth,omitempty"`
+	GrpcWeb           *TraefikMiddlewareGrpcWeb           `yaml:"grpcWeb,omitempty"`
+	Headers           *TraefikMiddlewareHeaders           `yaml:"headers,omitempty"`
+	IPAllowList       *TraefikMiddlewareIPAllowList       `yaml:"ipAllowList,omitempty"`
+	IPWhiteList       *TraefikMiddlewareIPWhiteList       `yaml:"ipWhiteList,omitempty"`
+	InFlightReq       *TraefikMiddlewareInFlightReq       `yaml:"inFlightReq,omitempty"`
+	PassTLSClientCert *TraefikMiddlewarePassTLSClientCert `yaml:"passTLSClientCert,omitempty"`
+	Plugin            map[string]map[string]interface{}   `yaml:"plugin,omitempty"`
+	RateLimit         *TraefikMiddlewareRateLimit         `yaml:"rateLimit,omitempty"`
+	RedirectRegex     *TraefikMiddlewareRedirectRegex     `yaml:"redirectRegex,omitempty"`
+	RedirectScheme    *TraefikMiddlewareRedirectScheme    `yaml:"redirectScheme,omitempty"`
+	ReplacePath       *TraefikMiddlewareReplacePath       `yaml:"replacePath,omitempty"`
+	ReplacePathRegex  *TraefikMiddlewareReplacePathRegex  `yaml:"replacePathRegex,omitempty"`
+	Retry             *TraefikMiddlewareRetry             `yaml:"retry,omitempty"`
+	StripPrefix       *TraefikMiddlewareStripPrefix       `yaml:"stripPrefix,omitempty"`
+	StripPrefixRegex  *TraefikMiddlewareStripPrefixRegex  `yaml:"stripPrefixRegex,omitempty"`
+}
+
+type TraefikTCPMiddlewareIPAllowList struct {
+	SourceRange []string `yaml:"sourceRange,omitempty"`
+}
+
+// TraefikTCPMiddlewareIPWhiteList is the deprecated predecessor of IPAllowList.
+type TraefikTCPMiddlewareIPWhiteList struct {
+	SourceRange []string `yaml:"sourceRange,omitempty"`
+}
+
+type TraefikTCPMiddlewareInFlightConn struct {
+	Amount int64 `yaml:"amount,omitempty"`
+}
+
+// TraefikTCPMiddleware is a TCP middleware; exactly one field should be set.
+type TraefikTCPMiddleware struct {
+	IPAllowList  *TraefikTCPMiddlewareIPAllowList  `yaml:"ipAllowList,omitempty"`
+	IPWhiteList  *TraefikTCPMiddlewareIPWhiteList  `yaml:"ipWhiteList,omitempty"`
+	InFlightConn *TraefikTCPMiddlewareInFlightConn `yaml:"inFlightConn,omitempty"`
+}
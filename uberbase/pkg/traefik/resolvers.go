@@ -0,0 +1,78 @@
+package traefik
+
+type TraefikACMEEAB struct {
+	Kid         string `yaml:"kid,omitempty"`
+	HmacEncoded string `yaml:"hmacEncoded,omitempty"`
+}
+
+type TraefikACMEDNSPropagation struct {
+	DisableChecks     bool   `yaml:"disableChecks,omitempty"`
+	DisableANSChecks  bool   `yaml:"disableANSChecks,omitempty"`
+	RequireAllRNS     bool   `yaml:"requireAllRNS,omitempty"`
+	DelayBeforeChecks string `yaml:"delayBeforeChecks,omitempty"`
+}
+
+type TraefikACMEDNSChallenge struct {
+	Provider                string                     `yaml:"provider,omitempty"`
+	Resolvers               []string                   `yaml:"resolvers,omitempty"`
+	Propagation             *TraefikACMEDNSPropagation `yaml:"propagation,omitempty"`
+	DelayBeforeCheck        string                     `yaml:"delayBeforeCheck,omitempty"`
+	DisablePropagationCheck bool                       `yaml:"disablePropagationCheck,omitempty"`
+}
+
+type TraefikACMEHTTPChallenge struct {
+	EntryPoint string `yaml:"entryPoint,omitempty"`
+}
+
+type TraefikACMETLSChallenge struct{}
+
+type TraefikACMEResolver struct {
+	Email                string                    `yaml:"email,omitempty"`
+	CaServer             string                    `yaml:"caServer,omitempty"`
+	PreferredChain       string                    `yaml:"preferredChain,omitempty"`
+	Storage              string                    `yaml:"storage,omitempty"`
+	KeyType              string                    `yaml:"keyType,omitempty"`
+	Eab                  *TraefikACMEEAB           `yaml:"eab,omitempty"`
+	CertificatesDuration int                       `yaml:"certificatesDuration,omitempty"`
+	CaCertificates       []string                  `yaml:"caCertificates,omitempty"`
+	CaSystemCertPool     bool                      `yaml:"caSystemCertPool,omitempty"`
+	CaServerName         string                    `yaml:"caServerName,omitempty"`
+	DNSChallenge         *TraefikACMEDNSChallenge  `yaml:"dnsChallenge,omitempty"`
+	HTTPChallenge        *TraefikACMEHTTPChallenge `yaml:"httpChallenge,omitempty"`
+	TLSChallenge         *TraefikACMETLSChallenge  `yaml:"tlsChallenge,omitempty"`
+}
+
+type TraefikTailscaleResolver struct{}
+
+type TraefikCertificatesResolver struct {
+	Acme      *TraefikACMEResolver      `yaml:"acme,omitempty"`
+	Tailscale *TraefikTailscaleResolver `yaml:"tailscale,omitempty"`
+}
+
+// NewACMEResolver returns a resolver issuing certificates from Let's Encrypt
+// with the TLS-ALPN challenge, storing them in storage.
+func NewACMEResolver(email, storage string) TraefikCertificatesResolver {
+	return TraefikCertificatesResolver{Acme: &TraefikACMEResolver{
+		Email:        email,
+		Storage:      storage,
+		TLSChallenge: &TraefikACMETLSChallenge{},
+	}}
+}
+
+// NewACMEHTTPResolver returns a resolver issuing certificates from Let's
+// Encrypt with the HTTP challenge answered on entryPoint.
+func NewACMEHTTPResolver(email, storage, entryPoint string) TraefikCertificatesResolver {
+	return TraefikCertificatesResolver{Acme: &TraefikACMEResolver{
+		Email:         email,
+		Storage:       storage,
+		HTTPChallenge: &TraefikACMEHTTPChallenge{EntryPoint: entryPoint},
+	}}
+}
+
+// SetCertificatesResolver adds or replaces the named certificate resolver.
+func (c *TraefikStaticConfiguration) SetCertificatesResolver(name string, resolver TraefikCertificatesResolver) {
+	if c.CertificatesResolvers == nil {
+		c.CertificatesResolvers = make(map[string]TraefikCertificatesResolver)
+	}
+	c.CertificatesResolvers[name] = resolver
+}
@@ -0,0 +1,243 @@
+package traefik
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HTTPEntryPoint    = "web"
+	HTTPSEntryPoint   = "websecure"
+	MetricsEntryPoint = "metrics"
+	DefaultResolver   = "letsencryptresolver"
+)
+
+// TraefikStaticSettings are the uberbase settings the Traefik static
+// configuration is generated from.
+type TraefikStaticSettings struct {
+	HTTPPort  int
+	HTTPSPort int
+	// RedirectToHTTPS redirects the HTTP entrypoint to the HTTPS entrypoint
+	RedirectToHTTPS bool
+
+	// DynamicConfigDir is watched by the file provider when there is no
+	// HTTP provider. With one, uberbase serve serves the directory through
+	// it: deployed configs tagged, and the others, such as the platform
+	// routes and issued certificates, as they are.
+	DynamicConfigDir string
+	// ProviderEndpoint is polled by the HTTP provider for the active
+	// deployment's routing, no HTTP provider is configured when empty
+	ProviderEndpoint     string
+	ProviderPollInterval time.Duration
+
+	// Dashboard enables the API and dashboard on the traefik entrypoint
+	Dashboard bool
+	// MetricsPort serves Prometheus metrics on their own entrypoint, metrics
+	// are disabled when zero
+	MetricsPort int
+	// AccessLog enables access logs, written to stdout unless AccessLogPath is set
+	AccessLog     bool
+	AccessLogPath string
+	LogLevel      string
+
+	// ACMEEmail enables the Let's Encrypt resolver used by the HTTPS entrypoint
+	ACMEEmail   string
+	ACMEStorage string
+	// ACMEHTTPChallenge answers challenges on the HTTP entrypoint instead of
+	// with TLS-ALPN, for hosts whose HTTPS port Let's Encrypt cannot reach
+	ACMEHTTPChallenge bool
+
+	// RootCAs are trusted when Traefik connects to services over TLS
+	RootCAs []string
+}
+
+// DefaultTraefikStaticSettings returns the settings uberbase runs Traefik with.
+func DefaultTraefikStaticSettings() TraefikStaticSettings {
+	return TraefikStaticSettings{
+		HTTPPort:             80,
+		HTTPSPort:            443,
+		RedirectToHTTPS:      true,
+		DynamicConfigDir:     DynamicConfigPath + "/",
+		ProviderPollInterval: 5 * time.Second,
+		MetricsPort:          8082,
+		AccessLog:            true,
+		LogLevel:             "INFO",
+		ACMEStorage:          "/data/acme.json",
+		RootCAs:              []string{"/uberbase/certs/ca.crt"},
+	}
+}
+
+// NewTraefikStaticConfiguration builds the static configuration for settings.
+func NewTraefikStaticConfiguration(settings TraefikStaticSettings) *TraefikStaticConfiguration {
+	config := &TraefikStaticConfiguration{
+		Log: &TraefikLog{Level: settings.LogLevel},
+		EntryPoints: map[string]TraefikEntryPoint{
+			HTTPEntryPoint:  {Address: fmt.Sprintf(":%d", settings.HTTPPort)},
+			HTTPSEntryPoint: {Address: fmt.Sprintf(":%d", settings.HTTPSPort)},
+		},
+		Providers: &TraefikProviders{},
+	}
+
+	if settings.RedirectToHTTPS {
+		config.EntryPoints[HTTPEntryPoint] = TraefikEntryPoint{
+			Address: fmt.Sprintf(":%d", settings.HTTPPort),
+			HTTP: &TraefikEntryPointHTTP{Redirections: &TraefikEntryPointHTTPRedirections{
+				EntryPoint: &TraefikEntryPointHTTPRedirectionsEntryPoint{To: HTTPSEntryPoint, Scheme: "https"},
+			}},
+		}
+	}
+
+	// the file provider would serve the untagged routers a second time,
+	// pointing at services no deploy creates
+	if settings.DynamicConfigDir != "" && settings.ProviderEndpoint == "" {
+		watch := true
+		config.Providers.File = &TraefikProvidersFile{Directory: settings.DynamicConfigDir, Watch: &watch}
+	}
+	if settings.ProviderEndpoint != "" {
+		config.Providers.HTTP = &TraefikProvidersHTTP{
+			Endpoint:     settings.ProviderEndpoint,
+			PollInterval: settings.ProviderPollInterval.String(),
+		}
+	}
+
+	if settings.Dashboard {
+		dashboard := true
+		config.API = &TraefikAPI{Dashboard: &dashboard}
+	}
+
+	if settings.MetricsPort > 0 {
+		config.EntryPoints[MetricsEntryPoint] = TraefikEntryPoint{Address: fmt.Sprintf(":%d", settings.MetricsPort)}
+		servicesLabels := true
+		config.Metrics = &TraefikMetrics{Prometheus: &TraefikMetricsPrometheus{
+			EntryPoint:        MetricsEntryPoint,
+			AddServicesLabels: &servicesLabels,
+		}}
+	}
+
+	if settings.AccessLog {
+		config.AccessLog = &TraefikAccessLog{FilePath: settings.AccessLogPath}
+	}
+
+	if settings.ACMEEmail != "" {
+		resolver := NewACMEResolver(settings.ACMEEmail, settings.ACMEStorage)
+		if settings.ACMEHTTPChallenge {
+			resolver = NewACMEHTTPResolver(settings.ACMEEmail, settings.ACMEStorage, HTTPEntryPoint)
+		}
+		config.SetCertificatesResolver(DefaultResolver, resolver)
+		https := config.EntryPoints[HTTPSEntryPoint]
+		https.HTTP = &TraefikEntryPointHTTP{TLS: &TraefikEntryPointHTTPTLS{CertResolver: DefaultResolver}}
+		config.EntryPoints[HTTPSEntryPoint] = https
+	}
+
+	if len(settings.RootCAs) > 0 {
+		config.ServersTransport = &TraefikStaticServersTransport{RootCAs: settings.RootCAs}
+	}
+
+	return config
+}
+
+// Validate reports problems Traefik would reject or silently misroute: no
+// entrypoints or providers, clashing addresses, and references to undefined
+// entrypoints or certificate resolvers.
+func (c *TraefikStaticConfiguration) Validate() []error {
+	problems := []error{}
+
+	if len(c.EntryPoints) == 0 {
+		problems = append(problems, fmt.Errorf("no entrypoints defined"))
+	}
+	addresses := make(map[string]string)
+	for _, name := range sortedKeys(c.EntryPoints) {
+		entryPoint := c.EntryPoints[name]
+		if err := validateEntryPointAddress(entryPoint.Address); err != nil {
+			problems = append(problems, fmt.Errorf("entrypoint %s: %w", name, err))
+		}
+		if other, ok := addresses[entryPoint.Address]; ok {
+			problems = append(problems, fmt.Errorf("entrypoints %s and %s share address %s", other, name, entryPoint.Address))
+		}
+		addresses[entryPoint.Address] = name
+
+		if entryPoint.HTTP == nil {
+			continue
+		}
+		if redirections := entryPoint.HTTP.Redirections; redirections != nil && redirections.EntryPoint != nil {
+			if _, ok := c.EntryPoints[redirections.EntryPoint.To]; !ok && !strings.HasPrefix(redirections.EntryPoint.To, ":") {
+				problems = append(problems, fmt.Errorf("entrypoint %s redirects to undefined entrypoint %s", name, redirections.EntryPoint.To))
+			}
+		}
+		if tls := entryPoint.HTTP.TLS; tls != nil && tls.CertResolver != "" {
+			if _, ok := c.CertificatesResolvers[tls.CertResolver]; !ok {
+				problems = append(problems, fmt.Errorf("entrypoint %s uses undefined certificate resolver %s", name, tls.CertResolver))
+			}
+		}
+	}
+
+	if c.Providers == nil || (c.Providers.File == nil && c.Providers.HTTP == nil && c.Providers.Docker == nil &&
+		c.Providers.Swarm == nil && c.Providers.KubernetesIngress == nil && c.Providers.KubernetesCRD == nil &&
+		c.Providers.KubernetesGateway == nil && c.Providers.Rest == nil && c.Providers.ConsulCatalog == nil &&
+		c.Providers.Nomad == nil && c.Providers.Ecs == nil && c.Providers.Consul == nil && c.Providers.Etcd == nil &&
+		c.Providers.ZooKeeper == nil && c.Providers.Redis == nil && len(c.Providers.Plugin) == 0) {
+		problems = append(problems, fmt.Errorf("no providers defined"))
+	}
+	if c.Providers != nil {
+		if file := c.Providers.File; file != nil && file.Directory == "" && file.Filename == "" {
+			problems = append(problems, fmt.Errorf("file provider needs a directory or filename"))
+		}
+		if http := c.Providers.HTTP; http != nil {
+			if endpoint, err := url.Parse(http.Endpoint); err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+				problems = append(problems, fmt.Errorf("http provider endpoint %q is not a url", http.Endpoint))
+			}
+			if http.PollInterval != "" {
+				if _, err := time.ParseDuration(http.PollInterval); err != nil {
+					problems = append(problems, fmt.Errorf("http provider poll interval: %w", err))
+				}
+			}
+		}
+	}
+
+	if c.Metrics != nil && c.Metrics.Prometheus != nil && c.Metrics.Prometheus.EntryPoint != "" {
+		if _, ok := c.EntryPoints[c.Metrics.Prometheus.EntryPoint]; !ok && c.Metrics.Prometheus.EntryPoint != "traefik" {
+			problems = append(problems, fmt.Errorf("prometheus metrics use undefined entrypoint %s", c.Metrics.Prometheus.EntryPoint))
+		}
+	}
+
+	for _, name := range sortedKeys(c.CertificatesResolvers) {
+		acme := c.CertificatesResolvers[name].Acme
+		if acme == nil {
+			continue
+		}
+		if acme.Email == "" {
+			problems = append(problems, fmt.Errorf("certificate resolver %s has no email", name))
+		}
+		if acme.Storage == "" {
+			problems = append(problems, fmt.Errorf("certificate resolver %s has no storage", name))
+		}
+		if acme.TLSChallenge == nil && acme.HTTPChallenge == nil && acme.DNSChallenge == nil {
+			problems = append(problems, fmt.Errorf("certificate resolver %s has no challenge", name))
+		}
+		if acme.HTTPChallenge != nil {
+			if _, ok := c.EntryPoints[acme.HTTPChallenge.EntryPoint]; !ok {
+				problems = append(problems, fmt.Errorf("certificate resolver %s answers challenges on undefined entrypoint %s", name, acme.HTTPChallenge.EntryPoint))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateEntryPointAddress checks an entrypoint address such as :443 or
+// 0.0.0.0:53/udp. Unlike server addresses, the host may be empty.
+func validateEntryPointAddress(address string) error {
+	hostPort, _, _ := strings.Cut(address, "/")
+	_, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return fmt.Errorf("address %q must be [host]:port: %w", address, err)
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return fmt.Errorf("address %q has an invalid port", address)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package traefik
+
+import "testing"
+
+// TestStaticConfigLoadsDynamicDir checks that the dynamic directory bin/run/traefik
+// mounts is loaded, by the file provider or through the provider endpoint
+func TestStaticConfigLoadsDynamicDir(t *testing.T) {
+	settings := DefaultTraefikStaticSettings()
+	if settings.DynamicConfigDir != DynamicConfigPath+"/" {
+		t.Errorf("DynamicConfigDir = %s, want %s/", settings.DynamicConfigDir, DynamicConfigPath)
+	}
+
+	config := NewTraefikStaticConfiguration(settings)
+	if config.Providers.File == nil || config.Providers.File.Directory != settings.DynamicConfigDir {
+		t.Errorf("file provider = %+v, want it watching %s", config.Providers.File, settings.DynamicConfigDir)
+	}
+	if problems := config.Validate(); len(problems) > 0 {
+		t.Errorf("invalid static config: %v", problems)
+	}
+
+	settings.ProviderEndpoint = "http://localhost:8090/api/v1/traefik/config"
+	config = NewTraefikStaticConfiguration(settings)
+	if config.Providers.HTTP == nil || config.Providers.HTTP.Endpoint != settings.ProviderEndpoint {
+		t.Errorf("http provider = %+v, want it polling %s", config.Providers.HTTP, settings.ProviderEndpoint)
+	}
+	// uberbase serve serves the directory, the file provider would serve
+	// deployed routers a second time
+	if config.Providers.File != nil {
+		t.Errorf("file provider = %+v, want none alongside the http provider", config.Providers.File)
+	}
+	if problems := config.Validate(); len(problems) > 0 {
+		t.Errorf("invalid static config: %v", problems)
+	}
+}
+
+func TestStaticConfigACMEChallenge(t *testing.T) {
+	settings := DefaultTraefikStaticSettings()
+	settings.ACMEEmail = "admin@example.com"
+
+	acme := NewTraefikStaticConfiguration(settings).CertificatesResolvers[DefaultResolver].Acme
+	if acme == nil || acme.TLSChallenge == nil || acme.HTTPChallenge != nil {
+		t.Errorf("resolver = %+v, want the TLS-ALPN challenge", acme)
+	}
+
+	settings.ACMEHTTPChallenge = true
+	config := NewTraefikStaticConfiguration(settings)
+	acme = config.CertificatesResolvers[DefaultResolver].Acme
+	if acme == nil || acme.HTTPChallenge == nil || acme.HTTPChallenge.EntryPoint != HTTPEntryPoint || acme.TLSChallenge != nil {
+		t.Errorf("resolver = %+v, want the HTTP challenge on %s", acme, HTTPEntryPoint)
+	}
+	if problems := config.Validate(); len(problems) > 0 {
+		t.Errorf("invalid static config: %v", problems)
+	}
+}
@@ -1,596 +1,559 @@
 package traefik
 
+type TraefikGlobal struct {
+	CheckNewVersion    *bool `yaml:"checkNewVersion,omitempty"`
+	SendAnonymousUsage bool  `yaml:"sendAnonymousUsage,omitempty"`
+}
+
+type TraefikStaticServersTransport struct {
+	InsecureSkipVerify  bool                                       `yaml:"insecureSkipVerify,omitempty"`
+	RootCAs             []string                                   `yaml:"rootCAs,omitempty"`
+	MaxIdleConnsPerHost int                                        `yaml:"maxIdleConnsPerHost,omitempty"`
+	ForwardingTimeouts  *TraefikServersTransportForwardingTimeouts `yaml:"forwardingTimeouts,omitempty"`
+	Spiffe              *TraefikSpiffe                             `yaml:"spiffe,omitempty"`
+}
+
+type TraefikStaticTCPServersTransportTLS struct {
+	InsecureSkipVerify bool           `yaml:"insecureSkipVerify,omitempty"`
+	RootCAs            []string       `yaml:"rootCAs,omitempty"`
+	Spiffe             *TraefikSpiffe `yaml:"spiffe,omitempty"`
+}
+
+type TraefikStaticTCPServersTransport struct {
+	DialKeepAlive    string                               `yaml:"dialKeepAlive,omitempty"`
+	DialTimeout      string                               `yaml:"dialTimeout,omitempty"`
+	TerminationDelay string                               `yaml:"terminationDelay,omitempty"`
+	TLS              *TraefikStaticTCPServersTransportTLS `yaml:"tls,omitempty"`
+}
+
+type TraefikEntryPointTransportLifeCycle struct {
+	RequestAcceptGraceTimeout string `yaml:"requestAcceptGraceTimeout,omitempty"`
+	GraceTimeOut              string `yaml:"graceTimeOut,omitempty"`
+}
+
+type TraefikEntryPointTransportRespondingTimeouts struct {
+	ReadTimeout  string `yaml:"readTimeout,omitempty"`
+	WriteTimeout string `yaml:"writeTimeout,omitempty"`
+	IdleTimeout  string `yaml:"idleTimeout,omitempty"`
+}
+
+type TraefikEntryPointTransport struct {
+	LifeCycle            *TraefikEntryPointTransportLifeCycle          `yaml:"lifeCycle,omitempty"`
+	RespondingTimeouts   *TraefikEntryPointTransportRespondingTimeouts `yaml:"respondingTimeouts,omitempty"`
+	KeepAliveMaxTime     string                                        `yaml:"keepAliveMaxTime,omitempty"`
+	KeepAliveMaxRequests int                                           `yaml:"keepAliveMaxRequests,omitempty"`
+}
+
+type TraefikEntryPointProxyProtocol struct {
+	Insecure   bool     `yaml:"insecure,omitempty"`
+	TrustedIPs []string `yaml:"trustedIPs,omitempty"`
+}
+
+type TraefikEntryPointForwardedHeaders struct {
+	Insecure   bool     `yaml:"insecure,omitempty"`
+	TrustedIPs []string `yaml:"trustedIPs,omitempty"`
+	Connection []string `yaml:"connection,omitempty"`
+}
+
+type TraefikEntryPointHTTPRedirectionsEntryPoint struct {
+	To        string `yaml:"to,omitempty"`
+	Scheme    string `yaml:"scheme,omitempty"`
+	Permanent *bool  `yaml:"permanent,omitempty"`
+	Priority  int    `yaml:"priority,omitempty"`
+}
+
+type TraefikEntryPointHTTPRedirections struct {
+	EntryPoint *TraefikEntryPointHTTPRedirectionsEntryPoint `yaml:"entryPoint,omitempty"`
+}
+
+type TraefikEntryPointHTTPTLS struct {
+	Options      string          `yaml:"options,omitempty"`
+	CertResolver string          `yaml:"certResolver,omitempty"`
+	Domains      []TraefikDomain `yaml:"domains,omitempty"`
+}
+
+type TraefikEntryPointHTTP struct {
+	Redirections          *TraefikEntryPointHTTPRedirections `yaml:"redirections,omitempty"`
+	Middlewares           []string                           `yaml:"middlewares,omitempty"`
+	TLS                   *TraefikEntryPointHTTPTLS          `yaml:"tls,omitempty"`
+	EncodeQuerySemicolons bool                               `yaml:"encodeQuerySemicolons,omitempty"`
+	MaxHeaderBytes        int                                `yaml:"maxHeaderBytes,omitempty"`
+}
+
+type TraefikEntryPointHTTP2 struct {
+	MaxConcurrentStreams int `yaml:"maxConcurrentStreams,omitempty"`
+}
+
+type TraefikEntryPointHTTP3 struct {
+	AdvertisedPort int `yaml:"advertisedPort,omitempty"`
+}
+
+type TraefikEntryPointUDP struct {
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+type TraefikEntryPointObservability struct {
+	AccessLogs *bool `yaml:"accessLogs,omitempty"`
+	Tracing    *bool `yaml:"tracing,omitempty"`
+	Metrics    *bool `yaml:"metrics,omitempty"`
+}
+
+type TraefikEntryPoint struct {
+	Address          string                             `yaml:"address,omitempty"`
+	AllowACMEByPass  bool                               `yaml:"allowACMEByPass,omitempty"`
+	ReusePort        bool                               `yaml:"reusePort,omitempty"`
+	AsDefault        bool                               `yaml:"asDefault,omitempty"`
+	Transport        *TraefikEntryPointTransport        `yaml:"transport,omitempty"`
+	ProxyProtocol    *TraefikEntryPointProxyProtocol    `yaml:"proxyProtocol,omitempty"`
+	ForwardedHeaders *TraefikEntryPointForwardedHeaders `yaml:"forwardedHeaders,omitempty"`
+	HTTP             *TraefikEntryPointHTTP             `yaml:"http,omitempty"`
+	HTTP2            *TraefikEntryPointHTTP2            `yaml:"http2,omitempty"`
+	HTTP3            *TraefikEntryPointHTTP3            `yaml:"http3,omitempty"`
+	UDP              *TraefikEntryPointUDP              `yaml:"udp,omitempty"`
+	Observability    *TraefikEntryPointObservability    `yaml:"observability,omitempty"`
+}
+
+type TraefikProvidersDocker struct {
+	ExposedByDefault   *bool             `yaml:"exposedByDefault,omitempty"`
+	Constraints        string            `yaml:"constraints,omitempty"`
+	AllowEmptyServices bool              `yaml:"allowEmptyServices,omitempty"`
+	Network            string            `yaml:"network,omitempty"`
+	UseBindPortIP      bool              `yaml:"useBindPortIP,omitempty"`
+	Watch              *bool             `yaml:"watch,omitempty"`
+	DefaultRule        string            `yaml:"defaultRule,omitempty"`
+	Username           string            `yaml:"username,omitempty"`
+	Password           string            `yaml:"password,omitempty"`
+	Endpoint           string            `yaml:"endpoint,omitempty"`
+	TLS                *TraefikClientTLS `yaml:"tls,omitempty"`
+	HTTPClientTimeout  string            `yaml:"httpClientTimeout,omitempty"`
+}
+
+type TraefikProvidersSwarm struct {
+	ExposedByDefault   *bool             `yaml:"exposedByDefault,omitempty"`
+	Constraints        string            `yaml:"constraints,omitempty"`
+	AllowEmptyServices bool              `yaml:"allowEmptyServices,omitempty"`
+	Network            string            `yaml:"network,omitempty"`
+	UseBindPortIP      bool              `yaml:"useBindPortIP,omitempty"`
+	Watch              *bool             `yaml:"watch,omitempty"`
+	DefaultRule        string            `yaml:"defaultRule,omitempty"`
+	Username           string            `yaml:"username,omitempty"`
+	Password           string            `yaml:"password,omitempty"`
+	Endpoint           string            `yaml:"endpoint,omitempty"`
+	TLS                *TraefikClientTLS `yaml:"tls,omitempty"`
+	HTTPClientTimeout  string            `yaml:"httpClientTimeout,omitempty"`
+	RefreshSeconds     string            `yaml:"refreshSeconds,omitempty"`
+}
+
+type TraefikProvidersFile struct {
+	Directory                 string `yaml:"directory,omitempty"`
+	Watch                     *bool  `yaml:"watch,omitempty"`
+	Filename                  string `yaml:"filename,omitempty"`
+	DebugLogGeneratedTemplate bool   `yaml:"debugLogGeneratedTemplate,omitempty"`
+}
+
+type TraefikProvidersKubernetesIngressIngressEndpoint struct {
+	IP               string `yaml:"ip,omitempty"`
+	Hostname         string `yaml:"hostname,omitempty"`
+	PublishedService string `yaml:"publishedService,omitempty"`
+}
+
+type TraefikProvidersKubernetesIngress struct {
+	Endpoint                     string                                            `yaml:"endpoint,omitempty"`
+	Token                        string                                            `yaml:"token,omitempty"`
+	CertAuthFilePath             string                                            `yaml:"certAuthFilePath,omitempty"`
+	Namespaces                   []string                                          `yaml:"namespaces,omitempty"`
+	LabelSelector                string                                            `yaml:"labelSelector,omitempty"`
+	IngressClass                 string                                            `yaml:"ingressClass,omitempty"`
+	IngressEndpoint              *TraefikProvidersKubernetesIngressIngressEndpoint `yaml:"ingressEndpoint,omitempty"`
+	ThrottleDuration             string                                            `yaml:"throttleDuration,omitempty"`
+	AllowEmptyServices           bool                                              `yaml:"allowEmptyServices,omitempty"`
+	AllowExternalNameServices    bool                                              `yaml:"allowExternalNameServices,omitempty"`
+	DisableIngressClassLookup    bool                                              `yaml:"disableIngressClassLookup,omitempty"`
+	DisableClusterScopeResources bool                                              `yaml:"disableClusterScopeResources,omitempty"`
+	NativeLBByDefault            bool                                              `yaml:"nativeLBByDefault,omitempty"`
+}
+
+type TraefikProvidersKubernetesCRD struct {
+	Endpoint                     string   `yaml:"endpoint,omitempty"`
+	Token                        string   `yaml:"token,omitempty"`
+	CertAuthFilePath             string   `yaml:"certAuthFilePath,omitempty"`
+	Namespaces                   []string `yaml:"namespaces,omitempty"`
+	AllowCrossNamespace          bool     `yaml:"allowCrossNamespace,omitempty"`
+	AllowExternalNameServices    bool     `yaml:"allowExternalNameServices,omitempty"`
+	LabelSelector                string   `yaml:"labelSelector,omitempty"`
+	IngressClass                 string   `yaml:"ingressClass,omitempty"`
+	ThrottleDuration             string   `yaml:"throttleDuration,omitempty"`
+	AllowEmptyServices           bool     `yaml:"allowEmptyServices,omitempty"`
+	NativeLBByDefault            bool     `yaml:"nativeLBByDefault,omitempty"`
+	DisableClusterScopeResources bool     `yaml:"disableClusterScopeResources,omitempty"`
+}
+
+type TraefikProvidersKubernetesGatewayStatusAddressService struct {
+	Name      string `yaml:"name,omitempty"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type TraefikProvidersKubernetesGatewayStatusAddress struct {
+	IP       string                                                 `yaml:"ip,omitempty"`
+	Hostname string                                                 `yaml:"hostname,omitempty"`
+	Service  *TraefikProvidersKubernetesGatewayStatusAddressService `yaml:"service,omitempty"`
+}
+
+type TraefikProvidersKubernetesGateway struct {
+	Endpoint            string                                          `yaml:"endpoint,omitempty"`
+	Token               string                                          `yaml:"token,omitempty"`
+	CertAuthFilePath    string                                          `yaml:"certAuthFilePath,omitempty"`
+	Namespaces          []string                                        `yaml:"namespaces,omitempty"`
+	LabelSelector       string                                          `yaml:"labelSelector,omitempty"`
+	ThrottleDuration    string                                          `yaml:"throttleDuration,omitempty"`
+	ExperimentalChannel bool                                            `yaml:"experimentalChannel,omitempty"`
+	StatusAddress       *TraefikProvidersKubernetesGatewayStatusAddress `yaml:"statusAddress,omitempty"`
+	NativeLBByDefault   bool                                            `yaml:"nativeLBByDefault,omitempty"`
+}
+
+type TraefikProvidersRest struct {
+	Insecure bool `yaml:"insecure,omitempty"`
+}
+
+type TraefikProvidersConsulCatalogEndpointHTTPAuth struct {
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+type TraefikProvidersConsulCatalogEndpoint struct {
+	Address          string                                         `yaml:"address,omitempty"`
+	Scheme           string                                         `yaml:"scheme,omitempty"`
+	Datacenter       string                                         `yaml:"datacenter,omitempty"`
+	Token            string                                         `yaml:"token,omitempty"`
+	TLS              *TraefikClientTLS                              `yaml:"tls,omitempty"`
+	HTTPAuth         *TraefikProvidersConsulCatalogEndpointHTTPAuth `yaml:"httpAuth,omitempty"`
+	EndpointWaitTime string                                         `yaml:"endpointWaitTime,omitempty"`
+}
+
+type TraefikProvidersConsulCatalog struct {
+	Constraints       string                                 `yaml:"constraints,omitempty"`
+	Endpoint          *TraefikProvidersConsulCatalogEndpoint `yaml:"endpoint,omitempty"`
+	Prefix            string                                 `yaml:"prefix,omitempty"`
+	RefreshInterval   string                                 `yaml:"refreshInterval,omitempty"`
+	RequireConsistent bool                                   `yaml:"requireConsistent,omitempty"`
+	Stale             bool                                   `yaml:"stale,omitempty"`
+	Cache             bool                                   `yaml:"cache,omitempty"`
+	ExposedByDefault  *bool                                  `yaml:"exposedByDefault,omitempty"`
+	DefaultRule       string                                 `yaml:"defaultRule,omitempty"`
+	ConnectAware      bool                                   `yaml:"connectAware,omitempty"`
+	ConnectByDefault  bool                                   `yaml:"connectByDefault,omitempty"`
+	ServiceName       string                                 `yaml:"serviceName,omitempty"`
+	Watch             *bool                                  `yaml:"watch,omitempty"`
+	StrictChecks      []string                               `yaml:"strictChecks,omitempty"`
+	Namespaces        []string                               `yaml:"namespaces,omitempty"`
+}
+
+type TraefikProvidersNomadEndpoint struct {
+	Address          string            `yaml:"address,omitempty"`
+	Region           string            `yaml:"region,omitempty"`
+	Token            string            `yaml:"token,omitempty"`
+	TLS              *TraefikClientTLS `yaml:"tls,omitempty"`
+	EndpointWaitTime string            `yaml:"endpointWaitTime,omitempty"`
+}
+
+type TraefikProvidersNomad struct {
+	DefaultRule        string                         `yaml:"defaultRule,omitempty"`
+	Constraints        string                         `yaml:"constraints,omitempty"`
+	Endpoint           *TraefikProvidersNomadEndpoint `yaml:"endpoint,omitempty"`
+	Prefix             string                         `yaml:"prefix,omitempty"`
+	Stale              bool                           `yaml:"stale,omitempty"`
+	ExposedByDefault   *bool                          `yaml:"exposedByDefault,omitempty"`
+	RefreshInterval    string                         `yaml:"refreshInterval,omitempty"`
+	AllowEmptyServices bool                           `yaml:"allowEmptyServices,omitempty"`
+	Watch              *bool                          `yaml:"watch,omitempty"`
+	ThrottleDuration   string                         `yaml:"throttleDuration,omitempty"`
+	Namespaces         []string                       `yaml:"namespaces,omitempty"`
+}
+
+type TraefikProvidersEcs struct {
+	Constraints          string   `yaml:"constraints,omitempty"`
+	ExposedByDefault     *bool    `yaml:"exposedByDefault,omitempty"`
+	RefreshSeconds       int      `yaml:"refreshSeconds,omitempty"`
+	DefaultRule          string   `yaml:"defaultRule,omitempty"`
+	Clusters             []string `yaml:"clusters,omitempty"`
+	AutoDiscoverClusters bool     `yaml:"autoDiscoverClusters,omitempty"`
+	HealthyTasksOnly     bool     `yaml:"healthyTasksOnly,omitempty"`
+	EcsAnywhere          bool     `yaml:"ecsAnywhere,omitempty"`
+	Region               string   `yaml:"region,omitempty"`
+	AccessKeyID          string   `yaml:"accessKeyID,omitempty"`
+	SecretAccessKey      string   `yaml:"secretAccessKey,omitempty"`
+}
+
+type TraefikProvidersConsul struct {
+	RootKey    string            `yaml:"rootKey,omitempty"`
+	Endpoints  []string          `yaml:"endpoints,omitempty"`
+	Token      string            `yaml:"token,omitempty"`
+	TLS        *TraefikClientTLS `yaml:"tls,omitempty"`
+	Namespaces []string          `yaml:"namespaces,omitempty"`
+}
+
+type TraefikProvidersEtcd struct {
+	RootKey   string            `yaml:"rootKey,omitempty"`
+	Endpoints []string          `yaml:"endpoints,omitempty"`
+	TLS       *TraefikClientTLS `yaml:"tls,omitempty"`
+	Username  string            `yaml:"username,omitempty"`
+	Password  string            `yaml:"password,omitempty"`
+}
+
+type TraefikProvidersZooKeeper struct {
+	RootKey   string   `yaml:"rootKey,omitempty"`
+	Endpoints []string `yaml:"endpoints,omitempty"`
+	Username  string   `yaml:"username,omitempty"`
+	Password  string   `yaml:"password,omitempty"`
+}
+
+type TraefikProvidersRedisSentinel struct {
+	MasterName              string `yaml:"masterName,omitempty"`
+	Username                string `yaml:"username,omitempty"`
+	Password                string `yaml:"password,omitempty"`
+	LatencyStrategy         bool   `yaml:"latencyStrategy,omitempty"`
+	RandomStrategy          bool   `yaml:"randomStrategy,omitempty"`
+	ReplicaStrategy         bool   `yaml:"replicaStrategy,omitempty"`
+	UseDisconnectedReplicas bool   `yaml:"useDisconnectedReplicas,omitempty"`
+}
+
+type TraefikProvidersRedis struct {
+	RootKey   string                         `yaml:"rootKey,omitempty"`
+	Endpoints []string                       `yaml:"endpoints,omitempty"`
+	TLS       *TraefikClientTLS              `yaml:"tls,omitempty"`
+	Username  string                         `yaml:"username,omitempty"`
+	Password  string                         `yaml:"password,omitempty"`
+	Db        int                            `yaml:"db,omitempty"`
+	Sentinel  *TraefikProvidersRedisSentinel `yaml:"sentinel,omitempty"`
+}
+
+type TraefikProvidersHTTP struct {
+	Endpoint     string            `yaml:"endpoint,omitempty"`
+	PollInterval string            `yaml:"pollInterval,omitempty"`
+	PollTimeout  string            `yaml:"pollTimeout,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+	TLS          *TraefikClientTLS `yaml:"tls,omitempty"`
+}
+
+type TraefikProviders struct {
+	ProvidersThrottleDuration string                             `yaml:"providersThrottleDuration,omitempty"`
+	Docker                    *TraefikProvidersDocker            `yaml:"docker,omitempty"`
+	Swarm                     *TraefikProvidersSwarm             `yaml:"swarm,omitempty"`
+	File                      *TraefikProvidersFile              `yaml:"file,omitempty"`
+	KubernetesIngress         *TraefikProvidersKubernetesIngress `yaml:"kubernetesIngress,omitempty"`
+	KubernetesCRD             *TraefikProvidersKubernetesCRD     `yaml:"kubernetesCRD,omitempty"`
+	KubernetesGateway         *TraefikProvidersKubernetesGateway `yaml:"kubernetesGateway,omitempty"`
+	Rest                      *TraefikProvidersRest              `yaml:"rest,omitempty"`
+	ConsulCatalog             *TraefikProvidersConsulCatalog     `yaml:"consulCatalog,omitempty"`
+	Nomad                     *TraefikProvidersNomad             `yaml:"nomad,omitempty"`
+	Ecs                       *TraefikProvidersEcs               `yaml:"ecs,omitempty"`
+	Consul                    *TraefikProvidersConsul            `yaml:"consul,omitempty"`
+	Etcd                      *TraefikProvidersEtcd              `yaml:"etcd,omitempty"`
+	ZooKeeper                 *TraefikProvidersZooKeeper         `yaml:"zooKeeper,omitempty"`
+	Redis                     *TraefikProvidersRedis             `yaml:"redis,omitempty"`
+	HTTP                      *TraefikProvidersHTTP              `yaml:"http,omitempty"`
+	Plugin                    map[string]map[string]string       `yaml:"plugin,omitempty"`
+}
+
+type TraefikAPI struct {
+	BasePath           string `yaml:"basePath,omitempty"`
+	Insecure           bool   `yaml:"insecure,omitempty"`
+	Dashboard          *bool  `yaml:"dashboard,omitempty"`
+	Debug              bool   `yaml:"debug,omitempty"`
+	DisableDashboardAd bool   `yaml:"disableDashboardAd,omitempty"`
+}
+
+type TraefikMetricsPrometheus struct {
+	Buckets              []int             `yaml:"buckets,omitempty"`
+	AddEntryPointsLabels *bool             `yaml:"addEntryPointsLabels,omitempty"`
+	AddRoutersLabels     bool              `yaml:"addRoutersLabels,omitempty"`
+	AddServicesLabels    *bool             `yaml:"addServicesLabels,omitempty"`
+	EntryPoint           string            `yaml:"entryPoint,omitempty"`
+	ManualRouting        bool              `yaml:"manualRouting,omitempty"`
+	HeaderLabels         map[string]string `yaml:"headerLabels,omitempty"`
+}
+
+type TraefikMetricsPush struct {
+	Address              string `yaml:"address,omitempty"`
+	PushInterval         string `yaml:"pushInterval,omitempty"`
+	AddEntryPointsLabels *bool  `yaml:"addEntryPointsLabels,omitempty"`
+	AddRoutersLabels     bool   `yaml:"addRoutersLabels,omitempty"`
+	AddServicesLabels    *bool  `yaml:"addServicesLabels,omitempty"`
+	Prefix               string `yaml:"prefix,omitempty"`
+}
+
+type TraefikMetricsInfluxDB2 struct {
+	Address              string            `yaml:"address,omitempty"`
+	Token                string            `yaml:"token,omitempty"`
+	PushInterval         string            `yaml:"pushInterval,omitempty"`
+	Org                  string            `yaml:"org,omitempty"`
+	Bucket               string            `yaml:"bucket,omitempty"`
+	AddEntryPointsLabels *bool             `yaml:"addEntryPointsLabels,omitempty"`
+	AddRoutersLabels     bool              `yaml:"addRoutersLabels,omitempty"`
+	AddServicesLabels    *bool             `yaml:"addServicesLabels,omitempty"`
+	AdditionalLabels     map[string]string `yaml:"additionalLabels,omitempty"`
+}
+
+type TraefikOtlpGrpc struct {
+	Endpoint string            `yaml:"endpoint,omitempty"`
+	Insecure bool              `yaml:"insecure,omitempty"`
+	TLS      *TraefikClientTLS `yaml:"tls,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+}
+
+type TraefikOtlpHTTP struct {
+	Endpoint string            `yaml:"endpoint,omitempty"`
+	TLS      *TraefikClientTLS `yaml:"tls,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+}
+
+type TraefikMetricsOtlp struct {
+	Grpc                 *TraefikOtlpGrpc `yaml:"grpc,omitempty"`
+	HTTP                 *TraefikOtlpHTTP `yaml:"http,omitempty"`
+	AddEntryPointsLabels *bool            `yaml:"addEntryPointsLabels,omitempty"`
+	AddRoutersLabels     bool             `yaml:"addRoutersLabels,omitempty"`
+	AddServicesLabels    *bool            `yaml:"addServicesLabels,omitempty"`
+	ExplicitBoundaries   []int            `yaml:"explicitBoundaries,omitempty"`
+	PushInterval         string           `yaml:"pushInterval,omitempty"`
+	ServiceName          string           `yaml:"serviceName,omitempty"`
+}
+
+type TraefikMetrics struct {
+	AddInternals bool                      `yaml:"addInternals,omitempty"`
+	Prometheus   *TraefikMetricsPrometheus `yaml:"prometheus,omitempty"`
+	Datadog      *TraefikMetricsPush       `yaml:"datadog,omitempty"`
+	StatsD       *TraefikMetricsPush       `yaml:"statsD,omitempty"`
+	InfluxDB2    *TraefikMetricsInfluxDB2  `yaml:"influxDB2,omitempty"`
+	Otlp         *TraefikMetricsOtlp       `yaml:"otlp,omitempty"`
+}
+
+type TraefikPing struct {
+	EntryPoint            string `yaml:"entryPoint,omitempty"`
+	ManualRouting         bool   `yaml:"manualRouting,omitempty"`
+	TerminatingStatusCode int    `yaml:"terminatingStatusCode,omitempty"`
+}
+
+type TraefikLogOtlp struct {
+	ServiceName        string            `yaml:"serviceName,omitempty"`
+	ResourceAttributes map[string]string `yaml:"resourceAttributes,omitempty"`
+	Grpc               *TraefikOtlpGrpc  `yaml:"grpc,omitempty"`
+	HTTP               *TraefikOtlpHTTP  `yaml:"http,omitempty"`
+}
+
+type TraefikLog struct {
+	Level      string          `yaml:"level,omitempty"`
+	Format     string          `yaml:"format,omitempty"`
+	NoColor    bool            `yaml:"noColor,omitempty"`
+	FilePath   string          `yaml:"filePath,omitempty"`
+	MaxSize    int             `yaml:"maxSize,omitempty"`
+	MaxAge     int             `yaml:"maxAge,omitempty"`
+	MaxBackups int             `yaml:"maxBackups,omitempty"`
+	Compress   bool            `yaml:"compress,omitempty"`
+	Otlp       *TraefikLogOtlp `yaml:"otlp,omitempty"`
+}
+
+type TraefikAccessLogFilters struct {
+	StatusCodes   []string `yaml:"statusCodes,omitempty"`
+	RetryAttempts bool     `yaml:"retryAttempts,omitempty"`
+	MinDuration   string   `yaml:"minDuration,omitempty"`
+}
+
+type TraefikAccessLogFieldsHeaders struct {
+	DefaultMode string            `yaml:"defaultMode,omitempty"`
+	Names       map[string]string `yaml:"names,omitempty"`
+}
+
+type TraefikAccessLogFields struct {
+	DefaultMode string                         `yaml:"defaultMode,omitempty"`
+	Names       map[string]string              `yaml:"names,omitempty"`
+	Headers     *TraefikAccessLogFieldsHeaders `yaml:"headers,omitempty"`
+}
+
+type TraefikAccessLog struct {
+	FilePath      string                   `yaml:"filePath,omitempty"`
+	Format        string                   `yaml:"format,omitempty"`
+	Filters       *TraefikAccessLogFilters `yaml:"filters,omitempty"`
+	Fields        *TraefikAccessLogFields  `yaml:"fields,omitempty"`
+	BufferingSize int                      `yaml:"bufferingSize,omitempty"`
+	AddInternals  bool                     `yaml:"addInternals,omitempty"`
+	Otlp          *TraefikLogOtlp          `yaml:"otlp,omitempty"`
+}
+
+type TraefikTracingOtlp struct {
+	Grpc *TraefikOtlpGrpc `yaml:"grpc,omitempty"`
+	HTTP *TraefikOtlpHTTP `yaml:"http,omitempty"`
+}
+
+type TraefikTracing struct {
+	ServiceName             string              `yaml:"serviceName,omitempty"`
+	ResourceAttributes      map[string]string   `yaml:"resourceAttributes,omitempty"`
+	CapturedRequestHeaders  []string            `yaml:"capturedRequestHeaders,omitempty"`
+	CapturedResponseHeaders []string            `yaml:"capturedResponseHeaders,omitempty"`
+	SafeQueryParams         []string            `yaml:"safeQueryParams,omitempty"`
+	SampleRate              int                 `yaml:"sampleRate,omitempty"`
+	AddInternals            bool                `yaml:"addInternals,omitempty"`
+	Otlp                    *TraefikTracingOtlp `yaml:"otlp,omitempty"`
+	GlobalAttributes        map[string]string   `yaml:"globalAttributes,omitempty"`
+}
+
+type TraefikHostResolver struct {
+	CnameFlattening bool   `yaml:"cnameFlattening,omitempty"`
+	ResolvConfig    string `yaml:"resolvConfig,omitempty"`
+	ResolvDepth     int    `yaml:"resolvDepth,omitempty"`
+}
+
+type TraefikPluginSettings struct {
+	Envs   []string `yaml:"envs,omitempty"`
+	Mounts []string `yaml:"mounts,omitempty"`
+}
+
+type TraefikExperimentalPlugin struct {
+	ModuleName string                 `yaml:"moduleName,omitempty"`
+	Version    string                 `yaml:"version,omitempty"`
+	Settings   *TraefikPluginSettings `yaml:"settings,omitempty"`
+}
+
+type TraefikExperimentalLocalPlugin struct {
+	ModuleName string                 `yaml:"moduleName,omitempty"`
+	Settings   *TraefikPluginSettings `yaml:"settings,omitempty"`
+}
+
+type TraefikExperimentalFastProxy struct {
+	Debug bool `yaml:"debug,omitempty"`
+}
+
+type TraefikExperimental struct {
+	Plugins              []TraefikExperimentalPlugin      `yaml:"plugins,omitempty"`
+	LocalPlugins         []TraefikExperimentalLocalPlugin `yaml:"localPlugins,omitempty"`
+	AbortOnPluginFailure bool                             `yaml:"abortOnPluginFailure,omitempty"`
+	FastProxy            *TraefikExperimentalFastProxy    `yaml:"fastProxy,omitempty"`
+	Otlplogs             bool                             `yaml:"otlplogs,omitempty"`
+	KubernetesGateway    bool                             `yaml:"kubernetesGateway,omitempty"`
+}
+
+type TraefikCore struct {
+	DefaultRuleSyntax string `yaml:"defaultRuleSyntax,omitempty"`
+}
+
+type TraefikStaticSpiffe struct {
+	WorkloadAPIAddr string `yaml:"workloadAPIAddr,omitempty"`
+}
+
 type TraefikStaticConfiguration struct {
-	Global struct {
-		CheckNewVersion    bool `yaml:"checkNewVersion"`
-		SendAnonymousUsage bool `yaml:"sendAnonymousUsage"`
-	} `yaml:"global"`
-	ServersTransport struct {
-		InsecureSkipVerify  bool     `yaml:"insecureSkipVerify"`
-		RootCAs             []string `yaml:"rootCAs"`
-		MaxIdleConnsPerHost int      `yaml:"maxIdleConnsPerHost"`
-		ForwardingTimeouts  struct {
-			DialTimeout           string `yaml:"dialTimeout"`
-			ResponseHeaderTimeout string `yaml:"responseHeaderTimeout"`
-			IdleConnTimeout       string `yaml:"idleConnTimeout"`
-		} `yaml:"forwardingTimeouts"`
-		Spiffe struct {
-			Ids         []string `yaml:"ids"`
-			TrustDomain string   `yaml:"trustDomain"`
-		} `yaml:"spiffe"`
-	} `yaml:"serversTransport"`
-	TCPServersTransport struct {
-		DialKeepAlive    string `yaml:"dialKeepAlive"`
-		DialTimeout      string `yaml:"dialTimeout"`
-		TerminationDelay string `yaml:"terminationDelay"`
-		TLS              struct {
-			InsecureSkipVerify bool     `yaml:"insecureSkipVerify"`
-			RootCAs            []string `yaml:"rootCAs"`
-			Spiffe             struct {
-				Ids         []string `yaml:"ids"`
-				TrustDomain string   `yaml:"trustDomain"`
-			} `yaml:"spiffe"`
-		} `yaml:"tls"`
-	} `yaml:"tcpServersTransport"`
-	EntryPoints map[string]struct {
-		Address         string `yaml:"address"`
-		AllowACMEByPass bool   `yaml:"allowACMEByPass"`
-		ReusePort       bool   `yaml:"reusePort"`
-		AsDefault       bool   `yaml:"asDefault"`
-		Transport       struct {
-			LifeCycle struct {
-				RequestAcceptGraceTimeout string `yaml:"requestAcceptGraceTimeout"`
-				GraceTimeOut              string `yaml:"graceTimeOut"`
-			} `yaml:"lifeCycle"`
-			RespondingTimeouts struct {
-				ReadTimeout  string `yaml:"readTimeout"`
-				WriteTimeout string `yaml:"writeTimeout"`
-				IdleTimeout  string `yaml:"idleTimeout"`
-			} `yaml:"respondingTimeouts"`
-			KeepAliveMaxTime     string `yaml:"keepAliveMaxTime"`
-			KeepAliveMaxRequests int    `yaml:"keepAliveMaxRequests"`
-		} `yaml:"transport"`
-		ProxyProtocol struct {
-			Insecure   bool     `yaml:"insecure"`
-			TrustedIPs []string `yaml:"trustedIPs"`
-		} `yaml:"proxyProtocol"`
-		ForwardedHeaders struct {
-			Insecure   bool     `yaml:"insecure"`
-			TrustedIPs []string `yaml:"trustedIPs"`
-			Connection []string `yaml:"connection"`
-		} `yaml:"forwardedHeaders"`
-		HTTP struct {
-			Redirections struct {
-				EntryPoint struct {
-					To        string `yaml:"to"`
-					Scheme    string `yaml:"scheme"`
-					Permanent bool   `yaml:"permanent"`
-					Priority  int    `yaml:"priority"`
-				} `yaml:"entryPoint"`
-			} `yaml:"redirections"`
-			Middlewares []string `yaml:"middlewares"`
-			TLS         struct {
-				Options      string `yaml:"options"`
-				CertResolver string `yaml:"certResolver"`
-				Domains      []struct {
-					Main string   `yaml:"main"`
-					Sans []string `yaml:"sans"`
-				} `yaml:"domains"`
-			} `yaml:"tls"`
-			EncodeQuerySemicolons bool `yaml:"encodeQuerySemicolons"`
-			MaxHeaderBytes        int  `yaml:"maxHeaderBytes"`
-		} `yaml:"http"`
-		HTTP2 struct {
-			MaxConcurrentStreams int `yaml:"maxConcurrentStreams"`
-		} `yaml:"http2"`
-		HTTP3 struct {
-			AdvertisedPort int `yaml:"advertisedPort"`
-		} `yaml:"http3"`
-		UDP struct {
-			Timeout string `yaml:"timeout"`
-		} `yaml:"udp"`
-		Observability struct {
-			AccessLogs bool `yaml:"accessLogs"`
-			Tracing    bool `yaml:"tracing"`
-			Metrics    bool `yaml:"metrics"`
-		} `yaml:"observability"`
-	} `yaml:"entryPoints"`
-	Providers struct {
-		ProvidersThrottleDuration string `yaml:"providersThrottleDuration"`
-		Docker                    struct {
-			ExposedByDefault   bool   `yaml:"exposedByDefault"`
-			Constraints        string `yaml:"constraints"`
-			AllowEmptyServices bool   `yaml:"allowEmptyServices"`
-			Network            string `yaml:"network"`
-			UseBindPortIP      bool   `yaml:"useBindPortIP"`
-			Watch              bool   `yaml:"watch"`
-			DefaultRule        string `yaml:"defaultRule"`
-			Username           string `yaml:"username"`
-			Password           string `yaml:"password"`
-			Endpoint           string `yaml:"endpoint"`
-			TLS                struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-			HTTPClientTimeout string `yaml:"httpClientTimeout"`
-		} `yaml:"docker"`
-		Swarm struct {
-			ExposedByDefault   bool   `yaml:"exposedByDefault"`
-			Constraints        string `yaml:"constraints"`
-			AllowEmptyServices bool   `yaml:"allowEmptyServices"`
-			Network            string `yaml:"network"`
-			UseBindPortIP      bool   `yaml:"useBindPortIP"`
-			Watch              bool   `yaml:"watch"`
-			DefaultRule        string `yaml:"defaultRule"`
-			Username           string `yaml:"username"`
-			Password           string `yaml:"password"`
-			Endpoint           string `yaml:"endpoint"`
-			TLS                struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-			HTTPClientTimeout string `yaml:"httpClientTimeout"`
-			RefreshSeconds    string `yaml:"refreshSeconds"`
-		} `yaml:"swarm"`
-		File struct {
-			Directory                 string `yaml:"directory"`
-			Watch                     bool   `yaml:"watch"`
-			Filename                  string `yaml:"filename"`
-			DebugLogGeneratedTemplate bool   `yaml:"debugLogGeneratedTemplate"`
-		} `yaml:"file"`
-		KubernetesIngress struct {
-			Endpoint         string   `yaml:"endpoint"`
-			Token            string   `yaml:"token"`
-			CertAuthFilePath string   `yaml:"certAuthFilePath"`
-			Namespaces       []string `yaml:"namespaces"`
-			LabelSelector    string   `yaml:"labelSelector"`
-			IngressClass     string   `yaml:"ingressClass"`
-			IngressEndpoint  struct {
-				IP               string `yaml:"ip"`
-				Hostname         string `yaml:"hostname"`
-				PublishedService string `yaml:"publishedService"`
-			} `yaml:"ingressEndpoint"`
-			ThrottleDuration             string `yaml:"throttleDuration"`
-			AllowEmptyServices           bool   `yaml:"allowEmptyServices"`
-			AllowExternalNameServices    bool   `yaml:"allowExternalNameServices"`
-			DisableIngressClassLookup    bool   `yaml:"disableIngressClassLookup"`
-			DisableClusterScopeResources bool   `yaml:"disableClusterScopeResources"`
-			NativeLBByDefault            bool   `yaml:"nativeLBByDefault"`
-		} `yaml:"kubernetesIngress"`
-		KubernetesCRD struct {
-			Endpoint                     string   `yaml:"endpoint"`
-			Token                        string   `yaml:"token"`
-			CertAuthFilePath             string   `yaml:"certAuthFilePath"`
-			Namespaces                   []string `yaml:"namespaces"`
-			AllowCrossNamespace          bool     `yaml:"allowCrossNamespace"`
-			AllowExternalNameServices    bool     `yaml:"allowExternalNameServices"`
-			LabelSelector                string   `yaml:"labelSelector"`
-			IngressClass                 string   `yaml:"ingressClass"`
-			ThrottleDuration             string   `yaml:"throttleDuration"`
-			AllowEmptyServices           bool     `yaml:"allowEmptyServices"`
-			NativeLBByDefault            bool     `yaml:"nativeLBByDefault"`
-			DisableClusterScopeResources bool     `yaml:"disableClusterScopeResources"`
-		} `yaml:"kubernetesCRD"`
-		KubernetesGateway struct {
-			Endpoint            string   `yaml:"endpoint"`
-			Token               string   `yaml:"token"`
-			CertAuthFilePath    string   `yaml:"certAuthFilePath"`
-			Namespaces          []string `yaml:"namespaces"`
-			LabelSelector       string   `yaml:"labelSelector"`
-			ThrottleDuration    string   `yaml:"throttleDuration"`
-			ExperimentalChannel bool     `yaml:"experimentalChannel"`
-			StatusAddress       struct {
-				IP       string `yaml:"ip"`
-				Hostname string `yaml:"hostname"`
-				Service  struct {
-					Name      string `yaml:"name"`
-					Namespace string `yaml:"namespace"`
-				} `yaml:"service"`
-			} `yaml:"statusAddress"`
-			NativeLBByDefault bool `yaml:"nativeLBByDefault"`
-		} `yaml:"kubernetesGateway"`
-		Rest struct {
-			Insecure bool `yaml:"insecure"`
-		} `yaml:"rest"`
-		ConsulCatalog struct {
-			Constraints string `yaml:"constraints"`
-			Endpoint    struct {
-				Address    string `yaml:"address"`
-				Scheme     string `yaml:"scheme"`
-				Datacenter string `yaml:"datacenter"`
-				Token      string `yaml:"token"`
-				TLS        struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				HTTPAuth struct {
-					Username string `yaml:"username"`
-					Password string `yaml:"password"`
-				} `yaml:"httpAuth"`
-				EndpointWaitTime string `yaml:"endpointWaitTime"`
-			} `yaml:"endpoint"`
-			Prefix            string   `yaml:"prefix"`
-			RefreshInterval   string   `yaml:"refreshInterval"`
-			RequireConsistent bool     `yaml:"requireConsistent"`
-			Stale             bool     `yaml:"stale"`
-			Cache             bool     `yaml:"cache"`
-			ExposedByDefault  bool     `yaml:"exposedByDefault"`
-			DefaultRule       string   `yaml:"defaultRule"`
-			ConnectAware      bool     `yaml:"connectAware"`
-			ConnectByDefault  bool     `yaml:"connectByDefault"`
-			ServiceName       string   `yaml:"serviceName"`
-			Watch             bool     `yaml:"watch"`
-			StrictChecks      []string `yaml:"strictChecks"`
-			Namespaces        []string `yaml:"namespaces"`
-		} `yaml:"consulCatalog"`
-		Nomad struct {
-			DefaultRule string `yaml:"defaultRule"`
-			Constraints string `yaml:"constraints"`
-			Endpoint    struct {
-				Address string `yaml:"address"`
-				Region  string `yaml:"region"`
-				Token   string `yaml:"token"`
-				TLS     struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				EndpointWaitTime string `yaml:"endpointWaitTime"`
-			} `yaml:"endpoint"`
-			Prefix             string   `yaml:"prefix"`
-			Stale              bool     `yaml:"stale"`
-			ExposedByDefault   bool     `yaml:"exposedByDefault"`
-			RefreshInterval    string   `yaml:"refreshInterval"`
-			AllowEmptyServices bool     `yaml:"allowEmptyServices"`
-			Watch              bool     `yaml:"watch"`
-			ThrottleDuration   string   `yaml:"throttleDuration"`
-			Namespaces         []string `yaml:"namespaces"`
-		} `yaml:"nomad"`
-		Ecs struct {
-			Constraints          string   `yaml:"constraints"`
-			ExposedByDefault     bool     `yaml:"exposedByDefault"`
-			RefreshSeconds       int      `yaml:"refreshSeconds"`
-			DefaultRule          string   `yaml:"defaultRule"`
-			Clusters             []string `yaml:"clusters"`
-			AutoDiscoverClusters bool     `yaml:"autoDiscoverClusters"`
-			HealthyTasksOnly     bool     `yaml:"healthyTasksOnly"`
-			EcsAnywhere          bool     `yaml:"ecsAnywhere"`
-			Region               string   `yaml:"region"`
-			AccessKeyID          string   `yaml:"accessKeyID"`
-			SecretAccessKey      string   `yaml:"secretAccessKey"`
-		} `yaml:"ecs"`
-		Consul struct {
-			RootKey   string   `yaml:"rootKey"`
-			Endpoints []string `yaml:"endpoints"`
-			Token     string   `yaml:"token"`
-			TLS       struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-			Namespaces []string `yaml:"namespaces"`
-		} `yaml:"consul"`
-		Etcd struct {
-			RootKey   string   `yaml:"rootKey"`
-			Endpoints []string `yaml:"endpoints"`
-			TLS       struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-			Username string `yaml:"username"`
-			Password string `yaml:"password"`
-		} `yaml:"etcd"`
-		ZooKeeper struct {
-			RootKey   string   `yaml:"rootKey"`
-			Endpoints []string `yaml:"endpoints"`
-			Username  string   `yaml:"username"`
-			Password  string   `yaml:"password"`
-		} `yaml:"zooKeeper"`
-		Redis struct {
-			RootKey   string   `yaml:"rootKey"`
-			Endpoints []string `yaml:"endpoints"`
-			TLS       struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-			Username string `yaml:"username"`
-			Password string `yaml:"password"`
-			Db       int    `yaml:"db"`
-			Sentinel struct {
-				MasterName              string `yaml:"masterName"`
-				Username                string `yaml:"username"`
-				Password                string `yaml:"password"`
-				LatencyStrategy         bool   `yaml:"latencyStrategy"`
-				RandomStrategy          bool   `yaml:"randomStrategy"`
-				ReplicaStrategy         bool   `yaml:"replicaStrategy"`
-				UseDisconnectedReplicas bool   `yaml:"useDisconnectedReplicas"`
-			} `yaml:"sentinel"`
-		} `yaml:"redis"`
-		HTTP struct {
-			Endpoint     string            `yaml:"endpoint"`
-			PollInterval string            `yaml:"pollInterval"`
-			PollTimeout  string            `yaml:"pollTimeout"`
-			Headers      map[string]string `yaml:"headers"`
-			TLS          struct {
-				Ca                 string `yaml:"ca"`
-				Cert               string `yaml:"cert"`
-				Key                string `yaml:"key"`
-				InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-			} `yaml:"tls"`
-		} `yaml:"http"`
-		Plugin map[string]map[string]string `yaml:"plugin"`
-	} `yaml:"providers"`
-	API struct {
-		BasePath           string `yaml:"basePath"`
-		Insecure           bool   `yaml:"insecure"`
-		Dashboard          bool   `yaml:"dashboard"`
-		Debug              bool   `yaml:"debug"`
-		DisableDashboardAd bool   `yaml:"disableDashboardAd"`
-	} `yaml:"api"`
-	Metrics struct {
-		AddInternals bool `yaml:"addInternals"`
-		Prometheus   struct {
-			Buckets              []int             `yaml:"buckets"`
-			AddEntryPointsLabels bool              `yaml:"addEntryPointsLabels"`
-			AddRoutersLabels     bool              `yaml:"addRoutersLabels"`
-			AddServicesLabels    bool              `yaml:"addServicesLabels"`
-			EntryPoint           string            `yaml:"entryPoint"`
-			ManualRouting        bool              `yaml:"manualRouting"`
-			HeaderLabels         map[string]string `yaml:"headerLabels"`
-		} `yaml:"prometheus"`
-		Datadog struct {
-			Address              string `yaml:"address"`
-			PushInterval         string `yaml:"pushInterval"`
-			AddEntryPointsLabels bool   `yaml:"addEntryPointsLabels"`
-			AddRoutersLabels     bool   `yaml:"addRoutersLabels"`
-			AddServicesLabels    bool   `yaml:"addServicesLabels"`
-			Prefix               string `yaml:"prefix"`
-		} `yaml:"datadog"`
-		StatsD struct {
-			Address              string `yaml:"address"`
-			PushInterval         string `yaml:"pushInterval"`
-			AddEntryPointsLabels bool   `yaml:"addEntryPointsLabels"`
-			AddRoutersLabels     bool   `yaml:"addRoutersLabels"`
-			AddServicesLabels    bool   `yaml:"addServicesLabels"`
-			Prefix               string `yaml:"prefix"`
-		} `yaml:"statsD"`
-		InfluxDB2 struct {
-			Address              string            `yaml:"address"`
-			Token                string            `yaml:"token"`
-			PushInterval         string            `yaml:"pushInterval"`
-			Org                  string            `yaml:"org"`
-			Bucket               string            `yaml:"bucket"`
-			AddEntryPointsLabels bool              `yaml:"addEntryPointsLabels"`
-			AddRoutersLabels     bool              `yaml:"addRoutersLabels"`
-			AddServicesLabels    bool              `yaml:"addServicesLabels"`
-			AdditionalLabels     map[string]string `yaml:"additionalLabels"`
-		} `yaml:"influxDB2"`
-		Otlp struct {
-			Grpc struct {
-				Endpoint string `yaml:"endpoint"`
-				Insecure bool   `yaml:"insecure"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"grpc"`
-			HTTP struct {
-				Endpoint string `yaml:"endpoint"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"http"`
-			AddEntryPointsLabels bool   `yaml:"addEntryPointsLabels"`
-			AddRoutersLabels     bool   `yaml:"addRoutersLabels"`
-			AddServicesLabels    bool   `yaml:"addServicesLabels"`
-			ExplicitBoundaries   []int  `yaml:"explicitBoundaries"`
-			PushInterval         string `yaml:"pushInterval"`
-			ServiceName          string `yaml:"serviceName"`
-		} `yaml:"otlp"`
-	} `yaml:"metrics"`
-	Ping struct {
-		EntryPoint            string `yaml:"entryPoint"`
-		ManualRouting         bool   `yaml:"manualRouting"`
-		TerminatingStatusCode int    `yaml:"terminatingStatusCode"`
-	} `yaml:"ping"`
-	Log struct {
-		Level      string `yaml:"level"`
-		Format     string `yaml:"format"`
-		NoColor    bool   `yaml:"noColor"`
-		FilePath   string `yaml:"filePath"`
-		MaxSize    int    `yaml:"maxSize"`
-		MaxAge     int    `yaml:"maxAge"`
-		MaxBackups int    `yaml:"maxBackups"`
-		Compress   bool   `yaml:"compress"`
-		Otlp       struct {
-			ServiceName        string            `yaml:"serviceName"`
-			ResourceAttributes map[string]string `yaml:"resourceAttributes"`
-			Grpc               struct {
-				Endpoint string `yaml:"endpoint"`
-				Insecure bool   `yaml:"insecure"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"grpc"`
-			HTTP struct {
-				Endpoint string `yaml:"endpoint"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"http"`
-		} `yaml:"otlp"`
-	} `yaml:"log"`
-	AccessLog struct {
-		FilePath string `yaml:"filePath"`
-		Format   string `yaml:"format"`
-		Filters  struct {
-			StatusCodes   []string `yaml:"statusCodes"`
-			RetryAttempts bool     `yaml:"retryAttempts"`
-			MinDuration   string   `yaml:"minDuration"`
-		} `yaml:"filters"`
-		Fields struct {
-			DefaultMode string            `yaml:"defaultMode"`
-			Names       map[string]string `yaml:"names"`
-			Headers     struct {
-				DefaultMode string            `yaml:"defaultMode"`
-				Names       map[string]string `yaml:"names"`
-			} `yaml:"headers"`
-		} `yaml:"fields"`
-		BufferingSize int  `yaml:"bufferingSize"`
-		AddInternals  bool `yaml:"addInternals"`
-		Otlp          struct {
-			ServiceName        string            `yaml:"serviceName"`
-			ResourceAttributes map[string]string `yaml:"resourceAttributes"`
-			Grpc               struct {
-				Endpoint string `yaml:"endpoint"`
-				Insecure bool   `yaml:"insecure"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"grpc"`
-			HTTP struct {
-				Endpoint string `yaml:"endpoint"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"http"`
-		} `yaml:"otlp"`
-	} `yaml:"accessLog"`
-	Tracing struct {
-		ServiceName             string            `yaml:"serviceName"`
-		ResourceAttributes      map[string]string `yaml:"resourceAttributes"`
-		CapturedRequestHeaders  []string          `yaml:"capturedRequestHeaders"`
-		CapturedResponseHeaders []string          `yaml:"capturedResponseHeaders"`
-		SafeQueryParams         []string          `yaml:"safeQueryParams"`
-		SampleRate              int               `yaml:"sampleRate"`
-		AddInternals            bool              `yaml:"addInternals"`
-		Otlp                    struct {
-			Grpc struct {
-				Endpoint string `yaml:"endpoint"`
-				Insecure bool   `yaml:"insecure"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"grpc"`
-			HTTP struct {
-				Endpoint string `yaml:"endpoint"`
-				TLS      struct {
-					Ca                 string `yaml:"ca"`
-					Cert               string `yaml:"cert"`
-					Key                string `yaml:"key"`
-					InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
-				} `yaml:"tls"`
-				Headers map[string]string `yaml:"headers"`
-			} `yaml:"http"`
-		} `yaml:"otlp"`
-		GlobalAttributes map[string]string `yaml:"globalAttributes"`
-	} `yaml:"tracing"`
-	HostResolver struct {
-		CnameFlattening bool   `yaml:"cnameFlattening"`
-		ResolvConfig    string `yaml:"resolvConfig"`
-		ResolvDepth     int    `yaml:"resolvDepth"`
-	} `yaml:"hostResolver"`
-	CertificatesResolvers map[string]struct {
-		Acme struct {
-			Email          string `yaml:"email"`
-			CaServer       string `yaml:"caServer"`
-			PreferredChain string `yaml:"preferredChain"`
-			Storage        string `yaml:"storage"`
-			KeyType        string `yaml:"keyType"`
-			Eab            struct {
-				Kid         string `yaml:"kid"`
-				HmacEncoded string `yaml:"hmacEncoded"`
-			} `yaml:"eab"`
-			CertificatesDuration int      `yaml:"certificatesDuration"`
-			CaCertificates       []string `yaml:"caCertificates"`
-			CaSystemCertPool     bool     `yaml:"caSystemCertPool"`
-			CaServerName         string   `yaml:"caServerName"`
-			DNSChallenge         struct {
-				Provider    string   `yaml:"provider"`
-				Resolvers   []string `yaml:"resolvers"`
-				Propagation struct {
-					DisableChecks     bool   `yaml:"disableChecks"`
-					DisableANSChecks  bool   `yaml:"disableANSChecks"`
-					RequireAllRNS     bool   `yaml:"requireAllRNS"`
-					DelayBeforeChecks string `yaml:"delayBeforeChecks"`
-				} `yaml:"propagation"`
-				DelayBeforeCheck        string `yaml:"delayBeforeCheck"`
-				DisablePropagationCheck bool   `yaml:"disablePropagationCheck"`
-			} `yaml:"dnsChallenge"`
-			HTTPChallenge struct {
-				EntryPoint string `yaml:"entryPoint"`
-			} `yaml:"httpChallenge"`
-			TLSChallenge struct {
-			} `yaml:"tlsChallenge"`
-		} `yaml:"acme"`
-		Tailscale struct{} `yaml:"tailscale"`
-	} `yaml:"certificatesResolvers"`
-	Experimental struct {
-		Plugins []struct {
-			ModuleName string `yaml:"moduleName"`
-			Version    string `yaml:"version"`
-			Settings   struct {
-				Envs   []string `yaml:"envs"`
-				Mounts []string `yaml:"mounts"`
-			} `yaml:"settings"`
-		} `yaml:"plugins"`
-		LocalPlugins []struct {
-			ModuleName string `yaml:"moduleName"`
-			Settings   struct {
-				Envs   []string `yaml:"envs"`
-				Mounts []string `yaml:"mounts"`
-			} `yaml:"settings"`
-		} `yaml:"localPlugins"`
-		AbortOnPluginFailure bool `yaml:"abortOnPluginFailure"`
-		FastProxy            struct {
-			Debug bool `yaml:"debug"`
-		} `yaml:"fastProxy"`
-		Otlplogs          bool `yaml:"otlplogs"`
-		KubernetesGateway bool `yaml:"kubernetesGateway"`
-	} `yaml:"experimental"`
-	Core struct {
-		DefaultRuleSyntax string `yaml:"defaultRuleSyntax"`
-	} `yaml:"core"`
-	Spiffe struct {
-		WorkloadAPIAddr string `yaml:"workloadAPIAddr"`
-	} `yaml:"spiffe"`
+	Global                *TraefikGlobal                         `yaml:"global,omitempty"`
+	ServersTransport      *TraefikStaticServersTransport         `yaml:"serversTransport,omitempty"`
+	TCPServersTransport   *TraefikStaticTCPServersTransport      `yaml:"tcpServersTransport,omitempty"`
+	EntryPoints           map[string]TraefikEntryPoint           `yaml:"entryPoints,omitempty"`
+	Providers             *TraefikProviders                      `yaml:"providers,omitempty"`
+	API                   *TraefikAPI                            `yaml:"api,omitempty"`
+	Metrics               *TraefikMetrics                        `yaml:"metrics,omitempty"`
+	Ping                  *TraefikPing                           `yaml:"ping,omitempty"`
+	Log                   *TraefikLog                            `yaml:"log,omitempty"`
+	AccessLog             *TraefikAccessLog                      `yaml:"accessLog,omitempty"`
+	Tracing               *TraefikTracing                        `yaml:"tracing,omitempty"`
+	HostResolver          *TraefikHostResolver                   `yaml:"hostResolver,omitempty"`
+	CertificatesResolvers map[string]TraefikCertificatesResolver `yaml:"certificatesResolvers,omitempty"`
+	Experimental          *TraefikExperimental                   `yaml:"experimental,omitempty"`
+	Core                  *TraefikCore                           `yaml:"core,omitempty"`
+	Spiffe                *TraefikStaticSpiffe                   `yaml:"spiffe,omitempty"`
 }
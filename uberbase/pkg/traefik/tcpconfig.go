@@ -0,0 +1,66 @@
+package traefik
+
+type TraefikTCPRouterTLS struct {
+	Passthrough  bool            `yaml:"passthrough,omitempty"`
+	Options      string          `yaml:"options,omitempty"`
+	CertResolver string          `yaml:"certResolver,omitempty"`
+	Domains      []TraefikDomain `yaml:"domains,omitempty"`
+}
+
+type TraefikTCPRouter struct {
+	EntryPoints []string             `yaml:"entryPoints,omitempty"`
+	Middlewares []string             `yaml:"middlewares,omitempty"`
+	Service     string               `yaml:"service,omitempty"`
+	Rule        string               `yaml:"rule,omitempty"`
+	RuleSyntax  string               `yaml:"ruleSyntax,omitempty"`
+	Priority    int                  `yaml:"priority,omitempty"`
+	TLS         *TraefikTCPRouterTLS `yaml:"tls,omitempty"`
+}
+
+type TraefikTCPServiceLoadBalancerServer struct {
+	Address string `yaml:"address,omitempty"`
+	TLS     bool   `yaml:"tls,omitempty"`
+}
+
+type TraefikTCPServiceLoadBalancerProxyProtocol struct {
+	Version int `yaml:"version,omitempty"`
+}
+
+type TraefikTCPServiceLoadBalancer struct {
+	ProxyProtocol    *TraefikTCPServiceLoadBalancerProxyProtocol `yaml:"proxyProtocol,omitempty"`
+	Servers          []TraefikTCPServiceLoadBalancerServer       `yaml:"servers,omitempty"`
+	ServersTransport string                                      `yaml:"serversTransport,omitempty"`
+	TerminationDelay *int                                        `yaml:"terminationDelay,omitempty"`
+}
+
+type TraefikTCPServiceWeighted struct {
+	Services []TraefikServiceWeightedService `yaml:"services,omitempty"`
+}
+
+type TraefikTCPService struct {
+	LoadBalancer *TraefikTCPServiceLoadBalancer `yaml:"loadBalancer,omitempty"`
+	Weighted     *TraefikTCPServiceWeighted     `yaml:"weighted,omitempty"`
+}
+
+type TraefikTCPServersTransportTLS struct {
+	ServerName         string               `yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool                 `yaml:"insecureSkipVerify,omitempty"`
+	RootCAs            []string             `yaml:"rootCAs,omitempty"`
+	Certificates       []TraefikCertificate `yaml:"certificates,omitempty"`
+	PeerCertURI        string               `yaml:"peerCertURI,omitempty"`
+	Spiffe             *TraefikSpiffe       `yaml:"spiffe,omitempty"`
+}
+
+type TraefikTCPServersTransport struct {
+	DialKeepAlive    string                         `yaml:"dialKeepAlive,omitempty"`
+	DialTimeout      string                         `yaml:"dialTimeout,omitempty"`
+	TerminationDelay string                         `yaml:"terminationDelay,omitempty"`
+	TLS              *TraefikTCPServersTransportTLS `yaml:"tls,omitempty"`
+}
+
+type TraefikTCPConfiguration struct {
+	Routers           map[string]TraefikTCPRouter           `yaml:"routers,omitempty"`
+	Services          map[string]TraefikTCPService          `yaml:"services,omitempty"`
+	Middlewares       map[string]TraefikTCPMiddleware       `yaml:"middlewares,omitempty"`
+	ServersTransports map[string]TraefikTCPServersTransport `yaml:"serversTransports,omitempty"`
+}
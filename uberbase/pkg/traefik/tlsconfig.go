@@ -0,0 +1,39 @@
+package traefik
+
+type TraefikTLSCertificate struct {
+	CertFile string   `yaml:"certFile,omitempty"`
+	KeyFile  string   `yaml:"keyFile,omitempty"`
+	Stores   []string `yaml:"stores,omitempty"`
+}
+
+type TraefikTLSClientAuth struct {
+	CAFiles        []string `yaml:"caFiles,omitempty"`
+	ClientAuthType string   `yaml:"clientAuthType,omitempty"`
+}
+
+type TraefikTLSOptions struct {
+	MinVersion               string                `yaml:"minVersion,omitempty"`
+	MaxVersion               string                `yaml:"maxVersion,omitempty"`
+	CipherSuites             []string              `yaml:"cipherSuites,omitempty"`
+	CurvePreferences         []string              `yaml:"curvePreferences,omitempty"`
+	ClientAuth               *TraefikTLSClientAuth `yaml:"clientAuth,omitempty"`
+	SniStrict                bool                  `yaml:"sniStrict,omitempty"`
+	ALPNProtocols            []string              `yaml:"alpnProtocols,omitempty"`
+	PreferServerCipherSuites bool                  `yaml:"preferServerCipherSuites,omitempty"`
+}
+
+type TraefikTLSStoreDefaultGeneratedCert struct {
+	Resolver string         `yaml:"resolver,omitempty"`
+	Domain   *TraefikDomain `yaml:"domain,omitempty"`
+}
+
+type TraefikTLSStore struct {
+	DefaultCertificate   *TraefikCertificate                  `yaml:"defaultCertificate,omitempty"`
+	DefaultGeneratedCert *TraefikTLSStoreDefaultGeneratedCert `yaml:"defaultGeneratedCert,omitempty"`
+}
+
+type TraefikTLSConfiguration struct {
+	Certificates []TraefikTLSCertificate      `yaml:"certificates,omitempty"`
+	Options      map[string]TraefikTLSOptions `yaml:"options,omitempty"`
+	Stores       map[string]TraefikTLSStore   `yaml:"stores,omitempty"`
+}
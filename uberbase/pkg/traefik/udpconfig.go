@@ -0,0 +1,28 @@
+package traefik
+
+type TraefikUDPRouter struct {
+	EntryPoints []string `yaml:"entryPoints,omitempty"`
+	Service     string   `yaml:"service,omitempty"`
+}
+
+type TraefikUDPServiceLoadBalancerServer struct {
+	Address string `yaml:"address,omitempty"`
+}
+
+type TraefikUDPServiceLoadBalancer struct {
+	Servers []TraefikUDPServiceLoadBalancerServer `yaml:"servers,omitempty"`
+}
+
+type TraefikUDPServiceWeighted struct {
+	Services []TraefikServiceWeightedService `yaml:"services,omitempty"`
+}
+
+type TraefikUDPService struct {
+	LoadBalancer *TraefikUDPServiceLoadBalancer `yaml:"loadBalancer,omitempty"`
+	Weighted     *TraefikUDPServiceWeighted     `yaml:"weighted,omitempty"`
+}
+
+type TraefikUDPConfiguration struct {
+	Routers  map[string]TraefikUDPRouter  `yaml:"routers,omitempty"`
+	Services map[string]TraefikUDPService `yaml:"services,omitempty"`
+}
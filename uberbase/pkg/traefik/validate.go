@@ -0,0 +1,503 @@
+package traefik
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var (
+	httpRuleMatchers = map[string]bool{
+		"ClientIP": true, "Header": true, "HeaderRegexp": true, "Host": true,
+		"HostRegexp": true, "Method": true, "Path": true, "PathPrefix": true,
+		"PathRegexp": true, "Query": true, "QueryRegexp": true,
+	}
+	httpRuleMatchersV2 = map[string]bool{
+		"ClientIP": true, "Headers": true, "HeadersRegexp": true, "Host": true,
+		"HostHeader": true, "HostRegexp": true, "Method": true, "Path": true,
+		"PathPrefix": true, "Query": true,
+	}
+	tcpRuleMatchers = map[string]bool{
+		"ALPN": true, "ClientIP": true, "HostSNI": true, "HostSNIRegexp": true,
+	}
+)
+
+// Validate checks the configuration for problems Traefik would only report
+// when loading it, such as references to undefined services or middlewares,
+// malformed rules and servers without ports. All problems found are returned.
+func (c *TraefikDynamicConfiguration) Validate() []error {
+	var problems []error
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.HTTP != nil {
+		for _, name := range sortedKeys(c.HTTP.Routers) {
+			router := c.HTTP.Routers[name]
+			if !c.hasHTTPService(router.Service) {
+				addProblem("http router %q references undefined service %q", name, router.Service)
+			}
+			for _, middleware := range router.Middlewares {
+				if !c.hasHTTPMiddleware(middleware) {
+					addProblem("http router %q references undefined middleware %q", name, middleware)
+				}
+			}
+			if duplicate := findDuplicate(router.EntryPoints); duplicate != "" {
+				addProblem("http router %q lists entrypoint %q more than once", name, duplicate)
+			}
+			matchers := httpRuleMatchers
+			if router.RuleSyntax == "v2" {
+				matchers = httpRuleMatchersV2
+			}
+			if err := validateRule(router.Rule, matchers); err != nil {
+				addProblem("http router %q has an invalid rule: %v", name, err)
+			}
+		}
+
+		for _, name := range sortedKeys(c.HTTP.Services) {
+			service := c.HTTP.Services[name]
+			if service.LoadBalancer != nil {
+				if len(service.LoadBalancer.Servers) == 0 {
+					addProblem("http service %q has no servers", name)
+				}
+				for _, server := range service.LoadBalancer.Servers {
+					if err := validateServerURL(server.URL); err != nil {
+						addProblem("http service %q has an invalid server: %v", name, err)
+					}
+				}
+			}
+			if service.Weighted != nil {
+				for _, weighted := range service.Weighted.Services {
+					if !c.hasHTTPService(weighted.Name) {
+						addProblem("http service %q references undefined service %q", name, weighted.Name)
+					}
+				}
+			}
+			if service.Mirroring != nil {
+				if !c.hasHTTPService(service.Mirroring.Service) {
+					addProblem("http service %q references undefined service %q", name, service.Mirroring.Service)
+				}
+				for _, mirror := range service.Mirroring.Mirrors {
+					if !c.hasHTTPService(mirror.Name) {
+						addProblem("http service %q references undefined service %q", name, mirror.Name)
+					}
+				}
+			}
+			if service.Failover != nil {
+				for _, ref := range []string{service.Failover.Service, service.Failover.Fallback} {
+					if !c.hasHTTPService(ref) {
+						addProblem("http service %q references undefined service %q", name, ref)
+					}
+				}
+			}
+		}
+
+		for _, name := range sortedKeys(c.HTTP.Middlewares) {
+			middleware := c.HTTP.Middlewares[name]
+			if middleware.Chain != nil {
+				for _, ref := range middleware.Chain.Middlewares {
+					if !c.hasHTTPMiddleware(ref) {
+						addProblem("http middleware %q references undefined middleware %q", name, ref)
+					}
+				}
+			}
+			if middleware.Errors != nil && !c.hasHTTPService(middleware.Errors.Service) {
+				addProblem("http middleware %q references undefined service %q", name, middleware.Errors.Service)
+			}
+		}
+	}
+
+	if c.TCP != nil {
+		for _, name := range sortedKeys(c.TCP.Routers) {
+			router := c.TCP.Routers[name]
+			if !c.hasTCPService(router.Service) {
+				addProblem("tcp router %q references undefined service %q", name, router.Service)
+			}
+			for _, middleware := range router.Middlewares {
+				if _, ok := c.TCP.Middlewares[middleware]; !ok && !isProviderRef(middleware) {
+					addProblem("tcp router %q references undefined middleware %q", name, middleware)
+				}
+			}
+			if duplicate := findDuplicate(router.EntryPoints); duplicate != "" {
+				addProblem("tcp router %q lists entrypoint %q more than once", name, duplicate)
+			}
+			if err := validateRule(router.Rule, tcpRuleMatchers); err != nil {
+				addProblem("tcp router %q has an invalid rule: %v", name, err)
+			}
+		}
+
+		for _, name := range sortedKeys(c.TCP.Services) {
+			service := c.TCP.Services[name]
+			if service.LoadBalancer != nil {
+				if len(service.LoadBalancer.Servers) == 0 {
+					addProblem("tcp service %q has no servers", name)
+				}
+				for _, server := range service.LoadBalancer.Servers {
+					if err := validateAddress(server.Address); err != nil {
+						addProblem("tcp service %q has an invalid server: %v", name, err)
+					}
+				}
+			}
+			if service.Weighted != nil {
+				for _, weighted := range service.Weighted.Services {
+					if !c.hasTCPService(weighted.Name) {
+						addProblem("tcp service %q references undefined service %q", name, weighted.Name)
+					}
+				}
+			}
+		}
+	}
+
+	if c.UDP != nil {
+		for _, name := range sortedKeys(c.UDP.Routers) {
+			router := c.UDP.Routers[name]
+			if !c.hasUDPService(router.Service) {
+				addProblem("udp router %q references undefined service %q", name, router.Service)
+			}
+			if duplicate := findDuplicate(router.EntryPoints); duplicate != "" {
+				addProblem("udp router %q lists entrypoint %q more than once", name, duplicate)
+			}
+		}
+
+		for _, name := range sortedKeys(c.UDP.Services) {
+			service := c.UDP.Services[name]
+			if service.LoadBalancer != nil {
+				if len(service.LoadBalancer.Servers) == 0 {
+					addProblem("udp service %q has no servers", name)
+				}
+				for _, server := range service.LoadBalancer.Servers {
+					if err := validateAddress(server.Address); err != nil {
+						addProblem("udp service %q has an invalid server: %v", name, err)
+					}
+				}
+			}
+			if service.Weighted != nil {
+				for _, weighted := range service.Weighted.Services {
+					if !c.hasUDPService(weighted.Name) {
+						addProblem("udp service %q references undefined service %q", name, weighted.Name)
+					}
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+func (c *TraefikDynamicConfiguration) hasHTTPService(name string) bool {
+	if isProviderRef(name) {
+		return true
+	}
+	_, ok := c.HTTP.Services[name]
+	return ok
+}
+
+func (c *TraefikDynamicConfiguration) hasHTTPMiddleware(name string) bool {
+	if isProviderRef(name) {
+		return true
+	}
+	_, ok := c.HTTP.Middlewares[name]
+	return ok
+}
+
+func (c *TraefikDynamicConfiguration) hasTCPService(name string) bool {
+	if isProviderRef(name) {
+		return true
+	}
+	if c.TCP == nil {
+		return false
+	}
+	_, ok := c.TCP.Services[name]
+	return ok
+}
+
+func (c *TraefikDynamicConfiguration) hasUDPService(name string) bool {
+	if isProviderRef(name) {
+		return true
+	}
+	if c.UDP == nil {
+		return false
+	}
+	_, ok := c.UDP.Services[name]
+	return ok
+}
+
+// isProviderRef reports whether name refers to an object of another provider,
+// such as api@internal, which cannot be checked from this configuration.
+func isProviderRef(name string) bool {
+	return strings.Contains(name, "@")
+}
+
+func validateServerURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("server has no url")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("url %q has no host", rawURL)
+	}
+	if parsed.Port() == "" {
+		return fmt.Errorf("url %q has no port", rawURL)
+	}
+	return nil
+}
+
+func validateAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("server has no address")
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("address %q must be host:port: %w", address, err)
+	}
+	if host == "" || port == "" {
+		return fmt.Errorf("address %q must be host:port", address)
+	}
+	return nil
+}
+
+// validateRule checks that rule is a well-formed expression of the given
+// matchers, each called with quoted arguments, joined by && and ||, negated
+// by ! and grouped by parentheses.
+func validateRule(rule string, matchers map[string]bool) error {
+	tokens, err := tokenizeRule(rule)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("rule is empty")
+	}
+
+	parser := &ruleParser{tokens: tokens, matchers: matchers}
+	if err := parser.expression(); err != nil {
+		return err
+	}
+	if token := parser.peek(); token != nil {
+		if token.kind == tokenClose {
+			return fmt.Errorf("unexpected ) at position %d", token.pos)
+		}
+		return fmt.Errorf("expected && or || at position %d, got %s", token.pos, token)
+	}
+	return nil
+}
+
+// RuleHosts returns the hosts named by the Host matchers of rule, or none
+// when the rule does not parse
+func RuleHosts(rule string) []string {
+	tokens, err := tokenizeRule(rule)
+	if err != nil {
+		return nil
+	}
+	var hosts []string
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind != tokenName || tokens[i].text != "Host" || tokens[i+1].kind != tokenOpen {
+			continue
+		}
+		for i += 2; i < len(tokens) && tokens[i].kind != tokenClose; i++ {
+			if tokens[i].kind == tokenString {
+				hosts = append(hosts, tokens[i].text[1:len(tokens[i].text)-1])
+			}
+		}
+	}
+	return hosts
+}
+
+type ruleTokenKind int
+
+const (
+	tokenName ruleTokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type ruleToken struct {
+	kind ruleTokenKind
+	text string
+	pos  int
+}
+
+func (t *ruleToken) String() string {
+	switch t.kind {
+	case tokenName:
+		return fmt.Sprintf("%q", t.text)
+	case tokenString:
+		return "a quoted argument"
+	}
+	return t.text
+}
+
+// tokenizeRule splits rule into matcher names, quoted arguments, operators,
+// parentheses and commas
+func tokenizeRule(rule string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	runes := []rune(rule)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+		case r == '`' || r == '"':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated %c quote at position %d", r, start)
+			}
+			tokens = append(tokens, ruleToken{kind: tokenString, text: string(runes[start : i+1]), pos: start})
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, fmt.Errorf("expected %c%c at position %d", r, r, i)
+			}
+			kind := tokenAnd
+			if r == '|' {
+				kind = tokenOr
+			}
+			tokens = append(tokens, ruleToken{kind: kind, text: string([]rune{r, r}), pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, ruleToken{kind: tokenOpen, text: "(", pos: i})
+		case r == ')':
+			tokens = append(tokens, ruleToken{kind: tokenClose, text: ")", pos: i})
+		case r == ',':
+			tokens = append(tokens, ruleToken{kind: tokenComma, text: ",", pos: i})
+		case r == '!':
+			tokens = append(tokens, ruleToken{kind: tokenNot, text: "!", pos: i})
+		case isLetter(r):
+			start := i
+			for i+1 < len(runes) && isLetter(runes[i+1]) {
+				i++
+			}
+			tokens = append(tokens, ruleToken{kind: tokenName, text: string(runes[start : i+1]), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// ruleParser checks the tokens of a rule, each step consuming what it
+// expects next: a matcher or group, its arguments, then an operator
+type ruleParser struct {
+	tokens   []ruleToken
+	next     int
+	matchers map[string]bool
+}
+
+func (p *ruleParser) peek() *ruleToken {
+	if p.next >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.next]
+}
+
+func (p *ruleParser) take() *ruleToken {
+	token := p.peek()
+	if token != nil {
+		p.next++
+	}
+	return token
+}
+
+// expression reads operands joined by && or ||
+func (p *ruleParser) expression() error {
+	for {
+		if err := p.operand(); err != nil {
+			return err
+		}
+		if token := p.peek(); token == nil || (token.kind != tokenAnd && token.kind != tokenOr) {
+			return nil
+		}
+		p.take()
+	}
+}
+
+// operand reads a matcher or a parenthesized expression, negated by any
+// number of !
+func (p *ruleParser) operand() error {
+	token := p.take()
+	for token != nil && token.kind == tokenNot {
+		token = p.take()
+	}
+	switch {
+	case token == nil:
+		return fmt.Errorf("rule ends where a matcher or ( is expected")
+	case token.kind == tokenOpen:
+		if err := p.expression(); err != nil {
+			return err
+		}
+		closing := p.take()
+		if closing == nil {
+			return fmt.Errorf("( at position %d is not closed", token.pos)
+		}
+		if closing.kind != tokenClose {
+			return fmt.Errorf("expected && or || at position %d, got %s", closing.pos, closing)
+		}
+		return nil
+	case token.kind == tokenName:
+		if !p.matchers[token.text] {
+			return fmt.Errorf("unknown matcher %q", token.text)
+		}
+		return p.arguments(token)
+	default:
+		return fmt.Errorf("expected a matcher or ( at position %d, got %s", token.pos, token)
+	}
+}
+
+// arguments reads the parenthesized, comma separated quoted arguments of matcher
+func (p *ruleParser) arguments(matcher *ruleToken) error {
+	if token := p.take(); token == nil || token.kind != tokenOpen {
+		return fmt.Errorf("matcher %q must be followed by (", matcher.text)
+	}
+	for {
+		argument := p.take()
+		if argument == nil {
+			return fmt.Errorf("matcher %q is not closed", matcher.text)
+		}
+		if argument.kind != tokenString {
+			return fmt.Errorf("matcher %q expects a quoted argument at position %d, got %s", matcher.text, argument.pos, argument)
+		}
+		token := p.take()
+		if token == nil {
+			return fmt.Errorf("matcher %q is not closed", matcher.text)
+		}
+		if token.kind == tokenClose {
+			return nil
+		}
+		if token.kind != tokenComma {
+			return fmt.Errorf("expected , or ) at position %d, got %s", token.pos, token)
+		}
+	}
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func findDuplicate(values []string) string {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if seen[value] {
+			return value
+		}
+		seen[value] = true
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
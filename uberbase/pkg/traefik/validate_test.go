@@ -0,0 +1,324 @@
+package traefik
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		matchers map[string]bool
+		// wantErr is part of the expected error, empty when the rule is valid
+		wantErr string
+	}{
+		{name: "matcher", rule: "Host(`example.com`)", matchers: httpRuleMatchers},
+		{name: "and", rule: "Host(`example.com`) && PathPrefix(`/api`)", matchers: httpRuleMatchers},
+		{name: "or and group", rule: "(Host(`a.com`) || Host(`b.com`)) && !Path(`/admin`)", matchers: httpRuleMatchers},
+		{name: "double quotes", rule: `Header("X-Env", "prod")`, matchers: httpRuleMatchers},
+		{name: "double negation", rule: "!!Method(`GET`)", matchers: httpRuleMatchers},
+		{name: "v2 arguments", rule: "Host(`a.com`, `b.com`)", matchers: httpRuleMatchersV2},
+		{name: "tcp", rule: "HostSNI(`*`)", matchers: tcpRuleMatchers},
+
+		{name: "empty", rule: "  ", matchers: httpRuleMatchers, wantErr: "rule is empty"},
+		{name: "unterminated quote", rule: "Host(`example.com)", matchers: httpRuleMatchers, wantErr: "unterminated ` quote at position 5"},
+		{name: "single ampersand", rule: "Host(`a.com`) & Path(`/`)", matchers: httpRuleMatchers, wantErr: "expected && at position 14"},
+		{name: "unexpected character", rule: "Host(`a.com`); Path(`/`)", matchers: httpRuleMatchers, wantErr: `unexpected ';' at position 13`},
+		{name: "unknown matcher", rule: "Hots(`a.com`)", matchers: httpRuleMatchers, wantErr: `unknown matcher "Hots"`},
+		{name: "v2 matcher in v3", rule: "HostHeader(`a.com`)", matchers: httpRuleMatchers, wantErr: `unknown matcher "HostHeader"`},
+		{name: "http matcher in tcp", rule: "Host(`a.com`)", matchers: tcpRuleMatchers, wantErr: `unknown matcher "Host"`},
+		{name: "matcher without arguments", rule: "Host && Path(`/`)", matchers: httpRuleMatchers, wantErr: `matcher "Host" must be followed by (`},
+		{name: "unquoted argument", rule: "Host(example)", matchers: httpRuleMatchers, wantErr: `matcher "Host" expects a quoted argument at position 5, got "example"`},
+		{name: "no arguments", rule: "Host()", matchers: httpRuleMatchers, wantErr: `matcher "Host" expects a quoted argument at position 5, got )`},
+		{name: "arguments not separated", rule: "Host(`a.com` `b.com`)", matchers: httpRuleMatchersV2, wantErr: "expected , or ) at position 13, got a quoted argument"},
+		{name: "matcher not closed", rule: "Host(`a.com`", matchers: httpRuleMatchers, wantErr: `matcher "Host" is not closed`},
+		{name: "missing operator", rule: "Host(`a`) Host(`b`)", matchers: httpRuleMatchers, wantErr: `expected && or || at position 10, got "Host"`},
+		{name: "missing operator in group", rule: "(Host(`a`) Host(`b`))", matchers: httpRuleMatchers, wantErr: `expected && or || at position 11, got "Host"`},
+		{name: "trailing operator", rule: "Host(`a`) &&", matchers: httpRuleMatchers, wantErr: "rule ends where a matcher or ( is expected"},
+		{name: "trailing negation", rule: "Host(`a`) && !", matchers: httpRuleMatchers, wantErr: "rule ends where a matcher or ( is expected"},
+		{name: "leading operator", rule: "|| Host(`a`)", matchers: httpRuleMatchers, wantErr: "expected a matcher or ( at position 0, got ||"},
+		{name: "double operator", rule: "Host(`a`) && || Host(`b`)", matchers: httpRuleMatchers, wantErr: "expected a matcher or ( at position 13, got ||"},
+		{name: "empty group", rule: "()", matchers: httpRuleMatchers, wantErr: "expected a matcher or ( at position 1, got )"},
+		{name: "group not closed", rule: "(Host(`a`) || Host(`b`)", matchers: httpRuleMatchers, wantErr: "( at position 0 is not closed"},
+		{name: "unbalanced close", rule: "Host(`a`))", matchers: httpRuleMatchers, wantErr: "unexpected ) at position 9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateRule(test.rule, test.matchers)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateRule(%q) = %v, want no error", test.rule, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("validateRule(%q) = %v, want %q", test.rule, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleHosts(t *testing.T) {
+	tests := []struct {
+		rule string
+		want []string
+	}{
+		{rule: "Host(`example.com`) && PathPrefix(`/api`)", want: []string{"example.com"}},
+		{rule: "Host(`a.com`, `b.com`) || (Host(\"c.com\") && Path(`/`))", want: []string{"a.com", "b.com", "c.com"}},
+		{rule: "PathPrefix(`/`)"},
+		{rule: "Host(`unterminated"},
+	}
+	for _, test := range tests {
+		if got := RuleHosts(test.rule); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RuleHosts(%q) = %q, want %q", test.rule, got, test.want)
+		}
+	}
+}
+
+// validConfig returns a configuration Validate finds no problems with, which
+// each test case breaks in one way
+func validConfig() *TraefikDynamicConfiguration {
+	return &TraefikDynamicConfiguration{
+		HTTP: &TraefikHTTPConfiguration{
+			Routers: map[string]TraefikRouter{
+				"web": {
+					EntryPoints: []string{"websecure"},
+					Middlewares: []string{"secure", "auth@file"},
+					Service:     "web",
+					Rule:        "Host(`example.com`)",
+				},
+				"dashboard": {Service: "api@internal", Rule: "PathPrefix(`/dashboard`)"},
+			},
+			Services: map[string]TraefikService{
+				"web": {LoadBalancer: &TraefikServiceLoadBalancer{
+					Servers: []TraefikServiceLoadBalancerServer{{URL: "http://web-abc123:8080"}},
+				}},
+				"canary": {Weighted: &TraefikServiceWeighted{
+					Services: []TraefikServiceWeightedService{{Name: "web"}},
+				}},
+			},
+			Middlewares: map[string]TraefikMiddleware{
+				"secure": NewChainMiddleware("auth@file"),
+			},
+		},
+		TCP: &TraefikTCPConfiguration{
+			Routers: map[string]TraefikTCPRouter{
+				"db": {EntryPoints: []string{"postgres"}, Service: "db", Rule: "HostSNI(`*`)"},
+			},
+			Services: map[string]TraefikTCPService{
+				"db": {LoadBalancer: &TraefikTCPServiceLoadBalancer{
+					Servers: []TraefikTCPServiceLoadBalancerServer{{Address: "db-abc123:5432"}},
+				}},
+			},
+		},
+		UDP: &TraefikUDPConfiguration{
+			Routers: map[string]TraefikUDPRouter{
+				"dns": {EntryPoints: []string{"dns"}, Service: "dns"},
+			},
+			Services: map[string]TraefikUDPService{
+				"dns": {LoadBalancer: &TraefikUDPServiceLoadBalancer{
+					Servers: []TraefikUDPServiceLoadBalancerServer{{Address: "dns-abc123:53"}},
+				}},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *TraefikDynamicConfiguration)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			change: func(*TraefikDynamicConfiguration) {},
+		},
+		{
+			name: "undefined service",
+			change: func(c *TraefikDynamicConfiguration) {
+				router := c.HTTP.Routers["web"]
+				router.Service = "missing"
+				c.HTTP.Routers["web"] = router
+			},
+			want: []string{`http router "web" references undefined service "missing"`},
+		},
+		{
+			name: "undefined middleware",
+			change: func(c *TraefikDynamicConfiguration) {
+				router := c.HTTP.Routers["web"]
+				router.Middlewares = append(router.Middlewares, "missing")
+				c.HTTP.Routers["web"] = router
+			},
+			want: []string{`http router "web" references undefined middleware "missing"`},
+		},
+		{
+			name: "duplicate entrypoint",
+			change: func(c *TraefikDynamicConfiguration) {
+				router := c.TCP.Routers["db"]
+				router.EntryPoints = []string{"postgres", "postgres"}
+				c.TCP.Routers["db"] = router
+			},
+			want: []string{`tcp router "db" lists entrypoint "postgres" more than once`},
+		},
+		{
+			name: "invalid rule",
+			change: func(c *TraefikDynamicConfiguration) {
+				router := c.HTTP.Routers["web"]
+				router.Rule = "Host(`a`) Host(`b`)"
+				c.HTTP.Routers["web"] = router
+			},
+			want: []string{`http router "web" has an invalid rule: expected && or ||`},
+		},
+		{
+			name: "v2 rule syntax",
+			change: func(c *TraefikDynamicConfiguration) {
+				router := c.HTTP.Routers["web"]
+				router.Rule = "HostHeader(`a.com`, `b.com`)"
+				router.RuleSyntax = "v2"
+				c.HTTP.Routers["web"] = router
+			},
+		},
+		{
+			name: "no servers",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.HTTP.Services["web"].LoadBalancer.Servers = nil
+			},
+			want: []string{`http service "web" has no servers`},
+		},
+		{
+			name: "server without port",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.HTTP.Services["web"].LoadBalancer.Servers[0].URL = "http://web-abc123"
+			},
+			want: []string{`http service "web" has an invalid server: url "http://web-abc123" has no port`},
+		},
+		{
+			name: "tcp address without port",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.TCP.Services["db"].LoadBalancer.Servers[0].Address = "db-abc123"
+			},
+			want: []string{`tcp service "db" has an invalid server: address "db-abc123" must be host:port`},
+		},
+		{
+			name: "udp server without address",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.UDP.Services["dns"].LoadBalancer.Servers[0].Address = ""
+			},
+			want: []string{`udp service "dns" has an invalid server: server has no address`},
+		},
+		{
+			name: "undefined weighted service",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.HTTP.Services["canary"].Weighted.Services[0].Name = "missing"
+			},
+			want: []string{`http service "canary" references undefined service "missing"`},
+		},
+		{
+			name: "undefined chained middleware",
+			change: func(c *TraefikDynamicConfiguration) {
+				c.HTTP.Middlewares["secure"] = NewChainMiddleware("missing")
+			},
+			want: []string{`http middleware "secure" references undefined middleware "missing"`},
+		},
+		{
+			name: "undefined udp service",
+			change: func(c *TraefikDynamicConfiguration) {
+				delete(c.UDP.Services, "dns")
+			},
+			want: []string{`udp router "dns" references undefined service "dns"`},
+		},
+		{
+			name: "every problem reported",
+			change: func(c *TraefikDynamicConfiguration) {
+				delete(c.HTTP.Services, "web")
+				router := c.TCP.Routers["db"]
+				router.Rule = "HostSNI(`*`) &&"
+				c.TCP.Routers["db"] = router
+			},
+			want: []string{
+				`http router "web" references undefined service "web"`,
+				`http service "canary" references undefined service "web"`,
+				`tcp router "db" has an invalid rule: rule ends where a matcher or ( is expected`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := validConfig()
+			test.change(config)
+
+			problems := config.Validate()
+			if len(problems) != len(test.want) {
+				t.Fatalf("problems = %v, want %q", problems, test.want)
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), test.want[i]) {
+					t.Errorf("problem %d = %q, want %q", i, problem, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeDynamicConfigs(t *testing.T) {
+	web := &TraefikDynamicConfiguration{
+		HTTP: &TraefikHTTPConfiguration{
+			Routers:  map[string]TraefikRouter{"web": {Service: "web", Rule: "Host(`example.com`)"}},
+			Services: map[string]TraefikService{"web": {}},
+		},
+		TLS: &TraefikTLSConfiguration{
+			Certificates: []TraefikTLSCertificate{{CertFile: "web.crt", KeyFile: "web.key"}},
+		},
+	}
+	db := &TraefikDynamicConfiguration{
+		TCP: &TraefikTCPConfiguration{
+			Routers: map[string]TraefikTCPRouter{"db": {Service: "db", Rule: "HostSNI(`*`)"}},
+		},
+		TLS: &TraefikTLSConfiguration{
+			Certificates: []TraefikTLSCertificate{{CertFile: "db.crt", KeyFile: "db.key"}},
+		},
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		merged, err := MergeDynamicConfigs(map[string]*TraefikDynamicConfiguration{
+			"web.yml": web, "db.yml": db, "empty.yml": nil,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := merged.HTTP.Routers["web"]; !ok {
+			t.Errorf("http router web not merged")
+		}
+		if _, ok := merged.TCP.Routers["db"]; !ok {
+			t.Errorf("tcp router db not merged")
+		}
+		if merged.UDP != nil {
+			t.Errorf("udp = %+v, want none", merged.UDP)
+		}
+		// certificates are appended in file name order
+		certificates := merged.TLS.Certificates
+		if len(certificates) != 2 || certificates[0].CertFile != "db.crt" || certificates[1].CertFile != "web.crt" {
+			t.Errorf("certificates = %+v, want db.crt then web.crt", certificates)
+		}
+	})
+
+	t.Run("defined twice", func(t *testing.T) {
+		other := &TraefikDynamicConfiguration{
+			HTTP: &TraefikHTTPConfiguration{
+				Services: map[string]TraefikService{"web": {}},
+			},
+		}
+		_, err := MergeDynamicConfigs(map[string]*TraefikDynamicConfiguration{
+			"web.yml": web, "z-other.yml": other,
+		})
+		want := `http service "web" in z-other.yml is already defined`
+		if err == nil || err.Error() != want {
+			t.Fatalf("err = %v, want %q", err, want)
+		}
+	})
+}
@@ -44,3 +44,8 @@ func ExpandTildeLocal(relPath string) (string, error) {
 	}
 	return absPath, nil
 }
+
+// ShellQuote quotes s as a single shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}